@@ -2,9 +2,12 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"margin/internal/model"
 	"margin/internal/service"
 	"margin/pkg/db"
+	"os"
 	goruntime "runtime"
 	"time"
 
@@ -14,29 +17,65 @@ import (
 
 // App 应用结构
 type App struct {
-	ctx              context.Context
-	db               *gorm.DB
-	configService    *service.ConfigService
-	assetService     *service.AssetService
-	historyService   *service.HistoryService
-	fundService      *service.FundService
-	sourceService    *service.SourceService
-	indexService     *service.IndexService
-	rebalanceService *service.RebalanceService
-	isAuthenticated  bool // 后端维护的登录状态
+	ctx                context.Context
+	db                 *gorm.DB
+	configService      *service.ConfigService
+	assetService       *service.AssetService
+	historyService     *service.HistoryService
+	fundService        *service.FundService
+	sourceService      *service.SourceService
+	indexService       *service.IndexService
+	calendarService    *service.CalendarService
+	indexHistory       *service.IndexHistoryService
+	rebalanceService   *service.RebalanceService
+	importService      *service.ImportService
+	exportService      *service.ExportService
+	interchangeService *service.InterchangeService
+	feeService         *service.FeeService
+	scheduleService    *service.ScheduleService
+	valuationService   *service.ValuationService
+	backtestService    *service.BacktestService
+	monteCarloService  *service.MonteCarloService
+	analyticsService   *service.AnalyticsService
+	benchmarkService   *service.BenchmarkService
+	dueReview          map[string]interface{} // 启动时检查出的到期再平衡检查
+	isAuthenticated    bool                   // 后端维护的登录状态
 }
 
 // NewApp 创建应用实例
 func NewApp(db *gorm.DB) *App {
+	fundService := service.NewFundService()
+	assetService := service.NewAssetService(db)
+	exportService := service.NewExportService(db)
+	calendarService := service.NewCalendarService(db)
+	valuationService := service.NewValuationService(db)
+	indexHistoryService := service.NewIndexHistoryService(db)
+	// 设置回放目录后指数日线从本地文件读取而不访问网络，MARGIN_INDEX_RECORD=1 时改为录制
+	if dir := os.Getenv("MARGIN_INDEX_FIXTURES"); dir != "" {
+		indexHistoryService.UseTransport(service.NewFixtureTransport(dir, os.Getenv("MARGIN_INDEX_RECORD") == "1"))
+	}
+
 	return &App{
-		db:               db,
-		configService:    service.NewConfigService(db),
-		assetService:     service.NewAssetService(db),
-		historyService:   service.NewHistoryService(db),
-		fundService:      service.NewFundService(),
-		sourceService:    service.NewSourceService(db),
-		indexService:     service.NewIndexService(db),
-		rebalanceService: service.NewRebalanceService(db),
+		db:                 db,
+		configService:      service.NewConfigService(db),
+		assetService:       assetService,
+		historyService:     service.NewHistoryService(db),
+		fundService:        fundService,
+		sourceService:      service.NewSourceService(db),
+		indexService:       service.NewIndexService(db, calendarService),
+		calendarService:    calendarService,
+		indexHistory:       indexHistoryService,
+		rebalanceService:   service.NewRebalanceService(db, assetService, valuationService),
+		importService:      service.NewImportService(db, fundService),
+		exportService:      exportService,
+		interchangeService: service.NewInterchangeService(db, exportService),
+		feeService:         service.NewFeeService(db, fundService),
+		scheduleService:    service.NewScheduleService(db, assetService),
+		valuationService:   valuationService,
+		backtestService:    service.NewBacktestService(indexHistoryService),
+		monteCarloService:  service.NewMonteCarloService(assetService, indexHistoryService),
+		analyticsService:   service.NewAnalyticsService(db),
+		benchmarkService:   service.NewBenchmarkService(db, indexHistoryService),
 	}
 }
 
@@ -49,6 +88,35 @@ func (a *App) startup(ctx context.Context) {
 		// 记录错误但不中断启动
 		println("Failed to init default sources:", err.Error())
 	}
+
+	// 首次启动时写入默认指数
+	if err := a.indexService.InitDefaultIndexes(ctx); err != nil {
+		println("Failed to init default indexes:", err.Error())
+	}
+
+	// 加密旧版本明文保存的再平衡金额
+	if err := a.rebalanceService.MigrateLegacyAmounts(ctx); err != nil {
+		println("Failed to migrate rebalance amounts:", err.Error())
+	}
+
+	// 检查是否到了定期再平衡检查日期
+	review, err := a.scheduleService.CheckDue(ctx)
+	if err != nil {
+		println("Failed to check rebalance schedule:", err.Error())
+	}
+	a.dueReview = review
+
+	// 按策略保存每日快照
+	if _, err := a.historyService.AutoSnapshot(ctx, service.SnapshotTriggerStartup); err != nil {
+		println("Failed to save daily snapshot:", err.Error())
+	}
+}
+
+// domReady 前端加载完成后通知到期的再平衡检查
+func (a *App) domReady(ctx context.Context) {
+	if a.dueReview != nil {
+		runtime.EventsEmit(ctx, "rebalance:review-due", a.dueReview)
+	}
 }
 
 // IsFirstRun 检查是否首次运行
@@ -103,7 +171,11 @@ func (a *App) GetFundInfo(fundCode string) (map[string]interface{}, error) {
 
 // SaveAsset 保存资产
 func (a *App) SaveAsset(code string, name string, url string, assetType string, source string, amount float64) error {
-	return a.assetService.SaveAsset(a.ctx, code, name, url, assetType, source, amount)
+	if err := a.assetService.SaveAsset(a.ctx, code, name, url, assetType, source, amount); err != nil {
+		return err
+	}
+	a.snapshotAfterChange()
+	return nil
 }
 
 // GetPortfolioRatio 获取当前组合比例
@@ -111,9 +183,261 @@ func (a *App) GetPortfolioRatio() (map[string]float64, error) {
 	return a.assetService.GetPortfolioRatio(a.ctx)
 }
 
-// GetRebalanceAdvice 获取再平衡建议
+// GetRebalanceAdvice 获取再平衡建议，targetStockRatio 为负数时使用下滑路径或估值建议的默认目标
 func (a *App) GetRebalanceAdvice(targetStockRatio float64) (map[string]interface{}, error) {
-	return a.assetService.GetRebalanceAdvice(a.ctx, targetStockRatio)
+	return a.rebalanceService.GetRebalanceAdvice(a.ctx, targetStockRatio)
+}
+
+// GetValuationConfig 获取估值驱动的动态目标配置
+func (a *App) GetValuationConfig() (map[string]interface{}, error) {
+	config, err := a.valuationService.GetConfig(a.ctx)
+	if err != nil {
+		return nil, err
+	}
+	return config.ToMap(), nil
+}
+
+// SaveValuationConfig 保存估值驱动的动态目标配置
+// method: percentile/earnings_yield，metric: pe/pb；curveX 与 curveStock 一一对应，为空时使用默认曲线
+func (a *App) SaveValuationConfig(enabled bool, method, metric string, years int, curveX, curveStock []float64) error {
+	if len(curveX) != len(curveStock) {
+		return errors.New("曲线数据不完整")
+	}
+	curve := make([]service.CurvePoint, 0, len(curveX))
+	for i := range curveX {
+		curve = append(curve, service.CurvePoint{X: curveX[i], Stock: curveStock[i]})
+	}
+	return a.valuationService.SaveConfig(a.ctx, service.ValuationConfig{
+		Enabled: enabled,
+		Method:  method,
+		Metric:  metric,
+		Years:   years,
+		Curve:   curve,
+	})
+}
+
+// GetValuationSuggestion 获取沪深300当前估值及建议的股票比例
+func (a *App) GetValuationSuggestion() (map[string]interface{}, error) {
+	v, err := a.valuationService.Suggest(a.ctx)
+	if err != nil {
+		return nil, err
+	}
+	return v.ToMap(), nil
+}
+
+// GetGlidePath 获取目标股票比例的下滑路径
+func (a *App) GetGlidePath() (map[string]interface{}, error) {
+	glide, err := a.configService.GetGlidePath(a.ctx)
+	if err != nil {
+		return nil, err
+	}
+	return glide.ToMap(), nil
+}
+
+// SaveGlidePath 保存目标股票比例的下滑路径
+// anchor: birth_year/target_date，formula: age_rule/linear/breakpoints；
+// breakpointX（年龄或剩余年数）与 breakpointStock 一一对应，仅 breakpoints 公式使用
+func (a *App) SaveGlidePath(enabled bool, anchor string, birthYear int, targetDate, formula string, ageBase, startX, startRatio, endX, floorRatio float64, breakpointX, breakpointStock []float64) error {
+	if len(breakpointX) != len(breakpointStock) {
+		return errors.New("拐点数据不完整")
+	}
+	breakpoints := make([]service.CurvePoint, 0, len(breakpointX))
+	for i := range breakpointX {
+		breakpoints = append(breakpoints, service.CurvePoint{X: breakpointX[i], Stock: breakpointStock[i]})
+	}
+	return a.configService.SaveGlidePath(a.ctx, service.GlidePath{
+		Enabled:     enabled,
+		Anchor:      anchor,
+		BirthYear:   birthYear,
+		TargetDate:  targetDate,
+		Formula:     formula,
+		AgeBase:     ageBase,
+		StartX:      startX,
+		StartRatio:  startRatio,
+		EndX:        endX,
+		FloorRatio:  floorRatio,
+		Breakpoints: breakpoints,
+	})
+}
+
+// GetGlidePathSeries 获取按月的目标股票比例序列，从最早的历史记录开始，向后延伸 years 年
+func (a *App) GetGlidePathSeries(years int) ([]map[string]interface{}, error) {
+	return a.historyService.GetGlidePathSeries(a.ctx, years)
+}
+
+// GetBacktestIndexes 获取可用于回测的指数
+func (a *App) GetBacktestIndexes() ([]map[string]interface{}, error) {
+	return a.backtestService.BacktestIndexes(a.ctx)
+}
+
+// RunBacktest 回测再平衡策略（买入持有、定期再平衡、容忍带再平衡）
+// from/to 格式为 2006-01-02，空表示全部历史；frequency: monthly/quarterly/semiannual/annual；band 为绝对偏离(%)
+func (a *App) RunBacktest(stockCode, bondCode, from, to string, stockRatio float64, frequency string, band float64) (map[string]interface{}, error) {
+	return a.backtestService.RunBacktest(a.ctx, service.BacktestParams{
+		StockCode:  stockCode,
+		BondCode:   bondCode,
+		From:       from,
+		To:         to,
+		StockRatio: stockRatio,
+		Frequency:  frequency,
+		Band:       band,
+	})
+}
+
+// GetMonteCarloDefaults 获取蒙特卡洛模拟的默认参数（以当前持仓填充）
+func (a *App) GetMonteCarloDefaults() (map[string]interface{}, error) {
+	return a.monteCarloService.Defaults(a.ctx)
+}
+
+// RunMonteCarlo 蒙特卡洛模拟组合结果
+// method: bootstrap/parametric；stockRatio < 0 使用当前比例，initialValue <= 0 使用当前总资产；
+// 参数模型的年化收益率、波动率(%)和股债相关系数仅在 parametric 时使用
+func (a *App) RunMonteCarlo(method string, stockRatio, initialValue, monthlyContribution, monthlyWithdrawal float64, years int, goal float64, paths int, seed uint64, successRate, stockReturn, stockVolatility, bondReturn, bondVolatility, correlation float64) (map[string]interface{}, error) {
+	return a.monteCarloService.Simulate(a.ctx, service.MonteCarloParams{
+		Method:              method,
+		StockRatio:          stockRatio,
+		InitialValue:        initialValue,
+		MonthlyContribution: monthlyContribution,
+		MonthlyWithdrawal:   monthlyWithdrawal,
+		Years:               years,
+		Goal:                goal,
+		Paths:               paths,
+		Seed:                seed,
+		SuccessRate:         successRate,
+		StockReturn:         stockReturn,
+		StockVolatility:     stockVolatility,
+		BondReturn:          bondReturn,
+		BondVolatility:      bondVolatility,
+		Correlation:         correlation,
+	})
+}
+
+// GetRebalanceBands 获取再平衡容忍带规则
+func (a *App) GetRebalanceBands() (map[string]interface{}, error) {
+	bands, err := a.configService.GetRebalanceBands(a.ctx)
+	if err != nil {
+		return nil, err
+	}
+	return bands.ToMap(), nil
+}
+
+// SaveRebalanceBands 保存再平衡容忍带规则（mode: absolute/relative/combined）
+func (a *App) SaveRebalanceBands(stockMode string, stockAbsolute, stockRelative float64, bondMode string, bondAbsolute, bondRelative float64) error {
+	return a.configService.SaveRebalanceBands(a.ctx, service.RebalanceBands{
+		Stock: service.BandRule{Mode: stockMode, Absolute: stockAbsolute, Relative: stockRelative},
+		Bond:  service.BandRule{Mode: bondMode, Absolute: bondAbsolute, Relative: bondRelative},
+	})
+}
+
+// SetAssetTargetWeight 设置资产在类别内的目标权重（%）
+func (a *App) SetAssetTargetWeight(id uint, weight float64) error {
+	return a.assetService.SetAssetTargetWeight(a.ctx, id, weight)
+}
+
+// GetRebalanceSchedule 获取定期再平衡日历
+func (a *App) GetRebalanceSchedule() (map[string]interface{}, error) {
+	schedule, err := a.scheduleService.GetSchedule(a.ctx)
+	if err != nil {
+		return nil, err
+	}
+	return schedule.ToMap(), nil
+}
+
+// SaveRebalanceSchedule 保存定期再平衡日历
+// frequency: off/monthly/quarterly/semiannual/dates，dates 格式为 MM-DD
+func (a *App) SaveRebalanceSchedule(frequency string, day int, dates []string, targetStockRatio float64) error {
+	return a.scheduleService.SaveSchedule(a.ctx, service.RebalanceSchedule{
+		Frequency:        frequency,
+		Day:              day,
+		Dates:            dates,
+		TargetStockRatio: targetStockRatio,
+	})
+}
+
+// GetDueReview 获取启动时检查出的到期再平衡检查，没有时返回 nil
+func (a *App) GetDueReview() map[string]interface{} {
+	return a.dueReview
+}
+
+// RunRebalanceReview 立即检查当前持仓是否突破容忍带并记录
+func (a *App) RunRebalanceReview(targetStockRatio float64, note string) (map[string]interface{}, error) {
+	return a.scheduleService.RunReview(a.ctx, targetStockRatio, note)
+}
+
+// GetRebalanceReviews 获取定期检查记录
+func (a *App) GetRebalanceReviews() ([]map[string]interface{}, error) {
+	return a.scheduleService.GetReviews(a.ctx)
+}
+
+// UpdateRebalanceReview 更新检查结果（no_action/rebalance_needed/rebalanced）和备注
+func (a *App) UpdateRebalanceReview(id uint, outcome, note string) error {
+	if err := a.scheduleService.UpdateReview(a.ctx, id, outcome, note); err != nil {
+		return err
+	}
+	if a.dueReview != nil && a.dueReview["id"] == id {
+		a.dueReview = nil
+	}
+	return nil
+}
+
+// DeleteRebalanceReview 删除检查记录
+func (a *App) DeleteRebalanceReview(id uint) error {
+	return a.scheduleService.DeleteReview(a.ctx, id)
+}
+
+// GetRebalancePlan 获取基金级别的再平衡交易计划
+func (a *App) GetRebalancePlan(targetStockRatio float64, minTrade float64) (map[string]interface{}, error) {
+	return a.assetService.GetRebalancePlan(a.ctx, targetStockRatio, minTrade)
+}
+
+// PlanContribution 规划新增资金（负数为取出）在各类别和基金间的分配
+func (a *App) PlanContribution(amount float64, targetStockRatio float64) (map[string]interface{}, error) {
+	return a.assetService.PlanContribution(a.ctx, amount, targetStockRatio)
+}
+
+// GetAssetLots 获取资产的持仓批次
+func (a *App) GetAssetLots(assetID uint) ([]map[string]interface{}, error) {
+	return a.assetService.GetAssetLots(a.ctx, assetID)
+}
+
+// AddAssetLot 添加持仓批次（买入日期格式 2006-01-02）
+func (a *App) AddAssetLot(assetID uint, buyDate string, amount float64) error {
+	return a.assetService.AddAssetLot(a.ctx, assetID, buyDate, amount)
+}
+
+// DeleteAssetLot 删除持仓批次
+func (a *App) DeleteAssetLot(id uint) error {
+	return a.assetService.DeleteAssetLot(a.ctx, id)
+}
+
+// GetFeeRules 获取基金的费率规则
+func (a *App) GetFeeRules(code string) ([]map[string]interface{}, error) {
+	return a.feeService.GetFeeRules(a.ctx, code)
+}
+
+// FetchFeeRules 从基金费率页抓取申购费和赎回费，返回规则条数
+func (a *App) FetchFeeRules(code string) (int, error) {
+	return a.feeService.FetchFeeRules(a.ctx, code)
+}
+
+// SaveFeeRule 手动录入费率规则，kind 为 subscription（按金额分档）或 redemption（按持有天数分档）
+func (a *App) SaveFeeRule(code, source, kind string, minDays, maxDays int, minAmount, maxAmount, rate, fixed float64) error {
+	return a.feeService.SaveFeeRule(a.ctx, model.FeeRule{
+		Code:      code,
+		Source:    source,
+		Kind:      kind,
+		MinDays:   minDays,
+		MaxDays:   maxDays,
+		MinAmount: minAmount,
+		MaxAmount: maxAmount,
+		Rate:      rate,
+		Fixed:     fixed,
+	})
+}
+
+// DeleteFeeRule 删除费率规则
+func (a *App) DeleteFeeRule(id uint) error {
+	return a.feeService.DeleteFeeRule(a.ctx, id)
 }
 
 // SaveSnapshot 保存历史快照
@@ -121,16 +445,94 @@ func (a *App) SaveSnapshot() error {
 	return a.historyService.SaveSnapshot(a.ctx)
 }
 
+// snapshotAfterChange 持仓变化后按策略自动保存快照，失败不影响原操作
+func (a *App) snapshotAfterChange() {
+	if _, err := a.historyService.AutoSnapshot(a.ctx, service.SnapshotTriggerChange); err != nil {
+		println("Failed to save snapshot:", err.Error())
+	}
+}
+
+// GetSnapshotPolicy 获取自动快照策略
+func (a *App) GetSnapshotPolicy() (map[string]interface{}, error) {
+	policy, err := a.historyService.GetSnapshotPolicy(a.ctx)
+	if err != nil {
+		return nil, err
+	}
+	return policy.ToMap(), nil
+}
+
+// SaveSnapshotPolicy 保存自动快照策略（daily: 每天启动时保存，onChange: 持仓变化后保存）
+func (a *App) SaveSnapshotPolicy(daily, onChange bool) error {
+	return a.historyService.SaveSnapshotPolicy(a.ctx, service.SnapshotPolicy{Daily: daily, OnChange: onChange})
+}
+
 // GetHistory 获取历史记录
 func (a *App) GetHistory() ([]map[string]interface{}, error) {
 	return a.historyService.GetHistory(a.ctx)
 }
 
+// QueryHistory 按时间区间查询历史记录（日期格式 2006-01-02，空表示不限）
+// period: daily/weekly/monthly/quarterly 时每个周期只保留最后一条，为空不降采样；limit 为 0 表示不分页
+func (a *App) QueryHistory(from, to, period string, limit, offset int) (map[string]interface{}, error) {
+	return a.historyService.QueryHistory(a.ctx, service.TimeQuery{From: from, To: to, Period: period, Limit: limit, Offset: offset})
+}
+
+// GetHistoryItems 获取历史快照的资产明细
+func (a *App) GetHistoryItems(id uint) ([]map[string]interface{}, error) {
+	return a.historyService.GetHistoryItems(a.ctx, id)
+}
+
+// DiffHistory 逐个资产比较两个历史快照（fromID 为较早的快照）
+func (a *App) DiffHistory(fromID, toID uint) (map[string]interface{}, error) {
+	return a.historyService.DiffHistory(a.ctx, fromID, toID)
+}
+
 // DeleteHistory 删除历史记录
 func (a *App) DeleteHistory(id int64) error {
 	return a.historyService.DeleteHistory(a.ctx, id)
 }
 
+// GetCashFlows 获取资金流水
+func (a *App) GetCashFlows() ([]map[string]interface{}, error) {
+	return a.analyticsService.GetCashFlows(a.ctx)
+}
+
+// AddCashFlow 录入资金流水（kind: deposit/withdrawal，日期格式 2006-01-02）
+// assetID 为 0 时按 assetType（stock/bond）和 source 记录
+func (a *App) AddCashFlow(assetID uint, date, kind, assetType, source string, amount float64, note string) error {
+	return a.analyticsService.AddCashFlow(a.ctx, service.CashFlowInput{
+		AssetID: assetID,
+		Date:    date,
+		Kind:    kind,
+		Type:    assetType,
+		Source:  source,
+		Amount:  amount,
+		Note:    note,
+	})
+}
+
+// DeleteCashFlow 删除资金流水
+func (a *App) DeleteCashFlow(id uint) error {
+	return a.analyticsService.DeleteCashFlow(a.ctx, id)
+}
+
+// GetPerformance 获取收益分析（时间加权收益、资金加权收益、波动率、回撤和夏普比率），
+// 按整体、资产类别和来源分别计算；from/to 格式为 2006-01-02，空表示不限，riskFree 为无风险年收益率(%)
+func (a *App) GetPerformance(from, to string, riskFree float64) (map[string]interface{}, error) {
+	return a.analyticsService.GetPerformance(a.ctx, from, to, riskFree)
+}
+
+// GetBenchmarkIndexes 获取可用于对比的基准指数
+func (a *App) GetBenchmarkIndexes() ([]map[string]interface{}, error) {
+	return a.benchmarkService.BenchmarkIndexes(a.ctx)
+}
+
+// CompareBenchmarks 在历史快照日期上对比组合与所选指数、50/50 股债组合的归一化净值及跟踪差；
+// from/to 格式为 2006-01-02，空表示不限
+func (a *App) CompareBenchmarks(codes []string, from, to string) (map[string]interface{}, error) {
+	return a.benchmarkService.CompareBenchmarks(a.ctx, codes, from, to)
+}
+
 // GetSources 获取所有来源
 func (a *App) GetSources() ([]map[string]interface{}, error) {
 	return a.sourceService.GetSources(a.ctx)
@@ -148,17 +550,29 @@ func (a *App) DeleteSource(id uint) error {
 
 // DeleteAsset 删除资产
 func (a *App) DeleteAsset(id uint) error {
-	return a.assetService.DeleteAsset(a.ctx, id)
+	if err := a.assetService.DeleteAsset(a.ctx, id); err != nil {
+		return err
+	}
+	a.snapshotAfterChange()
+	return nil
 }
 
 // UpdateAssetAmount 更新资产金额
 func (a *App) UpdateAssetAmount(id uint, amount float64) error {
-	return a.assetService.UpdateAssetAmount(a.ctx, id, amount)
+	if err := a.assetService.UpdateAssetAmount(a.ctx, id, amount); err != nil {
+		return err
+	}
+	a.snapshotAfterChange()
+	return nil
 }
 
 // UpdateAsset 更新资产（包括类型、来源和金额）
 func (a *App) UpdateAsset(id uint, assetType, source string, amount float64) error {
-	return a.assetService.UpdateAsset(a.ctx, id, assetType, source, amount)
+	if err := a.assetService.UpdateAsset(a.ctx, id, assetType, source, amount); err != nil {
+		return err
+	}
+	a.snapshotAfterChange()
+	return nil
 }
 
 // GetIndexData 获取单个指数数据
@@ -168,36 +582,137 @@ func (a *App) GetIndexData(code string) (map[string]interface{}, error) {
 		return nil, err
 	}
 
-	return map[string]interface{}{
-		"code":        data.Code,
-		"name":        data.Name,
-		"price":       data.Price,
-		"change":      data.Change,
-		"change_rate": data.ChangeRate,
-		"update_time": data.UpdateTime,
-	}, nil
+	return indexDataToMap(data), nil
 }
 
-// GetAllIndexes 获取所有指数数据
+// GetAllIndexes 获取行情栏中所有指数的数据，每个指数带有 status（ok/stale/error）、失败原因和所在市场是否开市
 func (a *App) GetAllIndexes() ([]map[string]interface{}, error) {
 	indexes, err := a.indexService.GetAllIndexes(a.ctx)
 	if err != nil {
 		return nil, err
 	}
+	return indexDataToMaps(indexes), nil
+}
 
+// RefreshIndexes 忽略缓存重新获取行情栏中所有指数的数据
+func (a *App) RefreshIndexes() ([]map[string]interface{}, error) {
+	indexes, err := a.indexService.RefreshIndexes(a.ctx)
+	if err != nil {
+		return nil, err
+	}
+	return indexDataToMaps(indexes), nil
+}
+
+func indexDataToMaps(indexes []*service.IndexData) []map[string]interface{} {
 	result := make([]map[string]interface{}, 0, len(indexes))
 	for _, data := range indexes {
-		result = append(result, map[string]interface{}{
-			"code":        data.Code,
-			"name":        data.Name,
-			"price":       data.Price,
-			"change":      data.Change,
-			"change_rate": data.ChangeRate,
-			"update_time": data.UpdateTime,
-		})
+		result = append(result, indexDataToMap(data))
 	}
+	return result
+}
 
-	return result, nil
+func indexDataToMap(data *service.IndexData) map[string]interface{} {
+	return map[string]interface{}{
+		"code":         data.Code,
+		"name":         data.Name,
+		"price":        data.Price,
+		"change":       data.Change,
+		"change_rate":  data.ChangeRate,
+		"open":         data.Open,
+		"high":         data.High,
+		"low":          data.Low,
+		"prev_close":   data.PrevClose,
+		"volume":       data.Volume,
+		"amount":       data.Amount,
+		"update_time":  data.UpdateTime,
+		"status":       data.Status,
+		"error":        data.Error,
+		"market_open":  data.MarketOpen,
+		"market_state": data.MarketState,
+	}
+}
+
+// GetMarketStatus 获取 A 股、港股和美股当前的交易状态
+func (a *App) GetMarketStatus() ([]map[string]interface{}, error) {
+	return a.calendarService.MarketStatuses(a.ctx)
+}
+
+// GetHolidayTableInfo 获取休市日历的版本、来源和各市场覆盖到的日期
+func (a *App) GetHolidayTableInfo() map[string]interface{} {
+	return a.calendarService.HolidayTableInfo(a.ctx)
+}
+
+// ImportHolidayTable 选择并导入休市日历文件，用户取消时返回 nil
+func (a *App) ImportHolidayTable() (map[string]interface{}, error) {
+	path, err := runtime.OpenFileDialog(a.ctx, runtime.OpenDialogOptions{
+		Title: "选择休市日历",
+		Filters: []runtime.FileFilter{
+			{
+				DisplayName: "JSON 文件 (*.json)",
+				Pattern:     "*.json",
+			},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file dialog: %w", err)
+	}
+
+	// 用户取消了对话框
+	if path == "" {
+		return nil, nil
+	}
+	return a.calendarService.ImportHolidayTable(a.ctx, path)
+}
+
+// ResetHolidayTable 删除导入的休市日历，恢复使用内置日历
+func (a *App) ResetHolidayTable() error {
+	return a.calendarService.ResetHolidayTable(a.ctx)
+}
+
+// GetIndexDefs 获取所有已添加的指数（按显示顺序）
+func (a *App) GetIndexDefs() ([]map[string]interface{}, error) {
+	return a.indexService.GetIndexDefs(a.ctx)
+}
+
+// GetIndexPresets 获取尚未添加的常见指数
+func (a *App) GetIndexPresets() ([]map[string]interface{}, error) {
+	return a.indexService.IndexPresets(a.ctx)
+}
+
+// AddIndex 添加指数并显示在行情栏
+// secID: 东方财富行情 ID，如 1.000905；market: cn/hk/us；currency 为空时按市场推断
+func (a *App) AddIndex(code, name, secID, market, currency string) error {
+	return a.indexService.AddIndex(a.ctx, code, name, secID, market, currency)
+}
+
+// RemoveIndex 删除指数
+func (a *App) RemoveIndex(code string) error {
+	return a.indexService.RemoveIndex(a.ctx, code)
+}
+
+// SetIndexWatched 设置指数是否显示在行情栏
+func (a *App) SetIndexWatched(code string, watch bool) error {
+	return a.indexService.SetIndexWatched(a.ctx, code, watch)
+}
+
+// ReorderIndexes 按给定的代码顺序排列指数
+func (a *App) ReorderIndexes(codes []string) error {
+	return a.indexService.ReorderIndexes(a.ctx, codes)
+}
+
+// GetIndexHistory 获取指数日线（from/to 格式为 2006-01-02，空表示不限），无法联网时返回本地缓存
+func (a *App) GetIndexHistory(code, from, to string) (map[string]interface{}, error) {
+	return a.indexHistory.Series(a.ctx, code, from, to)
+}
+
+// RefreshIndexHistory 立即增量下载指数日线
+func (a *App) RefreshIndexHistory(code string) error {
+	return a.indexHistory.Refresh(a.ctx, code)
+}
+
+// GetIndexHistoryStatus 获取各指数日线缓存的状态
+func (a *App) GetIndexHistoryStatus() ([]map[string]interface{}, error) {
+	return a.indexHistory.Status(a.ctx)
 }
 
 // GetDBInfo 获取数据库信息
@@ -248,9 +763,9 @@ func (a *App) BackupDatabase() error {
 	return nil
 }
 
-// SaveRebalance 保存再平衡记录
-func (a *App) SaveRebalance(stockRatio, bondRatio, totalAmount, stockAmount, bondAmount, targetStockRatio, targetBondRatio float64, note string) error {
-	return a.rebalanceService.SaveRebalance(a.ctx, stockRatio, bondRatio, totalAmount, stockAmount, bondAmount, targetStockRatio, targetBondRatio, note)
+// SaveRebalance 按当前持仓保存再平衡记录
+func (a *App) SaveRebalance(targetStockRatio float64, note string) error {
+	return a.rebalanceService.SaveRebalance(a.ctx, targetStockRatio, note)
 }
 
 // GetRebalanceHistory 获取再平衡历史记录
@@ -258,12 +773,207 @@ func (a *App) GetRebalanceHistory() ([]map[string]interface{}, error) {
 	return a.rebalanceService.GetRebalanceHistory(a.ctx)
 }
 
+// QueryRebalanceHistory 按时间区间查询再平衡记录，参数同 QueryHistory
+func (a *App) QueryRebalanceHistory(from, to, period string, limit, offset int) (map[string]interface{}, error) {
+	return a.rebalanceService.QueryRebalanceHistory(a.ctx, service.TimeQuery{From: from, To: to, Period: period, Limit: limit, Offset: offset})
+}
+
 // GetLatestRebalance 获取最新的再平衡记录
 func (a *App) GetLatestRebalance() (map[string]interface{}, error) {
 	return a.rebalanceService.GetLatestRebalance(a.ctx)
 }
 
+// GetRebalanceHoldings 获取再平衡记录的持仓快照
+func (a *App) GetRebalanceHoldings(id uint) ([]map[string]interface{}, error) {
+	return a.rebalanceService.GetRebalanceHoldings(a.ctx, id)
+}
+
+// CreateRebalancePlan 按当前持仓生成并保存待执行的再平衡计划，返回记录 ID
+func (a *App) CreateRebalancePlan(targetStockRatio float64, minTrade float64, note string) (uint, error) {
+	return a.rebalanceService.CreateRebalancePlan(a.ctx, targetStockRatio, minTrade, note)
+}
+
+// GetRebalanceOrders 获取再平衡计划的交易及执行情况
+func (a *App) GetRebalanceOrders(rebalanceID uint) ([]map[string]interface{}, error) {
+	return a.rebalanceService.GetRebalanceOrders(a.ctx, rebalanceID)
+}
+
+// UpdateOrderStatus 更新再平衡交易状态（pending/submitted/cancelled）
+func (a *App) UpdateOrderStatus(orderID uint, status string) error {
+	return a.rebalanceService.UpdateOrderStatus(a.ctx, orderID, status)
+}
+
+// FillOrder 确认再平衡交易成交并更新资产持仓（日期格式 2006-01-02）
+func (a *App) FillOrder(orderID uint, amount float64, filledDate string) error {
+	if err := a.rebalanceService.FillOrder(a.ctx, orderID, amount, filledDate); err != nil {
+		return err
+	}
+	a.snapshotAfterChange()
+	return nil
+}
+
+// GetOpenRebalancePlans 获取尚未完成的再平衡计划
+func (a *App) GetOpenRebalancePlans() ([]map[string]interface{}, error) {
+	return a.rebalanceService.GetOpenRebalancePlans(a.ctx)
+}
+
 // DeleteRebalance 删除再平衡记录
 func (a *App) DeleteRebalance(id uint) error {
 	return a.rebalanceService.DeleteRebalance(a.ctx, id)
 }
+
+// SelectImportFile 选择要导入的持仓文件
+func (a *App) SelectImportFile() (string, error) {
+	path, err := runtime.OpenFileDialog(a.ctx, runtime.OpenDialogOptions{
+		Title: "选择持仓文件",
+		Filters: []runtime.FileFilter{
+			{
+				DisplayName: "持仓/账单文件 (*.csv;*.xlsx;*.xls;*.txt)",
+				Pattern:     "*.csv;*.xlsx;*.xls;*.txt",
+			},
+		},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to open file dialog: %w", err)
+	}
+	return path, nil
+}
+
+// GetImportColumns 获取导入文件的表头
+func (a *App) GetImportColumns(path string) ([]string, error) {
+	return a.importService.GetColumns(a.ctx, path)
+}
+
+// PreviewImport 预览导入（列映射: code/name/source/type/amount/shares/nav -> 表头）
+func (a *App) PreviewImport(path string, mapping map[string]string, defaultSource string) (map[string]interface{}, error) {
+	return a.importService.PreviewImport(a.ctx, path, mapping, defaultSource)
+}
+
+// ApplyImport 执行导入
+func (a *App) ApplyImport(path string, mapping map[string]string, defaultSource string) (map[string]interface{}, error) {
+	result, err := a.importService.ApplyImport(a.ctx, path, mapping, defaultSource)
+	if err != nil {
+		return nil, err
+	}
+	a.snapshotAfterChange()
+	return result, nil
+}
+
+// GetStatementParsers 获取支持的平台账单解析器
+func (a *App) GetStatementParsers() []map[string]interface{} {
+	return a.importService.GetStatementParsers(a.ctx)
+}
+
+// PreviewStatement 预览平台账单导入（parser 为空时自动识别）
+func (a *App) PreviewStatement(path string, parser string) (map[string]interface{}, error) {
+	return a.importService.PreviewStatement(a.ctx, path, parser)
+}
+
+// ApplyStatement 导入平台账单
+func (a *App) ApplyStatement(path string, parser string) (map[string]interface{}, error) {
+	result, err := a.importService.ApplyStatement(a.ctx, path, parser)
+	if err != nil {
+		return nil, err
+	}
+	a.snapshotAfterChange()
+	return result, nil
+}
+
+// ExportData 导出全部数据（format: csv/json/xlsx），需要已登录
+func (a *App) ExportData(format string) error {
+	if !a.isAuthenticated {
+		return errors.New("请先登录")
+	}
+
+	var filter runtime.FileFilter
+	var ext string
+	switch format {
+	case service.ExportFormatCSV:
+		ext = "zip"
+		filter = runtime.FileFilter{DisplayName: "CSV 压缩包 (*.zip)", Pattern: "*.zip"}
+	case service.ExportFormatJSON:
+		ext = "json"
+		filter = runtime.FileFilter{DisplayName: "JSON 文件 (*.json)", Pattern: "*.json"}
+	case service.ExportFormatXLSX:
+		ext = "xlsx"
+		filter = runtime.FileFilter{DisplayName: "Excel 文件 (*.xlsx)", Pattern: "*.xlsx"}
+	default:
+		return fmt.Errorf("不支持的导出格式: %s", format)
+	}
+
+	savePath, err := runtime.SaveFileDialog(a.ctx, runtime.SaveDialogOptions{
+		DefaultFilename: fmt.Sprintf("margin_export_%s.%s", time.Now().Format("20060102_150405"), ext),
+		Title:           "导出数据",
+		Filters:         []runtime.FileFilter{filter},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to open save dialog: %w", err)
+	}
+
+	// 用户取消了对话框
+	if savePath == "" {
+		return nil
+	}
+
+	return a.exportService.Export(a.ctx, format, savePath)
+}
+
+// ExportInterchange 导出迁移文件（passphrase 为空时金额为明文），需要已登录
+func (a *App) ExportInterchange(passphrase string) error {
+	if !a.isAuthenticated {
+		return errors.New("请先登录")
+	}
+
+	savePath, err := runtime.SaveFileDialog(a.ctx, runtime.SaveDialogOptions{
+		DefaultFilename: fmt.Sprintf("margin_migrate_%s.json", time.Now().Format("20060102_150405")),
+		Title:           "导出迁移文件",
+		Filters: []runtime.FileFilter{
+			{
+				DisplayName: "JSON 文件 (*.json)",
+				Pattern:     "*.json",
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to open save dialog: %w", err)
+	}
+
+	// 用户取消了对话框
+	if savePath == "" {
+		return nil
+	}
+
+	return a.interchangeService.Export(a.ctx, savePath, passphrase)
+}
+
+// ImportInterchange 导入迁移文件（mode: merge/replace），需要已登录
+func (a *App) ImportInterchange(passphrase string, mode string) (map[string]interface{}, error) {
+	if !a.isAuthenticated {
+		return nil, errors.New("请先登录")
+	}
+
+	path, err := runtime.OpenFileDialog(a.ctx, runtime.OpenDialogOptions{
+		Title: "选择迁移文件",
+		Filters: []runtime.FileFilter{
+			{
+				DisplayName: "JSON 文件 (*.json)",
+				Pattern:     "*.json",
+			},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file dialog: %w", err)
+	}
+
+	// 用户取消了对话框
+	if path == "" {
+		return nil, nil
+	}
+
+	result, err := a.interchangeService.Import(a.ctx, path, passphrase, mode)
+	if err != nil {
+		return nil, err
+	}
+	a.snapshotAfterChange()
+	return result, nil
+}
@@ -1,58 +1,200 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
+export function AddAssetLot(arg1:number,arg2:string,arg3:number):Promise<void>;
+
+export function AddCashFlow(arg1:number,arg2:string,arg3:string,arg4:string,arg5:string,arg6:number,arg7:string):Promise<void>;
+
+export function AddIndex(arg1:string,arg2:string,arg3:string,arg4:string,arg5:string):Promise<void>;
+
 export function AddSource(arg1:string):Promise<void>;
 
+export function ApplyImport(arg1:string,arg2:Record<string, string>,arg3:string):Promise<Record<string, any>>;
+
+export function ApplyStatement(arg1:string,arg2:string):Promise<Record<string, any>>;
+
 export function BackupDatabase():Promise<void>;
 
+export function CompareBenchmarks(arg1:Array<string>,arg2:string,arg3:string):Promise<Record<string, any>>;
+
+export function CreateRebalancePlan(arg1:number,arg2:number,arg3:string):Promise<number>;
+
 export function DeleteAsset(arg1:number):Promise<void>;
 
+export function DeleteAssetLot(arg1:number):Promise<void>;
+
+export function DeleteCashFlow(arg1:number):Promise<void>;
+
+export function DeleteFeeRule(arg1:number):Promise<void>;
+
 export function DeleteHistory(arg1:number):Promise<void>;
 
 export function DeleteRebalance(arg1:number):Promise<void>;
 
+export function DeleteRebalanceReview(arg1:number):Promise<void>;
+
 export function DeleteSource(arg1:number):Promise<void>;
 
+export function DiffHistory(arg1:number,arg2:number):Promise<Record<string, any>>;
+
+export function ExportData(arg1:string):Promise<void>;
+
+export function ExportInterchange(arg1:string):Promise<void>;
+
+export function FetchFeeRules(arg1:string):Promise<number>;
+
+export function FillOrder(arg1:number,arg2:number,arg3:string):Promise<void>;
+
 export function GetAllIndexes():Promise<Array<Record<string, any>>>;
 
+export function GetAssetLots(arg1:number):Promise<Array<Record<string, any>>>;
+
 export function GetAssets():Promise<Array<Record<string, any>>>;
 
+export function GetBacktestIndexes():Promise<Array<Record<string, any>>>;
+
+export function GetBenchmarkIndexes():Promise<Array<Record<string, any>>>;
+
+export function GetCashFlows():Promise<Array<Record<string, any>>>;
+
 export function GetDBInfo():Promise<Record<string, any>>;
 
+export function GetDueReview():Promise<Record<string, any>>;
+
+export function GetFeeRules(arg1:string):Promise<Array<Record<string, any>>>;
+
 export function GetFundInfo(arg1:string):Promise<Record<string, any>>;
 
+export function GetGlidePath():Promise<Record<string, any>>;
+
+export function GetGlidePathSeries(arg1:number):Promise<Array<Record<string, any>>>;
+
 export function GetHistory():Promise<Array<Record<string, any>>>;
 
+export function GetHistoryItems(arg1:number):Promise<Array<Record<string, any>>>;
+
+export function GetHolidayTableInfo():Promise<Record<string, any>>;
+
+export function GetImportColumns(arg1:string):Promise<Array<string>>;
+
 export function GetIndexData(arg1:string):Promise<Record<string, any>>;
 
+export function GetIndexDefs():Promise<Array<Record<string, any>>>;
+
+export function GetIndexHistory(arg1:string,arg2:string,arg3:string):Promise<Record<string, any>>;
+
+export function GetIndexHistoryStatus():Promise<Array<Record<string, any>>>;
+
+export function GetIndexPresets():Promise<Array<Record<string, any>>>;
+
 export function GetLatestRebalance():Promise<Record<string, any>>;
 
+export function GetMarketStatus():Promise<Array<Record<string, any>>>;
+
+export function GetMonteCarloDefaults():Promise<Record<string, any>>;
+
+export function GetOpenRebalancePlans():Promise<Array<Record<string, any>>>;
+
+export function GetPerformance(arg1:string,arg2:string,arg3:number):Promise<Record<string, any>>;
+
 export function GetPortfolioRatio():Promise<Record<string, number>>;
 
 export function GetRebalanceAdvice(arg1:number):Promise<Record<string, any>>;
 
+export function GetRebalanceBands():Promise<Record<string, any>>;
+
 export function GetRebalanceHistory():Promise<Array<Record<string, any>>>;
 
+export function GetRebalanceHoldings(arg1:number):Promise<Array<Record<string, any>>>;
+
+export function GetRebalanceOrders(arg1:number):Promise<Array<Record<string, any>>>;
+
+export function GetRebalancePlan(arg1:number,arg2:number):Promise<Record<string, any>>;
+
+export function GetRebalanceReviews():Promise<Array<Record<string, any>>>;
+
+export function GetRebalanceSchedule():Promise<Record<string, any>>;
+
+export function GetSnapshotPolicy():Promise<Record<string, any>>;
+
 export function GetSources():Promise<Array<Record<string, any>>>;
 
+export function GetStatementParsers():Promise<Array<Record<string, any>>>;
+
 export function GetSystemInfo():Promise<Record<string, any>>;
 
+export function GetValuationConfig():Promise<Record<string, any>>;
+
+export function GetValuationSuggestion():Promise<Record<string, any>>;
+
+export function ImportHolidayTable():Promise<Record<string, any>>;
+
+export function ImportInterchange(arg1:string,arg2:string):Promise<Record<string, any>>;
+
 export function IsAuthenticated():Promise<boolean>;
 
 export function IsFirstRun():Promise<boolean>;
 
 export function Logout():Promise<void>;
 
+export function PlanContribution(arg1:number,arg2:number):Promise<Record<string, any>>;
+
+export function PreviewImport(arg1:string,arg2:Record<string, string>,arg3:string):Promise<Record<string, any>>;
+
+export function PreviewStatement(arg1:string,arg2:string):Promise<Record<string, any>>;
+
+export function QueryHistory(arg1:string,arg2:string,arg3:string,arg4:number,arg5:number):Promise<Record<string, any>>;
+
+export function QueryRebalanceHistory(arg1:string,arg2:string,arg3:string,arg4:number,arg5:number):Promise<Record<string, any>>;
+
+export function RefreshIndexHistory(arg1:string):Promise<void>;
+
+export function RefreshIndexes():Promise<Array<Record<string, any>>>;
+
+export function RemoveIndex(arg1:string):Promise<void>;
+
+export function ReorderIndexes(arg1:Array<string>):Promise<void>;
+
+export function ResetHolidayTable():Promise<void>;
+
+export function RunBacktest(arg1:string,arg2:string,arg3:string,arg4:string,arg5:number,arg6:string,arg7:number):Promise<Record<string, any>>;
+
+export function RunMonteCarlo(arg1:string,arg2:number,arg3:number,arg4:number,arg5:number,arg6:number,arg7:number,arg8:number,arg9:number,arg10:number,arg11:number,arg12:number,arg13:number,arg14:number,arg15:number):Promise<Record<string, any>>;
+
+export function RunRebalanceReview(arg1:number,arg2:string):Promise<Record<string, any>>;
+
 export function SaveAsset(arg1:string,arg2:string,arg3:string,arg4:string,arg5:string,arg6:number):Promise<void>;
 
-export function SaveRebalance(arg1:number,arg2:number,arg3:number,arg4:number,arg5:number,arg6:number,arg7:number,arg8:string):Promise<void>;
+export function SaveFeeRule(arg1:string,arg2:string,arg3:string,arg4:number,arg5:number,arg6:number,arg7:number,arg8:number,arg9:number):Promise<void>;
+
+export function SaveGlidePath(arg1:boolean,arg2:string,arg3:number,arg4:string,arg5:string,arg6:number,arg7:number,arg8:number,arg9:number,arg10:number,arg11:Array<number>,arg12:Array<number>):Promise<void>;
+
+export function SaveRebalance(arg1:number,arg2:string):Promise<void>;
+
+export function SaveRebalanceBands(arg1:string,arg2:number,arg3:number,arg4:string,arg5:number,arg6:number):Promise<void>;
+
+export function SaveRebalanceSchedule(arg1:string,arg2:number,arg3:Array<string>,arg4:number):Promise<void>;
 
 export function SaveSnapshot():Promise<void>;
 
+export function SaveSnapshotPolicy(arg1:boolean,arg2:boolean):Promise<void>;
+
+export function SaveValuationConfig(arg1:boolean,arg2:string,arg3:string,arg4:number,arg5:Array<number>,arg6:Array<number>):Promise<void>;
+
+export function SelectImportFile():Promise<string>;
+
+export function SetAssetTargetWeight(arg1:number,arg2:number):Promise<void>;
+
+export function SetIndexWatched(arg1:string,arg2:boolean):Promise<void>;
+
 export function SetPassword(arg1:string):Promise<void>;
 
 export function UpdateAsset(arg1:number,arg2:string,arg3:string,arg4:number):Promise<void>;
 
 export function UpdateAssetAmount(arg1:number,arg2:number):Promise<void>;
 
+export function UpdateOrderStatus(arg1:number,arg2:string):Promise<void>;
+
+export function UpdateRebalanceReview(arg1:number,arg2:string,arg3:string):Promise<void>;
+
 export function VerifyPassword(arg1:string):Promise<boolean>;
@@ -2,18 +2,58 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
+export function AddAssetLot(arg1, arg2, arg3) {
+  return window['go']['main']['App']['AddAssetLot'](arg1, arg2, arg3);
+}
+
+export function AddCashFlow(arg1, arg2, arg3, arg4, arg5, arg6, arg7) {
+  return window['go']['main']['App']['AddCashFlow'](arg1, arg2, arg3, arg4, arg5, arg6, arg7);
+}
+
+export function AddIndex(arg1, arg2, arg3, arg4, arg5) {
+  return window['go']['main']['App']['AddIndex'](arg1, arg2, arg3, arg4, arg5);
+}
+
 export function AddSource(arg1) {
   return window['go']['main']['App']['AddSource'](arg1);
 }
 
+export function ApplyImport(arg1, arg2, arg3) {
+  return window['go']['main']['App']['ApplyImport'](arg1, arg2, arg3);
+}
+
+export function ApplyStatement(arg1, arg2) {
+  return window['go']['main']['App']['ApplyStatement'](arg1, arg2);
+}
+
 export function BackupDatabase() {
   return window['go']['main']['App']['BackupDatabase']();
 }
 
+export function CompareBenchmarks(arg1, arg2, arg3) {
+  return window['go']['main']['App']['CompareBenchmarks'](arg1, arg2, arg3);
+}
+
+export function CreateRebalancePlan(arg1, arg2, arg3) {
+  return window['go']['main']['App']['CreateRebalancePlan'](arg1, arg2, arg3);
+}
+
 export function DeleteAsset(arg1) {
   return window['go']['main']['App']['DeleteAsset'](arg1);
 }
 
+export function DeleteAssetLot(arg1) {
+  return window['go']['main']['App']['DeleteAssetLot'](arg1);
+}
+
+export function DeleteCashFlow(arg1) {
+  return window['go']['main']['App']['DeleteCashFlow'](arg1);
+}
+
+export function DeleteFeeRule(arg1) {
+  return window['go']['main']['App']['DeleteFeeRule'](arg1);
+}
+
 export function DeleteHistory(arg1) {
   return window['go']['main']['App']['DeleteHistory'](arg1);
 }
@@ -22,38 +62,138 @@ export function DeleteRebalance(arg1) {
   return window['go']['main']['App']['DeleteRebalance'](arg1);
 }
 
+export function DeleteRebalanceReview(arg1) {
+  return window['go']['main']['App']['DeleteRebalanceReview'](arg1);
+}
+
 export function DeleteSource(arg1) {
   return window['go']['main']['App']['DeleteSource'](arg1);
 }
 
+export function DiffHistory(arg1, arg2) {
+  return window['go']['main']['App']['DiffHistory'](arg1, arg2);
+}
+
+export function ExportData(arg1) {
+  return window['go']['main']['App']['ExportData'](arg1);
+}
+
+export function ExportInterchange(arg1) {
+  return window['go']['main']['App']['ExportInterchange'](arg1);
+}
+
+export function FetchFeeRules(arg1) {
+  return window['go']['main']['App']['FetchFeeRules'](arg1);
+}
+
+export function FillOrder(arg1, arg2, arg3) {
+  return window['go']['main']['App']['FillOrder'](arg1, arg2, arg3);
+}
+
 export function GetAllIndexes() {
   return window['go']['main']['App']['GetAllIndexes']();
 }
 
+export function GetAssetLots(arg1) {
+  return window['go']['main']['App']['GetAssetLots'](arg1);
+}
+
 export function GetAssets() {
   return window['go']['main']['App']['GetAssets']();
 }
 
+export function GetBacktestIndexes() {
+  return window['go']['main']['App']['GetBacktestIndexes']();
+}
+
+export function GetBenchmarkIndexes() {
+  return window['go']['main']['App']['GetBenchmarkIndexes']();
+}
+
+export function GetCashFlows() {
+  return window['go']['main']['App']['GetCashFlows']();
+}
+
 export function GetDBInfo() {
   return window['go']['main']['App']['GetDBInfo']();
 }
 
+export function GetDueReview() {
+  return window['go']['main']['App']['GetDueReview']();
+}
+
+export function GetFeeRules(arg1) {
+  return window['go']['main']['App']['GetFeeRules'](arg1);
+}
+
 export function GetFundInfo(arg1) {
   return window['go']['main']['App']['GetFundInfo'](arg1);
 }
 
+export function GetGlidePath() {
+  return window['go']['main']['App']['GetGlidePath']();
+}
+
+export function GetGlidePathSeries(arg1) {
+  return window['go']['main']['App']['GetGlidePathSeries'](arg1);
+}
+
 export function GetHistory() {
   return window['go']['main']['App']['GetHistory']();
 }
 
+export function GetHistoryItems(arg1) {
+  return window['go']['main']['App']['GetHistoryItems'](arg1);
+}
+
+export function GetHolidayTableInfo() {
+  return window['go']['main']['App']['GetHolidayTableInfo']();
+}
+
+export function GetImportColumns(arg1) {
+  return window['go']['main']['App']['GetImportColumns'](arg1);
+}
+
 export function GetIndexData(arg1) {
   return window['go']['main']['App']['GetIndexData'](arg1);
 }
 
+export function GetIndexDefs() {
+  return window['go']['main']['App']['GetIndexDefs']();
+}
+
+export function GetIndexHistory(arg1, arg2, arg3) {
+  return window['go']['main']['App']['GetIndexHistory'](arg1, arg2, arg3);
+}
+
+export function GetIndexHistoryStatus() {
+  return window['go']['main']['App']['GetIndexHistoryStatus']();
+}
+
+export function GetIndexPresets() {
+  return window['go']['main']['App']['GetIndexPresets']();
+}
+
 export function GetLatestRebalance() {
   return window['go']['main']['App']['GetLatestRebalance']();
 }
 
+export function GetMarketStatus() {
+  return window['go']['main']['App']['GetMarketStatus']();
+}
+
+export function GetMonteCarloDefaults() {
+  return window['go']['main']['App']['GetMonteCarloDefaults']();
+}
+
+export function GetOpenRebalancePlans() {
+  return window['go']['main']['App']['GetOpenRebalancePlans']();
+}
+
+export function GetPerformance(arg1, arg2, arg3) {
+  return window['go']['main']['App']['GetPerformance'](arg1, arg2, arg3);
+}
+
 export function GetPortfolioRatio() {
   return window['go']['main']['App']['GetPortfolioRatio']();
 }
@@ -62,18 +202,66 @@ export function GetRebalanceAdvice(arg1) {
   return window['go']['main']['App']['GetRebalanceAdvice'](arg1);
 }
 
+export function GetRebalanceBands() {
+  return window['go']['main']['App']['GetRebalanceBands']();
+}
+
 export function GetRebalanceHistory() {
   return window['go']['main']['App']['GetRebalanceHistory']();
 }
 
+export function GetRebalanceHoldings(arg1) {
+  return window['go']['main']['App']['GetRebalanceHoldings'](arg1);
+}
+
+export function GetRebalanceOrders(arg1) {
+  return window['go']['main']['App']['GetRebalanceOrders'](arg1);
+}
+
+export function GetRebalancePlan(arg1, arg2) {
+  return window['go']['main']['App']['GetRebalancePlan'](arg1, arg2);
+}
+
+export function GetRebalanceReviews() {
+  return window['go']['main']['App']['GetRebalanceReviews']();
+}
+
+export function GetRebalanceSchedule() {
+  return window['go']['main']['App']['GetRebalanceSchedule']();
+}
+
+export function GetSnapshotPolicy() {
+  return window['go']['main']['App']['GetSnapshotPolicy']();
+}
+
 export function GetSources() {
   return window['go']['main']['App']['GetSources']();
 }
 
+export function GetStatementParsers() {
+  return window['go']['main']['App']['GetStatementParsers']();
+}
+
 export function GetSystemInfo() {
   return window['go']['main']['App']['GetSystemInfo']();
 }
 
+export function GetValuationConfig() {
+  return window['go']['main']['App']['GetValuationConfig']();
+}
+
+export function GetValuationSuggestion() {
+  return window['go']['main']['App']['GetValuationSuggestion']();
+}
+
+export function ImportHolidayTable() {
+  return window['go']['main']['App']['ImportHolidayTable']();
+}
+
+export function ImportInterchange(arg1, arg2) {
+  return window['go']['main']['App']['ImportInterchange'](arg1, arg2);
+}
+
 export function IsAuthenticated() {
   return window['go']['main']['App']['IsAuthenticated']();
 }
@@ -86,18 +274,106 @@ export function Logout() {
   return window['go']['main']['App']['Logout']();
 }
 
+export function PlanContribution(arg1, arg2) {
+  return window['go']['main']['App']['PlanContribution'](arg1, arg2);
+}
+
+export function PreviewImport(arg1, arg2, arg3) {
+  return window['go']['main']['App']['PreviewImport'](arg1, arg2, arg3);
+}
+
+export function PreviewStatement(arg1, arg2) {
+  return window['go']['main']['App']['PreviewStatement'](arg1, arg2);
+}
+
+export function QueryHistory(arg1, arg2, arg3, arg4, arg5) {
+  return window['go']['main']['App']['QueryHistory'](arg1, arg2, arg3, arg4, arg5);
+}
+
+export function QueryRebalanceHistory(arg1, arg2, arg3, arg4, arg5) {
+  return window['go']['main']['App']['QueryRebalanceHistory'](arg1, arg2, arg3, arg4, arg5);
+}
+
+export function RefreshIndexHistory(arg1) {
+  return window['go']['main']['App']['RefreshIndexHistory'](arg1);
+}
+
+export function RefreshIndexes() {
+  return window['go']['main']['App']['RefreshIndexes']();
+}
+
+export function RemoveIndex(arg1) {
+  return window['go']['main']['App']['RemoveIndex'](arg1);
+}
+
+export function ReorderIndexes(arg1) {
+  return window['go']['main']['App']['ReorderIndexes'](arg1);
+}
+
+export function ResetHolidayTable() {
+  return window['go']['main']['App']['ResetHolidayTable']();
+}
+
+export function RunBacktest(arg1, arg2, arg3, arg4, arg5, arg6, arg7) {
+  return window['go']['main']['App']['RunBacktest'](arg1, arg2, arg3, arg4, arg5, arg6, arg7);
+}
+
+export function RunMonteCarlo(arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8, arg9, arg10, arg11, arg12, arg13, arg14, arg15) {
+  return window['go']['main']['App']['RunMonteCarlo'](arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8, arg9, arg10, arg11, arg12, arg13, arg14, arg15);
+}
+
+export function RunRebalanceReview(arg1, arg2) {
+  return window['go']['main']['App']['RunRebalanceReview'](arg1, arg2);
+}
+
 export function SaveAsset(arg1, arg2, arg3, arg4, arg5, arg6) {
   return window['go']['main']['App']['SaveAsset'](arg1, arg2, arg3, arg4, arg5, arg6);
 }
 
-export function SaveRebalance(arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8) {
-  return window['go']['main']['App']['SaveRebalance'](arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8);
+export function SaveFeeRule(arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8, arg9) {
+  return window['go']['main']['App']['SaveFeeRule'](arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8, arg9);
+}
+
+export function SaveGlidePath(arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8, arg9, arg10, arg11, arg12) {
+  return window['go']['main']['App']['SaveGlidePath'](arg1, arg2, arg3, arg4, arg5, arg6, arg7, arg8, arg9, arg10, arg11, arg12);
+}
+
+export function SaveRebalance(arg1, arg2) {
+  return window['go']['main']['App']['SaveRebalance'](arg1, arg2);
+}
+
+export function SaveRebalanceBands(arg1, arg2, arg3, arg4, arg5, arg6) {
+  return window['go']['main']['App']['SaveRebalanceBands'](arg1, arg2, arg3, arg4, arg5, arg6);
+}
+
+export function SaveRebalanceSchedule(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['SaveRebalanceSchedule'](arg1, arg2, arg3, arg4);
 }
 
 export function SaveSnapshot() {
   return window['go']['main']['App']['SaveSnapshot']();
 }
 
+export function SaveSnapshotPolicy(arg1, arg2) {
+  return window['go']['main']['App']['SaveSnapshotPolicy'](arg1, arg2);
+}
+
+export function SaveValuationConfig(arg1, arg2, arg3, arg4, arg5, arg6) {
+  return window['go']['main']['App']['SaveValuationConfig'](arg1, arg2, arg3, arg4, arg5, arg6);
+}
+
+export function SelectImportFile() {
+  return window['go']['main']['App']['SelectImportFile']();
+}
+
+export function SetAssetTargetWeight(arg1, arg2) {
+  return window['go']['main']['App']['SetAssetTargetWeight'](arg1, arg2);
+}
+
+export function SetIndexWatched(arg1, arg2) {
+  return window['go']['main']['App']['SetIndexWatched'](arg1, arg2);
+}
+
 export function SetPassword(arg1) {
   return window['go']['main']['App']['SetPassword'](arg1);
 }
@@ -110,6 +386,14 @@ export function UpdateAssetAmount(arg1, arg2) {
   return window['go']['main']['App']['UpdateAssetAmount'](arg1, arg2);
 }
 
+export function UpdateOrderStatus(arg1, arg2) {
+  return window['go']['main']['App']['UpdateOrderStatus'](arg1, arg2);
+}
+
+export function UpdateRebalanceReview(arg1, arg2, arg3) {
+  return window['go']['main']['App']['UpdateRebalanceReview'](arg1, arg2, arg3);
+}
+
 export function VerifyPassword(arg1) {
   return window['go']['main']['App']['VerifyPassword'](arg1);
 }
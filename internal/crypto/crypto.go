@@ -3,6 +3,7 @@ package crypto
 import (
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/pbkdf2"
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/base64"
@@ -19,6 +20,29 @@ func GenerateKey() (string, error) {
 	return base64.StdEncoding.EncodeToString(key), nil
 }
 
+// GenerateSalt 生成随机盐值
+func GenerateSalt() (string, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(salt), nil
+}
+
+// DeriveKey 使用 PBKDF2-SHA256 从口令派生加密密钥，返回值可直接用于 Encrypt/Decrypt
+func DeriveKey(passphrase, saltStr string, iterations int) (string, error) {
+	salt, err := base64.StdEncoding.DecodeString(saltStr)
+	if err != nil {
+		return "", err
+	}
+
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, iterations, 32)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
 // HashPassword 哈希密码
 func HashPassword(password string) string {
 	hash := sha256.Sum256([]byte(password))
@@ -0,0 +1,212 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/xuri/excelize/v2"
+)
+
+// Document 平台导出的账单文件（可能包含标题、汇总等非表格内容）
+type Document struct {
+	Name string     // 文件名
+	Text string     // 全部文本内容，用于识别平台
+	Rows [][]string // 所有原始行（含表头之前的说明行）
+}
+
+var (
+	oleMagic     = []byte{0xd0, 0xcf, 0x11, 0xe0}
+	textSplitter = regexp.MustCompile(`\t|\s{2,}`)
+)
+
+// LoadDocument 读取平台账单：CSV、XLSX、XLS（HTML 表格格式）以及 PDF 复制出的文本
+func LoadDocument(path string) (*Document, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取文件失败: %w", err)
+	}
+
+	doc := &Document{Name: filepath.Base(path)}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		doc.Rows, err = csvRows(decodeText(data))
+	case ".xlsx":
+		doc.Rows, err = xlsxRows(data)
+	case ".xls":
+		// 多数平台导出的 .xls 实际是 HTML 表格，真正的二进制 xls 需另存为 xlsx
+		if bytes.HasPrefix(data, oleMagic) {
+			return nil, errors.New("暂不支持二进制 XLS 文件，请另存为 XLSX 后导入")
+		}
+		doc.Rows, doc.Text, err = htmlRows(decodeText(data))
+	case ".txt":
+		doc.Rows = textRows(decodeText(data))
+	default:
+		return nil, fmt.Errorf("不支持的文件格式: %s", filepath.Ext(path))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if doc.Text == "" {
+		lines := make([]string, 0, len(doc.Rows))
+		for _, row := range doc.Rows {
+			lines = append(lines, strings.Join(row, " "))
+		}
+		doc.Text = strings.Join(lines, "\n")
+	}
+
+	return doc, nil
+}
+
+// Contains 判断文本中是否包含任意一个关键字
+func (d *Document) Contains(keywords ...string) bool {
+	for _, keyword := range keywords {
+		if strings.Contains(d.Text, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// FindTable 查找第一个同时包含所有指定列（任一别名即可）的表头行，
+// 并截取到下一个空行为止作为表格
+func (d *Document) FindTable(columns ...[]string) *Table {
+	for i, row := range d.Rows {
+		headers := make([]string, len(row))
+		for j, cell := range row {
+			headers[j] = normalizeHeader(cell)
+		}
+
+		candidate := &Table{Headers: headers}
+		matched := true
+		for _, aliases := range columns {
+			if candidate.FindColumn(aliases...) < 0 {
+				matched = false
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+
+		for _, r := range d.Rows[i+1:] {
+			if isBlankRow(r) {
+				break
+			}
+			candidate.Rows = append(candidate.Rows, r)
+		}
+		return candidate
+	}
+	return nil
+}
+
+// FindColumn 按别名顺序查找列，不存在返回 -1
+func (t *Table) FindColumn(aliases ...string) int {
+	for _, alias := range aliases {
+		if idx := t.ColumnIndex(alias); idx >= 0 {
+			return idx
+		}
+	}
+	return -1
+}
+
+// normalizeHeader 去除表头中的空白和单位说明，如"持有金额(元)" -> "持有金额"
+func normalizeHeader(s string) string {
+	s = strings.Join(strings.Fields(s), "")
+	for _, unit := range []string{"(元)", "（元）", "(份)", "（份）"} {
+		s = strings.TrimSuffix(s, unit)
+	}
+	return s
+}
+
+func csvRows(data []byte) ([][]string, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	var rows [][]string
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("解析 CSV 失败: %w", err)
+		}
+		rows = append(rows, trimRow(record))
+	}
+	return rows, nil
+}
+
+func xlsxRows(data []byte) ([][]string, error) {
+	f, err := excelize.OpenReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("解析 XLSX 失败: %w", err)
+	}
+	defer f.Close()
+
+	var rows [][]string
+	for _, sheet := range f.GetSheetList() {
+		sheetRows, err := f.GetRows(sheet)
+		if err != nil {
+			return nil, fmt.Errorf("读取工作表失败: %w", err)
+		}
+		for _, row := range sheetRows {
+			rows = append(rows, trimRow(row))
+		}
+		// 工作表之间插入空行，避免表格跨表拼接
+		rows = append(rows, nil)
+	}
+	return rows, nil
+}
+
+// htmlRows 解析 HTML 表格，同时返回页面全部文本（表格外的标题也用于识别平台）
+func htmlRows(data []byte) ([][]string, string, error) {
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("解析 XLS 失败: %w", err)
+	}
+
+	var rows [][]string
+	doc.Find("tr").Each(func(_ int, tr *goquery.Selection) {
+		var row []string
+		tr.Find("th,td").Each(func(_ int, cell *goquery.Selection) {
+			row = append(row, strings.TrimSpace(cell.Text()))
+		})
+		rows = append(rows, row)
+	})
+	if len(rows) == 0 {
+		return nil, "", errors.New("XLS 文件中没有表格")
+	}
+	return rows, doc.Text(), nil
+}
+
+// textRows 将 PDF 复制出的文本按制表符或连续空格切分为列
+func textRows(data []byte) [][]string {
+	var rows [][]string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			rows = append(rows, nil)
+			continue
+		}
+		rows = append(rows, trimRow(textSplitter.Split(line, -1)))
+	}
+	return rows
+}
+
+func trimRow(row []string) []string {
+	for i := range row {
+		row[i] = strings.TrimSpace(row[i])
+	}
+	return row
+}
@@ -0,0 +1,165 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"margin/internal/model"
+	"strconv"
+	"strings"
+)
+
+// 列映射字段
+const (
+	FieldCode   = "code"
+	FieldName   = "name"
+	FieldSource = "source"
+	FieldType   = "type"
+	FieldAmount = "amount"
+	FieldShares = "shares"
+	FieldNAV    = "nav"
+)
+
+// Mapping 列映射：字段 -> 表头名称
+type Mapping map[string]string
+
+// Record 导入的一条持仓记录
+type Record struct {
+	Row    int     `json:"row"`    // 原文件中的行号（从 1 开始，含表头）
+	Code   string  `json:"code"`   // 基金代码
+	Name   string  `json:"name"`   // 基金名称
+	Source string  `json:"source"` // 来源
+	Type   string  `json:"type"`   // stock/bond，未识别时为空
+	Amount float64 `json:"amount"` // 持仓金额
+	Error  string  `json:"error"`  // 行解析错误
+}
+
+// Validate 检查映射是否完整
+func (m Mapping) Validate() error {
+	if m[FieldCode] == "" {
+		return errors.New("请指定基金代码列")
+	}
+	if m[FieldAmount] == "" && (m[FieldShares] == "" || m[FieldNAV] == "") {
+		return errors.New("请指定金额列，或同时指定份额列和净值列")
+	}
+	return nil
+}
+
+// Apply 按映射将表格转换为持仓记录，defaultSource 用于未映射来源列的情况
+func (m Mapping) Apply(t *Table, defaultSource string) ([]Record, error) {
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+
+	columns := make(map[string]int, len(m))
+	for field, header := range m {
+		if header == "" {
+			continue
+		}
+		idx := t.ColumnIndex(header)
+		if idx < 0 {
+			return nil, fmt.Errorf("文件中不存在列\"%s\"", header)
+		}
+		columns[field] = idx
+	}
+
+	col := func(field string) int {
+		if idx, ok := columns[field]; ok {
+			return idx
+		}
+		return -1
+	}
+
+	records := make([]Record, 0, len(t.Rows))
+	for i, row := range t.Rows {
+		record := Record{
+			Row:    i + 2,
+			Code:   NormalizeCode(t.Cell(row, col(FieldCode))),
+			Name:   t.Cell(row, col(FieldName)),
+			Source: t.Cell(row, col(FieldSource)),
+			Type:   NormalizeAssetType(t.Cell(row, col(FieldType))),
+		}
+		if record.Source == "" {
+			record.Source = defaultSource
+		}
+
+		amount, err := m.amount(t, row, col)
+		if err != nil {
+			record.Error = err.Error()
+		}
+		record.Amount = amount
+
+		if record.Code == "" && record.Error == "" {
+			record.Error = "基金代码为空"
+		}
+		if record.Source == "" && record.Error == "" {
+			record.Error = "来源为空"
+		}
+
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+func (m Mapping) amount(t *Table, row []string, col func(string) int) (float64, error) {
+	if m[FieldAmount] != "" {
+		amount, err := ParseNumber(t.Cell(row, col(FieldAmount)))
+		if err != nil {
+			return 0, fmt.Errorf("金额格式错误: %w", err)
+		}
+		if amount < 0 {
+			return 0, errors.New("金额不能小于 0")
+		}
+		return amount, nil
+	}
+
+	shares, err := ParseNumber(t.Cell(row, col(FieldShares)))
+	if err != nil {
+		return 0, fmt.Errorf("份额格式错误: %w", err)
+	}
+	nav, err := ParseNumber(t.Cell(row, col(FieldNAV)))
+	if err != nil {
+		return 0, fmt.Errorf("净值格式错误: %w", err)
+	}
+	if shares < 0 || nav < 0 {
+		return 0, errors.New("份额和净值不能小于 0")
+	}
+	return shares * nav, nil
+}
+
+// ParseNumber 解析金额数字，兼容千分位、货币符号和"元"等后缀
+func ParseNumber(s string) (float64, error) {
+	replacer := strings.NewReplacer(",", "", "，", "", "¥", "", "￥", "", "元", "", "份", "", " ", "", "\u00a0", "")
+	s = replacer.Replace(strings.TrimSpace(s))
+	if s == "" || s == "-" || s == "--" {
+		return 0, errors.New("数值为空")
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+// NormalizeCode 规范化基金代码：去除空格和引号，纯数字代码补足 6 位
+func NormalizeCode(code string) string {
+	code = strings.Trim(strings.TrimSpace(code), "'\"=")
+	if code == "" {
+		return ""
+	}
+	if _, err := strconv.Atoi(code); err == nil && len(code) < 6 {
+		code = strings.Repeat("0", 6-len(code)) + code
+	}
+	return code
+}
+
+// NormalizeAssetType 将基金类型描述映射为 stock/bond，与前端录入时的识别规则一致
+func NormalizeAssetType(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	switch {
+	case s == "":
+		return ""
+	case s == model.AssetTypeStock || strings.Contains(s, "股票") || strings.Contains(s, "指数") || strings.Contains(s, "混合"):
+		return model.AssetTypeStock
+	case s == model.AssetTypeBond || strings.Contains(s, "债") || strings.Contains(s, "货币"):
+		return model.AssetTypeBond
+	default:
+		return ""
+	}
+}
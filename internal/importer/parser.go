@@ -0,0 +1,336 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 账单类型
+const (
+	StatementHoldings     = "holdings"     // 持仓账单
+	StatementTransactions = "transactions" // 交易流水
+)
+
+// 交易方向
+const (
+	ActionBuy  = "buy"
+	ActionSell = "sell"
+)
+
+// Transaction 交易流水中的一笔确认交易
+type Transaction struct {
+	Row    int       `json:"row"`
+	Date   time.Time `json:"date"`
+	Code   string    `json:"code"`
+	Name   string    `json:"name"`
+	Action string    `json:"action"` // buy/sell
+	Amount float64   `json:"amount"` // 确认金额
+	Fee    float64   `json:"fee"`    // 手续费
+}
+
+// Statement 解析后的平台账单
+type Statement struct {
+	Parser       string        `json:"parser"`
+	Source       string        `json:"source"`
+	Kind         string        `json:"kind"`
+	Holdings     []Record      `json:"holdings"`
+	Transactions []Transaction `json:"transactions"`
+}
+
+// Parser 平台账单解析器
+type Parser interface {
+	// Name 解析器标识
+	Name() string
+	// Source 对应的资产来源，与 model.DefaultSources 中的名称一致
+	Source() string
+	// Detect 判断文件是否属于该平台
+	Detect(doc *Document) bool
+	// Parse 解析账单
+	Parse(doc *Document) (*Statement, error)
+}
+
+var (
+	parsersMu sync.RWMutex
+	parsers   []Parser
+)
+
+// Register 注册平台解析器
+func Register(p Parser) {
+	parsersMu.Lock()
+	defer parsersMu.Unlock()
+	parsers = append(parsers, p)
+}
+
+// Parsers 返回已注册的解析器
+func Parsers() []Parser {
+	parsersMu.RLock()
+	defer parsersMu.RUnlock()
+	return append([]Parser(nil), parsers...)
+}
+
+// ParseStatement 自动识别平台并解析账单，name 不为空时使用指定的解析器
+func ParseStatement(doc *Document, name string) (*Statement, error) {
+	for _, p := range Parsers() {
+		if name != "" && p.Name() != name {
+			continue
+		}
+		if name == "" && !p.Detect(doc) {
+			continue
+		}
+		return p.Parse(doc)
+	}
+
+	if name != "" {
+		return nil, fmt.Errorf("未知的解析器: %s", name)
+	}
+	return nil, errors.New("无法识别账单所属平台")
+}
+
+// columnSpec 账单中各字段可能使用的列名
+type columnSpec struct {
+	code   []string
+	name   []string
+	amount []string
+	shares []string
+	nav    []string
+	date   []string
+	action []string
+	fee    []string
+	status []string
+}
+
+// platformParser 基于列名别名的通用账单解析器，各平台只需提供配置
+type platformParser struct {
+	name         string
+	source       string
+	markers      []string // 用于识别平台的关键字
+	holdings     columnSpec
+	transactions columnSpec
+	buyWords     []string // 视为买入的交易类型
+	sellWords    []string // 视为卖出的交易类型
+	failedWords  []string // 视为未成交的交易状态
+}
+
+var codePattern = regexp.MustCompile(`\d{6}`)
+
+func (p *platformParser) Name() string {
+	return p.name
+}
+
+func (p *platformParser) Source() string {
+	return p.source
+}
+
+func (p *platformParser) Detect(doc *Document) bool {
+	if !doc.Contains(p.markers...) && !containsAny(doc.Name, p.markers) {
+		return false
+	}
+	return p.holdingsTable(doc) != nil || p.transactionsTable(doc) != nil
+}
+
+func (p *platformParser) Parse(doc *Document) (*Statement, error) {
+	statement := &Statement{Parser: p.name, Source: p.source}
+
+	if table := p.holdingsTable(doc); table != nil {
+		statement.Kind = StatementHoldings
+		statement.Holdings = p.parseHoldings(table)
+		return statement, nil
+	}
+
+	if table := p.transactionsTable(doc); table != nil {
+		statement.Kind = StatementTransactions
+		transactions, err := p.parseTransactions(table)
+		if err != nil {
+			return nil, err
+		}
+		statement.Transactions = transactions
+		return statement, nil
+	}
+
+	return nil, fmt.Errorf("未在文件中找到%s的持仓或交易明细表", p.source)
+}
+
+func (p *platformParser) holdingsTable(doc *Document) *Table {
+	spec := p.holdings
+	if table := doc.FindTable(spec.name, spec.amount); table != nil {
+		return table
+	}
+	return doc.FindTable(spec.name, spec.shares, spec.nav)
+}
+
+func (p *platformParser) transactionsTable(doc *Document) *Table {
+	spec := p.transactions
+	return doc.FindTable(spec.date, spec.name, spec.action, spec.amount)
+}
+
+func (p *platformParser) parseHoldings(t *Table) []Record {
+	spec := p.holdings
+	codeCol := t.FindColumn(spec.code...)
+	nameCol := t.FindColumn(spec.name...)
+	amountCol := t.FindColumn(spec.amount...)
+	sharesCol := t.FindColumn(spec.shares...)
+	navCol := t.FindColumn(spec.nav...)
+
+	records := make([]Record, 0, len(t.Rows))
+	for i, row := range t.Rows {
+		name := t.Cell(row, nameCol)
+		if name == "" || isSummaryRow(name) {
+			continue
+		}
+
+		record := Record{
+			Row:    i + 2,
+			Code:   extractCode(t.Cell(row, codeCol), name),
+			Name:   cleanFundName(name),
+			Source: p.source,
+			Type:   NormalizeAssetType(name),
+		}
+
+		var err error
+		if amountCol >= 0 {
+			record.Amount, err = ParseNumber(t.Cell(row, amountCol))
+		} else {
+			var shares, nav float64
+			shares, err = ParseNumber(t.Cell(row, sharesCol))
+			if err == nil {
+				nav, err = ParseNumber(t.Cell(row, navCol))
+			}
+			record.Amount = shares * nav
+		}
+		if err != nil {
+			record.Error = fmt.Sprintf("金额格式错误: %s", err)
+		} else if record.Code == "" {
+			record.Error = "未找到基金代码"
+		}
+
+		records = append(records, record)
+	}
+	return records
+}
+
+func (p *platformParser) parseTransactions(t *Table) ([]Transaction, error) {
+	spec := p.transactions
+	codeCol := t.FindColumn(spec.code...)
+	nameCol := t.FindColumn(spec.name...)
+	dateCol := t.FindColumn(spec.date...)
+	actionCol := t.FindColumn(spec.action...)
+	amountCol := t.FindColumn(spec.amount...)
+	feeCol := t.FindColumn(spec.fee...)
+	statusCol := t.FindColumn(spec.status...)
+
+	transactions := make([]Transaction, 0, len(t.Rows))
+	for i, row := range t.Rows {
+		name := t.Cell(row, nameCol)
+		if name == "" || isSummaryRow(name) {
+			continue
+		}
+		if statusCol >= 0 && containsAny(t.Cell(row, statusCol), p.failedWords) {
+			continue
+		}
+
+		actionText := t.Cell(row, actionCol)
+		var action string
+		switch {
+		case containsAny(actionText, p.sellWords):
+			action = ActionSell
+		case containsAny(actionText, p.buyWords):
+			action = ActionBuy
+		default:
+			// 分红、修改分红方式等不影响持仓金额的记录
+			continue
+		}
+
+		date, err := ParseDate(t.Cell(row, dateCol))
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 行日期格式错误: %w", i+2, err)
+		}
+		amount, err := ParseNumber(t.Cell(row, amountCol))
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 行金额格式错误: %w", i+2, err)
+		}
+		fee, _ := ParseNumber(t.Cell(row, feeCol))
+
+		code := extractCode(t.Cell(row, codeCol), name)
+		if code == "" {
+			return nil, fmt.Errorf("第 %d 行未找到基金代码", i+2)
+		}
+
+		transactions = append(transactions, Transaction{
+			Row:    i + 2,
+			Date:   date,
+			Code:   code,
+			Name:   cleanFundName(name),
+			Action: action,
+			Amount: abs(amount),
+			Fee:    fee,
+		})
+	}
+	return transactions, nil
+}
+
+// ParseDate 解析账单中常见的日期格式
+func ParseDate(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	layouts := []string{
+		"2006-01-02 15:04:05",
+		"2006-01-02 15:04",
+		"2006-01-02",
+		"2006/01/02 15:04:05",
+		"2006/1/2 15:04:05",
+		"2006/01/02",
+		"2006/1/2",
+		"20060102",
+		"2006年01月02日",
+		"2006年1月2日",
+	}
+	for _, layout := range layouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("无法识别的日期: %s", s)
+}
+
+// extractCode 优先使用代码列，否则从名称中提取 6 位基金代码，如"华夏成长混合(000001)"
+func extractCode(code, name string) string {
+	if code = NormalizeCode(code); code != "" {
+		return code
+	}
+	return codePattern.FindString(name)
+}
+
+// cleanFundName 去除名称中附带的代码
+func cleanFundName(name string) string {
+	code := codePattern.FindString(name)
+	if code == "" {
+		return name
+	}
+	for _, wrapped := range []string{"(" + code + ")", "（" + code + "）", code} {
+		name = strings.Replace(name, wrapped, "", 1)
+	}
+	return strings.TrimSpace(name)
+}
+
+func isSummaryRow(name string) bool {
+	return strings.HasPrefix(name, "合计") || strings.HasPrefix(name, "总计") || strings.HasPrefix(name, "小计")
+}
+
+func containsAny(s string, keywords []string) bool {
+	for _, keyword := range keywords {
+		if keyword != "" && strings.Contains(s, keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+func abs(x float64) float64 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
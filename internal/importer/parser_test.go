@@ -0,0 +1,137 @@
+package importer
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func loadStatement(t *testing.T, file string) *Statement {
+	t.Helper()
+	doc, err := LoadDocument(filepath.Join("testdata", file))
+	if err != nil {
+		t.Fatalf("LoadDocument(%s): %v", file, err)
+	}
+	statement, err := ParseStatement(doc, "")
+	if err != nil {
+		t.Fatalf("ParseStatement(%s): %v", file, err)
+	}
+	return statement
+}
+
+func TestParseHoldings(t *testing.T) {
+	tests := []struct {
+		file   string
+		parser string
+		source string
+		want   []Record
+	}{
+		{
+			file:   "alipay_holdings.csv",
+			parser: "alipay",
+			source: "支付宝",
+			want: []Record{
+				{Code: "110020", Name: "易方达沪深300ETF联接A", Amount: 12345.67},
+				{Code: "217022", Name: "招商产业债券A", Amount: 5000},
+				{Code: "", Name: "示例货币基金", Amount: 1000, Error: "未找到基金代码"},
+			},
+		},
+		{
+			file:   "ttfund_holdings.xls",
+			parser: "ttfund",
+			source: "天天基金",
+			want: []Record{
+				{Code: "000001", Name: "华夏成长混合", Amount: 1234},
+				{Code: "000069", Name: "国投瑞银中高等级债券A", Amount: 2200},
+			},
+		},
+		{
+			file:   "cmb_holdings.txt",
+			parser: "cmb",
+			source: "招商银行",
+			want: []Record{
+				{Code: "000216", Name: "华安黄金易ETF联接A", Amount: 3974},
+				{Code: "160119", Name: "南方中证500ETF联接A", Amount: 3150},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			s := loadStatement(t, tt.file)
+			if s.Parser != tt.parser || s.Source != tt.source || s.Kind != StatementHoldings {
+				t.Fatalf("got parser=%s source=%s kind=%s", s.Parser, s.Source, s.Kind)
+			}
+			if len(s.Holdings) != len(tt.want) {
+				t.Fatalf("got %d holdings, want %d: %+v", len(s.Holdings), len(tt.want), s.Holdings)
+			}
+			for i, want := range tt.want {
+				got := s.Holdings[i]
+				if got.Code != want.Code || got.Name != want.Name || got.Source != tt.source ||
+					math.Abs(got.Amount-want.Amount) > 1e-9 || got.Error != want.Error {
+					t.Errorf("holding %d = %+v, want %+v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseTransactions(t *testing.T) {
+	date := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+	}
+	tests := []struct {
+		file   string
+		parser string
+		source string
+		want   []Transaction
+	}{
+		{
+			file:   "alipay_transactions.csv",
+			parser: "alipay",
+			source: "支付宝",
+			want: []Transaction{
+				{Date: date(2024, 5, 6).Add(14*time.Hour + 30*time.Minute), Code: "110020", Name: "易方达沪深300ETF联接A", Action: ActionBuy, Amount: 1000, Fee: 1.2},
+				{Date: date(2024, 5, 10).Add(10 * time.Hour), Code: "217022", Name: "招商产业债券A", Action: ActionSell, Amount: 500, Fee: 0.75},
+			},
+		},
+		{
+			file:   "ttfund_transactions.xls",
+			parser: "ttfund",
+			source: "天天基金",
+			want: []Transaction{
+				{Date: date(2024, 5, 6), Code: "000001", Name: "华夏成长混合", Action: ActionBuy, Amount: 500, Fee: 0.75},
+				{Date: date(2024, 5, 13), Code: "000069", Name: "国投瑞银中高等级债券A", Action: ActionSell, Amount: 800, Fee: 0},
+			},
+		},
+		{
+			file:   "cmb_transactions.txt",
+			parser: "cmb",
+			source: "招商银行",
+			want: []Transaction{
+				{Date: date(2024, 5, 8), Code: "000216", Name: "华安黄金易ETF联接A", Action: ActionBuy, Amount: 2000, Fee: 3},
+				{Date: date(2024, 5, 20), Code: "000216", Name: "华安黄金易ETF联接A", Action: ActionSell, Amount: 1000, Fee: 5},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			s := loadStatement(t, tt.file)
+			if s.Parser != tt.parser || s.Source != tt.source || s.Kind != StatementTransactions {
+				t.Fatalf("got parser=%s source=%s kind=%s", s.Parser, s.Source, s.Kind)
+			}
+			if len(s.Transactions) != len(tt.want) {
+				t.Fatalf("got %d transactions, want %d: %+v", len(s.Transactions), len(tt.want), s.Transactions)
+			}
+			for i, want := range tt.want {
+				got := s.Transactions[i]
+				if !got.Date.Equal(want.Date) || got.Code != want.Code || got.Name != want.Name || got.Action != want.Action ||
+					math.Abs(got.Amount-want.Amount) > 1e-9 || math.Abs(got.Fee-want.Fee) > 1e-9 {
+					t.Errorf("transaction %d = %+v, want %+v", i, got, want)
+				}
+			}
+		})
+	}
+}
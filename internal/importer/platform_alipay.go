@@ -0,0 +1,29 @@
+package importer
+
+// 支付宝（蚂蚁财富）基金持仓及交易明细导出
+func init() {
+	Register(&platformParser{
+		name:    "alipay",
+		source:  "支付宝",
+		markers: []string{"支付宝", "蚂蚁财富", "Alipay"},
+		holdings: columnSpec{
+			code:   []string{"基金代码", "产品代码"},
+			name:   []string{"基金名称", "产品名称"},
+			amount: []string{"持有金额", "持仓金额", "资产金额", "总金额"},
+			shares: []string{"持有份额", "持仓份额"},
+			nav:    []string{"最新净值", "单位净值", "净值"},
+		},
+		transactions: columnSpec{
+			code:   []string{"基金代码", "产品代码"},
+			name:   []string{"基金名称", "产品名称", "商品说明"},
+			date:   []string{"确认日期", "交易时间", "交易创建时间", "交易日期"},
+			action: []string{"交易类型", "业务类型", "交易分类"},
+			amount: []string{"确认金额", "交易金额", "金额"},
+			fee:    []string{"手续费", "服务费"},
+			status: []string{"交易状态", "状态"},
+		},
+		buyWords:    []string{"买入", "申购", "认购", "定投", "转换转入", "红利再投"},
+		sellWords:   []string{"卖出", "赎回", "转换转出"},
+		failedWords: []string{"失败", "关闭", "撤销", "已撤"},
+	})
+}
@@ -0,0 +1,29 @@
+package importer
+
+// 招商银行基金持仓及交易流水（网银导出或 PDF 对账单复制的文本）
+func init() {
+	Register(&platformParser{
+		name:    "cmb",
+		source:  "招商银行",
+		markers: []string{"招商银行", "招行", "一网通", "China Merchants Bank"},
+		holdings: columnSpec{
+			code:   []string{"产品代码", "基金代码"},
+			name:   []string{"产品名称", "基金名称"},
+			amount: []string{"参考市值", "市值", "持有金额", "资产金额"},
+			shares: []string{"持有份额", "份额"},
+			nav:    []string{"参考净值", "净值"},
+		},
+		transactions: columnSpec{
+			code:   []string{"产品代码", "基金代码"},
+			name:   []string{"产品名称", "基金名称"},
+			date:   []string{"确认日期", "交易日期"},
+			action: []string{"交易类型", "业务名称", "业务类型"},
+			amount: []string{"确认金额", "交易金额"},
+			fee:    []string{"手续费"},
+			status: []string{"交易状态", "状态"},
+		},
+		buyWords:    []string{"申购", "认购", "定投", "买入", "转换转入"},
+		sellWords:   []string{"赎回", "卖出", "转换转出"},
+		failedWords: []string{"失败", "撤单", "已撤"},
+	})
+}
@@ -0,0 +1,29 @@
+package importer
+
+// 天天基金（东方财富）资产明细及交易记录导出
+func init() {
+	Register(&platformParser{
+		name:    "ttfund",
+		source:  "天天基金",
+		markers: []string{"天天基金", "东方财富", "1234567.com.cn"},
+		holdings: columnSpec{
+			code:   []string{"基金代码"},
+			name:   []string{"基金名称", "基金简称"},
+			amount: []string{"参考市值", "持仓市值", "资产", "持有金额"},
+			shares: []string{"持有份额", "持仓份额", "可用份额"},
+			nav:    []string{"单位净值", "最新净值", "净值"},
+		},
+		transactions: columnSpec{
+			code:   []string{"基金代码"},
+			name:   []string{"基金名称", "基金简称"},
+			date:   []string{"确认日期", "申请日期", "交易日期"},
+			action: []string{"业务类型", "交易类型"},
+			amount: []string{"确认金额", "申请金额", "交易金额"},
+			fee:    []string{"手续费"},
+			status: []string{"确认状态", "交易状态", "状态"},
+		},
+		buyWords:    []string{"申购", "认购", "定投", "买入", "转换转入", "超级转换-转入"},
+		sellWords:   []string{"赎回", "卖出", "转换转出", "超级转换-转出"},
+		failedWords: []string{"失败", "撤单", "已撤", "无效"},
+	})
+}
@@ -0,0 +1,151 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/xuri/excelize/v2"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+// Table 表格数据（首行为表头）
+type Table struct {
+	Headers []string   `json:"headers"`
+	Rows    [][]string `json:"rows"`
+}
+
+// ReadFile 根据扩展名读取 CSV / XLSX 文件
+func ReadFile(path string) (*Table, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取文件失败: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv", ".txt":
+		return ReadCSV(data)
+	case ".xlsx":
+		return ReadXLSX(data)
+	default:
+		return nil, fmt.Errorf("不支持的文件格式: %s", filepath.Ext(path))
+	}
+}
+
+// ReadCSV 解析 CSV 内容，自动识别 UTF-8 BOM 和 GBK 编码
+func ReadCSV(data []byte) (*Table, error) {
+	data = decodeText(data)
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.TrimLeadingSpace = true
+
+	var records [][]string
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("解析 CSV 失败: %w", err)
+		}
+		records = append(records, record)
+	}
+
+	return newTable(records)
+}
+
+// ReadXLSX 解析 XLSX 内容（读取第一个工作表）
+func ReadXLSX(data []byte) (*Table, error) {
+	f, err := excelize.OpenReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("解析 XLSX 失败: %w", err)
+	}
+	defer f.Close()
+
+	sheets := f.GetSheetList()
+	if len(sheets) == 0 {
+		return nil, errors.New("XLSX 文件中没有工作表")
+	}
+
+	records, err := f.GetRows(sheets[0])
+	if err != nil {
+		return nil, fmt.Errorf("读取工作表失败: %w", err)
+	}
+
+	return newTable(records)
+}
+
+// ColumnIndex 返回表头所在列（忽略首尾空格），不存在返回 -1
+func (t *Table) ColumnIndex(header string) int {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return -1
+	}
+	for i, h := range t.Headers {
+		if h == header {
+			return i
+		}
+	}
+	return -1
+}
+
+// Cell 读取指定行列的值，越界时返回空字符串
+func (t *Table) Cell(row []string, col int) string {
+	if col < 0 || col >= len(row) {
+		return ""
+	}
+	return strings.TrimSpace(row[col])
+}
+
+// newTable 以第一个非空行为表头，跳过空行
+func newTable(records [][]string) (*Table, error) {
+	table := &Table{}
+	for _, record := range records {
+		if isBlankRow(record) {
+			continue
+		}
+		if table.Headers == nil {
+			table.Headers = make([]string, len(record))
+			for i, h := range record {
+				table.Headers[i] = strings.TrimSpace(h)
+			}
+			continue
+		}
+		table.Rows = append(table.Rows, record)
+	}
+
+	if table.Headers == nil {
+		return nil, errors.New("文件内容为空")
+	}
+	return table, nil
+}
+
+func isBlankRow(record []string) bool {
+	for _, cell := range record {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// decodeText 去除 UTF-8 BOM，非 UTF-8 内容按 GBK 解码（国内平台导出文件常用）
+func decodeText(data []byte) []byte {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if utf8.Valid(data) {
+		return data
+	}
+	decoded, err := simplifiedchinese.GBK.NewDecoder().Bytes(data)
+	if err != nil {
+		return data
+	}
+	return decoded
+}
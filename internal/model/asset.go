@@ -11,6 +11,7 @@ type Asset struct {
 	Type            string    `gorm:"index;not null" json:"type"`            // stock/bond
 	Source          string    `gorm:"not null" json:"source"`                // 支付宝/天天基金
 	EncryptedAmount string    `gorm:"type:text;not null" json:"-"`           // 加密后的金额
+	TargetWeight    float64   `gorm:"default:0" json:"target_weight"`        // 类别内目标权重(%)，0 表示未设置
 	CreatedAt       time.Time `json:"created_at"`
 	UpdatedAt       time.Time `json:"updated_at"`
 }
@@ -0,0 +1,28 @@
+package model
+
+import "time"
+
+// CashFlow 资金流水。存入/取出为组合外部的资金进出；
+// 转入/转出为组合内部的调仓（如再平衡成交），只影响分类和来源维度的收益计算
+type CashFlow struct {
+	ID              uint      `gorm:"primaryKey" json:"id"`
+	Date            time.Time `gorm:"index;not null" json:"date"`
+	Kind            string    `gorm:"not null" json:"kind"` // deposit/withdrawal/transfer_in/transfer_out
+	AssetID         uint      `json:"asset_id"`             // 资产删除后仍保留流水
+	Code            string    `json:"code"`
+	Name            string    `json:"name"`
+	Type            string    `gorm:"not null" json:"type"` // 资产类型 stock/bond
+	Source          string    `json:"source"`
+	EncryptedAmount string    `gorm:"type:text;not null" json:"-"` // 加密的金额（正数）
+	OrderID         uint      `gorm:"default:0" json:"order_id"`   // 由再平衡成交生成时对应的交易
+	Note            string    `json:"note"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// 资金流水类型常量
+const (
+	CashFlowDeposit     = "deposit"
+	CashFlowWithdrawal  = "withdrawal"
+	CashFlowTransferIn  = "transfer_in"
+	CashFlowTransferOut = "transfer_out"
+)
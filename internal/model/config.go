@@ -13,7 +13,13 @@ type Config struct {
 
 // 配置键常量
 const (
-	ConfigKeyPasswordHash = "password_hash"
-	ConfigKeyEncryptKey   = "encrypt_key"
-	ConfigKeyFirstRun     = "first_run"
+	ConfigKeyPasswordHash      = "password_hash"
+	ConfigKeyEncryptKey        = "encrypt_key"
+	ConfigKeyFirstRun          = "first_run"
+	ConfigKeyRebalanceBands    = "rebalance_bands"    // 再平衡容忍带规则（JSON）
+	ConfigKeyRebalanceSchedule = "rebalance_schedule" // 定期再平衡日历（JSON）
+	ConfigKeyValuationTarget   = "valuation_target"   // 估值驱动的动态目标配置（JSON）
+	ConfigKeyGlidePath         = "glide_path"         // 随年龄或目标日期变化的目标股票比例（JSON）
+	ConfigKeySnapshotPolicy    = "snapshot_policy"    // 自动保存历史快照的策略（JSON）
+	ConfigKeyHolidayTable      = "holiday_table"      // 用户导入的休市日历（JSON），为空时使用内置日历
 )
@@ -0,0 +1,32 @@
+package model
+
+import "time"
+
+// FeeRule 基金费率规则（申购费按金额分档，赎回费按持有天数分档）
+type FeeRule struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Code      string    `gorm:"index;not null" json:"code"`     // 基金代码
+	Source    string    `gorm:"default:''" json:"source"`       // 适用平台，空表示所有平台
+	Kind      string    `gorm:"not null" json:"kind"`           // subscription/redemption
+	MinDays   int       `gorm:"default:0" json:"min_days"`      // 持有天数下限（含）
+	MaxDays   int       `gorm:"default:0" json:"max_days"`      // 持有天数上限（不含），0 表示无上限
+	MinAmount float64   `gorm:"default:0" json:"min_amount"`    // 金额下限（含）
+	MaxAmount float64   `gorm:"default:0" json:"max_amount"`    // 金额上限（不含），0 表示无上限
+	Rate      float64   `gorm:"default:0" json:"rate"`          // 费率(%)
+	Fixed     float64   `gorm:"default:0" json:"fixed"`         // 固定费用（元/笔），大于 0 时优先于费率
+	Origin    string    `gorm:"default:'manual'" json:"origin"` // manual/fetched
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// 费率类型常量
+const (
+	FeeKindSubscription = "subscription"
+	FeeKindRedemption   = "redemption"
+)
+
+// 费率来源常量
+const (
+	FeeOriginManual  = "manual"
+	FeeOriginFetched = "fetched"
+)
@@ -9,5 +9,18 @@ type History struct {
 	EncryptedBondTotal  string    `gorm:"type:text;not null" json:"-"` // 加密的债券总额
 	StockRatio          float64   `json:"stock_ratio"`                 // 股票比例
 	BondRatio           float64   `json:"bond_ratio"`                  // 债券比例
-	CreatedAt           time.Time `json:"created_at"`
+	CreatedAt           time.Time `gorm:"index" json:"created_at"`
+}
+
+// HistoryItem 历史快照中单个资产的持仓明细
+type HistoryItem struct {
+	ID              uint      `gorm:"primaryKey" json:"id"`
+	HistoryID       uint      `gorm:"index;not null" json:"history_id"`
+	AssetID         uint      `json:"asset_id"` // 资产删除后仍保留明细
+	Code            string    `json:"code"`
+	Name            string    `json:"name"`
+	Type            string    `json:"type"`
+	Source          string    `json:"source"`
+	EncryptedAmount string    `gorm:"type:text;not null" json:"-"` // 加密的持仓金额
+	CreatedAt       time.Time `json:"created_at"`
 }
@@ -0,0 +1,13 @@
+package model
+
+import "time"
+
+// ImportedTransaction 已导入的平台账单交易。重复导入同一份流水或导出区间重叠时，
+// 已计入持仓的交易按哈希跳过；只保存哈希，不保存交易金额明文
+type ImportedTransaction struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Hash      string    `gorm:"uniqueIndex;not null" json:"hash"`                   // 平台、日期、代码、方向、金额、手续费及同一文件内的序号的哈希
+	Source    string    `gorm:"not null;index:idx_imported_tx_asset" json:"source"` // 资产来源
+	Code      string    `gorm:"not null;index:idx_imported_tx_asset" json:"code"`   // 基金代码
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,65 @@
+package model
+
+import "time"
+
+// IndexDef 行情栏可显示的指数定义
+type IndexDef struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Code      string    `gorm:"uniqueIndex;not null" json:"code"` // 指数代码，如 000300、SPX
+	Name      string    `gorm:"not null" json:"name"`             // 显示名称
+	SecID     string    `gorm:"not null" json:"secid"`            // 东方财富行情 ID，如 1.000300、100.SPX
+	Market    string    `gorm:"not null" json:"market"`           // cn/hk/us
+	Currency  string    `gorm:"not null" json:"currency"`         // CNY/HKD/USD
+	Sort      int       `gorm:"default:0" json:"sort"`            // 显示顺序，越小越靠前
+	Watch     bool      `gorm:"default:false" json:"watch"`       // 是否显示在行情栏
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// 市场常量
+const (
+	MarketCN = "cn"
+	MarketHK = "hk"
+	MarketUS = "us"
+)
+
+// MarketCurrencies 各市场的计价货币
+var MarketCurrencies = map[string]string{
+	MarketCN: "CNY",
+	MarketHK: "HKD",
+	MarketUS: "USD",
+}
+
+// DefaultIndexes 首次启动时写入的指数
+var DefaultIndexes = []IndexDef{
+	{Code: "000001", Name: "上证指数", SecID: "1.000001", Market: MarketCN, Currency: "CNY", Watch: true},
+	{Code: "000300", Name: "沪深300", SecID: "1.000300", Market: MarketCN, Currency: "CNY", Watch: true},
+	{Code: "SPX", Name: "标普500", SecID: "100.SPX", Market: MarketUS, Currency: "USD", Watch: true},
+	{Code: "NDX", Name: "纳斯达克", SecID: "100.NDX", Market: MarketUS, Currency: "USD"},
+}
+
+// IndexPresets 添加指数时可直接选用的常见指数
+var IndexPresets = []IndexDef{
+	{Code: "000905", Name: "中证500", SecID: "1.000905", Market: MarketCN, Currency: "CNY"},
+	{Code: "399006", Name: "创业板指", SecID: "0.399006", Market: MarketCN, Currency: "CNY"},
+	{Code: "000922", Name: "中证红利", SecID: "1.000922", Market: MarketCN, Currency: "CNY"},
+	{Code: "000012", Name: "国债指数", SecID: "1.000012", Market: MarketCN, Currency: "CNY"},
+	{Code: "000013", Name: "企债指数", SecID: "1.000013", Market: MarketCN, Currency: "CNY"},
+	{Code: "HSI", Name: "恒生指数", SecID: "100.HSI", Market: MarketHK, Currency: "HKD"},
+	{Code: "HSTECH", Name: "恒生科技", SecID: "124.HSTECH", Market: MarketHK, Currency: "HKD"},
+	{Code: "DJIA", Name: "道琼斯", SecID: "100.DJIA", Market: MarketUS, Currency: "USD"},
+	{Code: "NDX", Name: "纳斯达克", SecID: "100.NDX", Market: MarketUS, Currency: "USD"},
+	{Code: "SPX", Name: "标普500", SecID: "100.SPX", Market: MarketUS, Currency: "USD"},
+}
+
+// FindIndexPreset 在默认指数和预置指数中按代码查找
+func FindIndexPreset(code string) (IndexDef, bool) {
+	for _, presets := range [][]IndexDef{DefaultIndexes, IndexPresets} {
+		for _, p := range presets {
+			if p.Code == code {
+				return p, true
+			}
+		}
+	}
+	return IndexDef{}, false
+}
@@ -0,0 +1,12 @@
+package model
+
+import "time"
+
+// IndexPrice 指数日线收盘价缓存（公开行情数据，不加密）
+type IndexPrice struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Code      string    `gorm:"not null;uniqueIndex:idx_index_price_code_date" json:"code"` // 指数代码
+	Date      string    `gorm:"not null;uniqueIndex:idx_index_price_code_date" json:"date"` // 交易日，格式 2006-01-02
+	Close     float64   `gorm:"not null" json:"close"`                                      // 收盘点位
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,21 @@
+package model
+
+import "time"
+
+// IndexQuote 指数最新行情缓存（公开行情数据，不加密），重启后网络不可用时仍可显示上次的行情
+type IndexQuote struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	Code       string    `gorm:"uniqueIndex;not null" json:"code"` // 指数代码
+	Price      float64   `json:"price"`                            // 最新点位
+	Change     float64   `json:"change"`                           // 涨跌点
+	ChangeRate float64   `json:"change_rate"`                      // 涨跌幅(%)
+	Open       float64   `json:"open"`                             // 今开
+	High       float64   `json:"high"`                             // 最高
+	Low        float64   `json:"low"`                              // 最低
+	PrevClose  float64   `json:"prev_close"`                       // 昨收
+	Volume     float64   `json:"volume"`                           // 成交量
+	Amount     float64   `json:"amount"`                           // 成交额
+	QuoteTime  time.Time `json:"quote_time"`                       // 交易所行情时间
+	FetchedAt  time.Time `json:"fetched_at"`                       // 获取时间
+	UpdatedAt  time.Time `json:"updated_at"`
+}
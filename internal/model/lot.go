@@ -0,0 +1,12 @@
+package model
+
+import "time"
+
+// AssetLot 资产持仓批次，用于按持有期计算赎回费
+type AssetLot struct {
+	ID              uint      `gorm:"primaryKey" json:"id"`
+	AssetID         uint      `gorm:"index;not null" json:"asset_id"`
+	BuyDate         time.Time `gorm:"not null" json:"buy_date"`    // 买入（确认）日期
+	EncryptedAmount string    `gorm:"type:text;not null" json:"-"` // 加密后的买入金额
+	CreatedAt       time.Time `json:"created_at"`
+}
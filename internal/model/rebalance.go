@@ -2,20 +2,61 @@ package model
 
 import "time"
 
-// Rebalance 再平衡记录
+// Rebalance 再平衡记录，金额由服务端根据当时持仓计算并加密保存
 type Rebalance struct {
-	ID               uint      `gorm:"primaryKey;autoIncrement" json:"id"`
-	StockRatio       float64   `gorm:"not null" json:"stock_ratio"`        // 股票比例
-	BondRatio        float64   `gorm:"not null" json:"bond_ratio"`         // 债券比例
-	TotalAmount      float64   `gorm:"not null" json:"total_amount"`       // 总金额
-	StockAmount      float64   `gorm:"not null" json:"stock_amount"`       // 股票金额
-	BondAmount       float64   `gorm:"not null" json:"bond_amount"`        // 债券金额
-	TargetStockRatio float64   `gorm:"not null" json:"target_stock_ratio"` // 目标股票比例
-	TargetBondRatio  float64   `gorm:"not null" json:"target_bond_ratio"`  // 目标债券比例
-	Note             string    `gorm:"type:text" json:"note"`              // 备注
-	CreatedAt        time.Time `gorm:"autoCreateTime" json:"created_at"`
+	ID                   uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	StockRatio           float64   `gorm:"not null" json:"stock_ratio"`            // 股票比例
+	BondRatio            float64   `gorm:"not null" json:"bond_ratio"`             // 债券比例
+	EncryptedTotalAmount string    `gorm:"type:text;not null;default:''" json:"-"` // 加密的总金额
+	EncryptedStockAmount string    `gorm:"type:text;not null;default:''" json:"-"` // 加密的股票金额
+	EncryptedBondAmount  string    `gorm:"type:text;not null;default:''" json:"-"` // 加密的债券金额
+	TargetStockRatio     float64   `gorm:"not null" json:"target_stock_ratio"`     // 目标股票比例
+	TargetBondRatio      float64   `gorm:"not null" json:"target_bond_ratio"`      // 目标债券比例
+	Note                 string    `gorm:"type:text" json:"note"`                  // 备注
+	CreatedAt            time.Time `gorm:"autoCreateTime;index" json:"created_at"`
 }
 
 func (Rebalance) TableName() string {
 	return "rebalances"
 }
+
+// RebalanceHolding 再平衡时的单个资产持仓快照
+type RebalanceHolding struct {
+	ID              uint      `gorm:"primaryKey" json:"id"`
+	RebalanceID     uint      `gorm:"index;not null" json:"rebalance_id"`
+	AssetID         uint      `json:"asset_id"` // 资产删除后仍保留快照
+	Code            string    `json:"code"`
+	Name            string    `json:"name"`
+	Type            string    `json:"type"`
+	Source          string    `json:"source"`
+	EncryptedAmount string    `gorm:"type:text;not null" json:"-"` // 加密的持仓金额
+	Ratio           float64   `json:"ratio"`                       // 占总资产比例(%)
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// RebalanceOrder 再平衡计划中的一笔交易及其执行情况
+type RebalanceOrder struct {
+	ID                    uint       `gorm:"primaryKey" json:"id"`
+	RebalanceID           uint       `gorm:"index;not null" json:"rebalance_id"`
+	AssetID               uint       `json:"asset_id"`
+	Code                  string     `json:"code"`
+	Name                  string     `json:"name"`
+	Type                  string     `json:"type"`
+	Source                string     `json:"source"`
+	Action                string     `gorm:"not null" json:"action"`                   // buy/sell
+	EncryptedAmount       string     `gorm:"type:text;not null" json:"-"`              // 加密的计划金额
+	EncryptedFilledAmount string     `gorm:"type:text;not null;default:''" json:"-"`   // 加密的实际成交金额
+	Status                string     `gorm:"not null;default:'pending'" json:"status"` // pending/submitted/filled/cancelled
+	SubmittedAt           *time.Time `json:"submitted_at"`                             // 提交时间
+	FilledAt              *time.Time `json:"filled_at"`                                // 成交（确认）日期
+	CreatedAt             time.Time  `json:"created_at"`
+	UpdatedAt             time.Time  `json:"updated_at"`
+}
+
+// 交易执行状态
+const (
+	OrderStatusPending   = "pending"
+	OrderStatusSubmitted = "submitted"
+	OrderStatusFilled    = "filled"
+	OrderStatusCancelled = "cancelled"
+)
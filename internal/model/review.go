@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// RebalanceReview 定期再平衡检查记录，无需交易时也会记录
+type RebalanceReview struct {
+	ID               uint      `gorm:"primaryKey" json:"id"`
+	DueDate          time.Time `gorm:"index;not null" json:"due_date"` // 计划检查日期，手动检查时为检查当天
+	TargetStockRatio float64   `json:"target_stock_ratio"`             // 目标股票比例
+	StockRatio       float64   `json:"stock_ratio"`                    // 检查时的股票比例
+	Breached         bool      `json:"breached"`                       // 是否突破容忍带
+	Reasons          string    `gorm:"type:text" json:"reasons"`       // 触发原因，每行一条
+	Outcome          string    `gorm:"not null" json:"outcome"`        // no_action/rebalance_needed/rebalanced
+	Note             string    `gorm:"type:text" json:"note"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// 检查结果
+const (
+	ReviewOutcomeNoAction        = "no_action"
+	ReviewOutcomeRebalanceNeeded = "rebalance_needed"
+	ReviewOutcomeRebalanced      = "rebalanced"
+)
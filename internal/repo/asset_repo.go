@@ -33,6 +33,14 @@ func (r *AssetRepository) Delete(ctx context.Context, id uint) error {
 	return r.db.WithContext(ctx).Delete(&model.Asset{}, id).Error
 }
 
+func (r *AssetRepository) GetByID(ctx context.Context, id uint) (*model.Asset, error) {
+	var asset model.Asset
+	if err := r.db.WithContext(ctx).First(&asset, id).Error; err != nil {
+		return nil, err
+	}
+	return &asset, nil
+}
+
 func (r *AssetRepository) GetByTypeAndSource(ctx context.Context, assetType, source string) (*model.Asset, error) {
 	var asset model.Asset
 	err := r.db.WithContext(ctx).
@@ -0,0 +1,49 @@
+package repo
+
+import (
+	"context"
+	"margin/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type CashFlowRepository struct {
+	db *gorm.DB
+}
+
+func NewCashFlowRepository(db *gorm.DB) *CashFlowRepository {
+	return &CashFlowRepository{db: db}
+}
+
+// GetAll 获取全部资金流水，按日期升序
+func (r *CashFlowRepository) GetAll(ctx context.Context) ([]model.CashFlow, error) {
+	var flows []model.CashFlow
+	err := r.db.WithContext(ctx).Order("date ASC, id ASC").Find(&flows).Error
+	return flows, err
+}
+
+// GetRange 获取 [from, to) 之间的资金流水，零值表示不限
+func (r *CashFlowRepository) GetRange(ctx context.Context, from, to time.Time) ([]model.CashFlow, error) {
+	query := r.db.WithContext(ctx)
+	if !from.IsZero() {
+		query = query.Where("date >= ?", from)
+	}
+	if !to.IsZero() {
+		query = query.Where("date < ?", to)
+	}
+
+	var flows []model.CashFlow
+	err := query.Order("date ASC, id ASC").Find(&flows).Error
+	return flows, err
+}
+
+// Create 创建资金流水
+func (r *CashFlowRepository) Create(ctx context.Context, flow *model.CashFlow) error {
+	return r.db.WithContext(ctx).Create(flow).Error
+}
+
+// Delete 删除资金流水
+func (r *CashFlowRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&model.CashFlow{}, id).Error
+}
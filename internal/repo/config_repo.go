@@ -28,6 +28,6 @@ func (r *ConfigRepository) Set(ctx context.Context, key, value string) error {
 	config := &model.Config{Key: key, Value: value}
 	return r.db.WithContext(ctx).
 		Where("key = ?", key).
-		Assign(model.Config{Value: value}).
+		Assign(map[string]interface{}{"value": value}). // 用 map 赋值，空字符串也能覆盖原值
 		FirstOrCreate(config).Error
 }
@@ -0,0 +1,50 @@
+package repo
+
+import (
+	"context"
+	"margin/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type FeeRuleRepository struct {
+	db *gorm.DB
+}
+
+func NewFeeRuleRepository(db *gorm.DB) *FeeRuleRepository {
+	return &FeeRuleRepository{db: db}
+}
+
+// GetAll 获取所有费率规则
+func (r *FeeRuleRepository) GetAll(ctx context.Context) ([]model.FeeRule, error) {
+	var rules []model.FeeRule
+	err := r.db.WithContext(ctx).Order("code ASC, kind ASC, min_days ASC, min_amount ASC").Find(&rules).Error
+	return rules, err
+}
+
+// GetByCode 获取基金的费率规则
+func (r *FeeRuleRepository) GetByCode(ctx context.Context, code string) ([]model.FeeRule, error) {
+	var rules []model.FeeRule
+	err := r.db.WithContext(ctx).
+		Where("code = ?", code).
+		Order("kind ASC, source ASC, min_days ASC, min_amount ASC").
+		Find(&rules).Error
+	return rules, err
+}
+
+// Create 创建费率规则
+func (r *FeeRuleRepository) Create(ctx context.Context, rule *model.FeeRule) error {
+	return r.db.WithContext(ctx).Create(rule).Error
+}
+
+// Delete 删除费率规则
+func (r *FeeRuleRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&model.FeeRule{}, id).Error
+}
+
+// DeleteFetched 删除基金从网页抓取的费率规则（手动录入的保留）
+func (r *FeeRuleRepository) DeleteFetched(ctx context.Context, code string) error {
+	return r.db.WithContext(ctx).
+		Where("code = ? AND origin = ?", code, model.FeeOriginFetched).
+		Delete(&model.FeeRule{}).Error
+}
@@ -28,3 +28,76 @@ func (r *HistoryRepository) GetAll(ctx context.Context) ([]model.History, error)
 func (r *HistoryRepository) Delete(ctx context.Context, id int64) error {
 	return r.db.WithContext(ctx).Delete(&model.History{}, id).Error
 }
+
+// GetLatest 获取最新一条快照
+func (r *HistoryRepository) GetLatest(ctx context.Context) (*model.History, error) {
+	var history model.History
+	err := r.db.WithContext(ctx).Order("created_at DESC").First(&history).Error
+	if err != nil {
+		return nil, err
+	}
+	return &history, nil
+}
+
+// Get 获取快照
+func (r *HistoryRepository) Get(ctx context.Context, id uint) (*model.History, error) {
+	var history model.History
+	if err := r.db.WithContext(ctx).First(&history, id).Error; err != nil {
+		return nil, err
+	}
+	return &history, nil
+}
+
+// CreateItems 批量保存快照的资产明细
+func (r *HistoryRepository) CreateItems(ctx context.Context, items []model.HistoryItem) error {
+	if len(items) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Create(&items).Error
+}
+
+// GetItems 获取快照的资产明细
+func (r *HistoryRepository) GetItems(ctx context.Context, historyID uint) ([]model.HistoryItem, error) {
+	var items []model.HistoryItem
+	err := r.db.WithContext(ctx).Where("history_id = ?", historyID).Order("type DESC, id ASC").Find(&items).Error
+	return items, err
+}
+
+// GetAllItems 获取全部快照的资产明细
+func (r *HistoryRepository) GetAllItems(ctx context.Context) ([]model.HistoryItem, error) {
+	var items []model.HistoryItem
+	err := r.db.WithContext(ctx).Order("history_id ASC, id ASC").Find(&items).Error
+	return items, err
+}
+
+// DeleteItems 删除快照的资产明细
+func (r *HistoryRepository) DeleteItems(ctx context.Context, historyID uint) error {
+	return r.db.WithContext(ctx).Where("history_id = ?", historyID).Delete(&model.HistoryItem{}).Error
+}
+
+// GetInRange 获取区间内的快照，按时间升序
+func (r *HistoryRepository) GetInRange(ctx context.Context, t TimeRange) ([]model.History, error) {
+	var histories []model.History
+	err := t.apply(r.db.WithContext(ctx)).Order("created_at ASC, id ASC").Find(&histories).Error
+	return histories, err
+}
+
+// ListTimes 获取区间内所有快照的 ID 和时间，按时间升序
+func (r *HistoryRepository) ListTimes(ctx context.Context, t TimeRange) ([]TimeRow, error) {
+	var rows []TimeRow
+	err := t.apply(r.db.WithContext(ctx).Model(&model.History{})).
+		Select("id", "created_at").
+		Order("created_at ASC, id ASC").
+		Scan(&rows).Error
+	return rows, err
+}
+
+// GetByIDs 按 ID 获取快照，按时间倒序
+func (r *HistoryRepository) GetByIDs(ctx context.Context, ids []uint) ([]model.History, error) {
+	var histories []model.History
+	if len(ids) == 0 {
+		return histories, nil
+	}
+	err := r.db.WithContext(ctx).Where("id IN ?", ids).Order("created_at DESC").Find(&histories).Error
+	return histories, err
+}
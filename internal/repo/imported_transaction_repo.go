@@ -0,0 +1,54 @@
+package repo
+
+import (
+	"context"
+	"margin/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type ImportedTransactionRepository struct {
+	db *gorm.DB
+}
+
+func NewImportedTransactionRepository(db *gorm.DB) *ImportedTransactionRepository {
+	return &ImportedTransactionRepository{db: db}
+}
+
+// GetAll 获取全部导入记录
+func (r *ImportedTransactionRepository) GetAll(ctx context.Context) ([]model.ImportedTransaction, error) {
+	var transactions []model.ImportedTransaction
+	err := r.db.WithContext(ctx).Order("id ASC").Find(&transactions).Error
+	return transactions, err
+}
+
+// Existing 返回 hashes 中已经导入过的哈希
+func (r *ImportedTransactionRepository) Existing(ctx context.Context, hashes []string) (map[string]bool, error) {
+	existing := make(map[string]bool)
+	if len(hashes) == 0 {
+		return existing, nil
+	}
+
+	var found []string
+	err := r.db.WithContext(ctx).Model(&model.ImportedTransaction{}).Where("hash IN ?", hashes).Pluck("hash", &found).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range found {
+		existing[h] = true
+	}
+	return existing, nil
+}
+
+// Create 批量记录已导入的交易
+func (r *ImportedTransactionRepository) Create(ctx context.Context, transactions []model.ImportedTransaction) error {
+	if len(transactions) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).CreateInBatches(transactions, 500).Error
+}
+
+// DeleteByAsset 删除某个资产的导入记录，删除资产后重新导入流水时可以再次计入
+func (r *ImportedTransactionRepository) DeleteByAsset(ctx context.Context, code, source string) error {
+	return r.db.WithContext(ctx).Where("code = ? AND source = ?", code, source).Delete(&model.ImportedTransaction{}).Error
+}
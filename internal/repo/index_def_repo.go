@@ -0,0 +1,123 @@
+package repo
+
+import (
+	"context"
+	"margin/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IndexDefRepository struct {
+	db *gorm.DB
+}
+
+func NewIndexDefRepository(db *gorm.DB) *IndexDefRepository {
+	return &IndexDefRepository{db: db}
+}
+
+// GetAll 获取所有指数，按显示顺序
+func (r *IndexDefRepository) GetAll(ctx context.Context) ([]model.IndexDef, error) {
+	var defs []model.IndexDef
+	err := r.db.WithContext(ctx).Order("sort ASC, id ASC").Find(&defs).Error
+	return defs, err
+}
+
+// GetWatched 获取显示在行情栏的指数，按显示顺序
+func (r *IndexDefRepository) GetWatched(ctx context.Context) ([]model.IndexDef, error) {
+	var defs []model.IndexDef
+	err := r.db.WithContext(ctx).Where("watch = ?", true).Order("sort ASC, id ASC").Find(&defs).Error
+	return defs, err
+}
+
+// GetByCode 根据代码查询指数
+func (r *IndexDefRepository) GetByCode(ctx context.Context, code string) (*model.IndexDef, error) {
+	var def model.IndexDef
+	err := r.db.WithContext(ctx).Where("code = ?", code).First(&def).Error
+	if err != nil {
+		return nil, err
+	}
+	return &def, nil
+}
+
+// Create 创建指数，排在最后
+func (r *IndexDefRepository) Create(ctx context.Context, def *model.IndexDef) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var maxSort int
+		if err := tx.Model(&model.IndexDef{}).Select("COALESCE(MAX(sort), -1)").Scan(&maxSort).Error; err != nil {
+			return err
+		}
+		def.Sort = maxSort + 1
+		return tx.Create(def).Error
+	})
+}
+
+// Upsert 按代码写入指数，已存在时更新名称、行情 ID、市场、货币、顺序和是否显示
+func (r *IndexDefRepository) Upsert(ctx context.Context, defs []model.IndexDef) error {
+	if len(defs) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "code"}},
+		DoUpdates: clause.AssignmentColumns([]string{"name", "sec_id", "market", "currency", "sort", "watch", "updated_at"}),
+	}).Create(&defs).Error
+}
+
+// SetWatch 设置指数是否显示在行情栏
+func (r *IndexDefRepository) SetWatch(ctx context.Context, code string, watch bool) error {
+	return r.db.WithContext(ctx).Model(&model.IndexDef{}).Where("code = ?", code).Update("watch", watch).Error
+}
+
+// DeleteByCode 删除指数
+func (r *IndexDefRepository) DeleteByCode(ctx context.Context, code string) error {
+	return r.db.WithContext(ctx).Where("code = ?", code).Delete(&model.IndexDef{}).Error
+}
+
+// Reorder 按给定的代码顺序重排，未列出的指数保持原有先后排在后面
+func (r *IndexDefRepository) Reorder(ctx context.Context, codes []string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var defs []model.IndexDef
+		if err := tx.Order("sort ASC, id ASC").Find(&defs).Error; err != nil {
+			return err
+		}
+
+		order := make([]string, 0, len(defs))
+		listed := map[string]bool{}
+		for _, code := range codes {
+			if !listed[code] {
+				listed[code] = true
+				order = append(order, code)
+			}
+		}
+		for _, d := range defs {
+			if !listed[d.Code] {
+				order = append(order, d.Code)
+			}
+		}
+
+		for i, code := range order {
+			if err := tx.Model(&model.IndexDef{}).Where("code = ?", code).Update("sort", i).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// InitDefaultIndexes 表为空时写入默认指数，用户删除的指数不会在下次启动时恢复
+func (r *IndexDefRepository) InitDefaultIndexes(ctx context.Context) error {
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&model.IndexDef{}).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	defs := make([]model.IndexDef, len(model.DefaultIndexes))
+	for i, d := range model.DefaultIndexes {
+		d.Sort = i
+		defs[i] = d
+	}
+	return r.db.WithContext(ctx).Create(&defs).Error
+}
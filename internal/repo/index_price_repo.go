@@ -0,0 +1,71 @@
+package repo
+
+import (
+	"context"
+	"margin/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IndexPriceRepository struct {
+	db *gorm.DB
+}
+
+func NewIndexPriceRepository(db *gorm.DB) *IndexPriceRepository {
+	return &IndexPriceRepository{db: db}
+}
+
+// GetRange 获取指数在 [from, to] 之间的日线，按日期升序；from/to 为空表示不限
+func (r *IndexPriceRepository) GetRange(ctx context.Context, code, from, to string) ([]model.IndexPrice, error) {
+	query := r.db.WithContext(ctx).Where("code = ?", code)
+	if from != "" {
+		query = query.Where("date >= ?", from)
+	}
+	if to != "" {
+		query = query.Where("date <= ?", to)
+	}
+
+	var prices []model.IndexPrice
+	err := query.Order("date ASC").Find(&prices).Error
+	return prices, err
+}
+
+// GetLatest 获取指数最新一条日线
+func (r *IndexPriceRepository) GetLatest(ctx context.Context, code string) (*model.IndexPrice, error) {
+	var price model.IndexPrice
+	err := r.db.WithContext(ctx).Where("code = ?", code).Order("date DESC").First(&price).Error
+	if err != nil {
+		return nil, err
+	}
+	return &price, nil
+}
+
+// IndexPriceStats 某个指数的缓存概况
+type IndexPriceStats struct {
+	Code  string
+	Count int
+	First string
+	Last  string
+}
+
+// Stats 按指数统计缓存的日线数量和起止日期
+func (r *IndexPriceRepository) Stats(ctx context.Context) ([]IndexPriceStats, error) {
+	var stats []IndexPriceStats
+	err := r.db.WithContext(ctx).Model(&model.IndexPrice{}).
+		Select("code, COUNT(*) AS count, MIN(date) AS first, MAX(date) AS last").
+		Group("code").
+		Scan(&stats).Error
+	return stats, err
+}
+
+// Upsert 批量写入日线，同一交易日已存在时更新收盘价
+func (r *IndexPriceRepository) Upsert(ctx context.Context, prices []model.IndexPrice) error {
+	if len(prices) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "code"}, {Name: "date"}},
+		DoUpdates: clause.AssignmentColumns([]string{"close"}),
+	}).CreateInBatches(prices, 500).Error
+}
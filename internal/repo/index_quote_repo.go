@@ -0,0 +1,37 @@
+package repo
+
+import (
+	"context"
+	"margin/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IndexQuoteRepository struct {
+	db *gorm.DB
+}
+
+func NewIndexQuoteRepository(db *gorm.DB) *IndexQuoteRepository {
+	return &IndexQuoteRepository{db: db}
+}
+
+// GetByCodes 获取指定指数的缓存行情
+func (r *IndexQuoteRepository) GetByCodes(ctx context.Context, codes []string) ([]model.IndexQuote, error) {
+	var quotes []model.IndexQuote
+	if len(codes) == 0 {
+		return quotes, nil
+	}
+	err := r.db.WithContext(ctx).Where("code IN ?", codes).Find(&quotes).Error
+	return quotes, err
+}
+
+// Upsert 写入行情，同一指数已存在时覆盖
+func (r *IndexQuoteRepository) Upsert(ctx context.Context, quote *model.IndexQuote) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "code"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"price", "change", "change_rate", "open", "high", "low", "prev_close", "volume", "amount", "quote_time", "fetched_at", "updated_at",
+		}),
+	}).Create(quote).Error
+}
@@ -0,0 +1,50 @@
+package repo
+
+import (
+	"context"
+	"margin/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type LotRepository struct {
+	db *gorm.DB
+}
+
+func NewLotRepository(db *gorm.DB) *LotRepository {
+	return &LotRepository{db: db}
+}
+
+// GetAll 获取所有持仓批次
+func (r *LotRepository) GetAll(ctx context.Context) ([]model.AssetLot, error) {
+	var lots []model.AssetLot
+	err := r.db.WithContext(ctx).Order("asset_id ASC, buy_date ASC").Find(&lots).Error
+	return lots, err
+}
+
+// GetByAsset 获取资产的持仓批次
+func (r *LotRepository) GetByAsset(ctx context.Context, assetID uint) ([]model.AssetLot, error) {
+	var lots []model.AssetLot
+	err := r.db.WithContext(ctx).Where("asset_id = ?", assetID).Order("buy_date ASC").Find(&lots).Error
+	return lots, err
+}
+
+// Create 创建持仓批次
+func (r *LotRepository) Create(ctx context.Context, lot *model.AssetLot) error {
+	return r.db.WithContext(ctx).Create(lot).Error
+}
+
+// UpdateAmount 更新持仓批次的金额（加密后）
+func (r *LotRepository) UpdateAmount(ctx context.Context, id uint, encryptedAmount string) error {
+	return r.db.WithContext(ctx).Model(&model.AssetLot{}).Where("id = ?", id).Update("encrypted_amount", encryptedAmount).Error
+}
+
+// Delete 删除持仓批次
+func (r *LotRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&model.AssetLot{}, id).Error
+}
+
+// DeleteByAsset 删除资产的全部持仓批次
+func (r *LotRepository) DeleteByAsset(ctx context.Context, assetID uint) error {
+	return r.db.WithContext(ctx).Where("asset_id = ?", assetID).Delete(&model.AssetLot{}).Error
+}
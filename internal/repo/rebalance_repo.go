@@ -37,7 +37,105 @@ func (r *RebalanceRepository) GetLatest(ctx context.Context) (*model.Rebalance,
 	return &rebalance, nil
 }
 
+// ListTimes 获取区间内所有再平衡记录的 ID 和时间，按时间升序
+func (r *RebalanceRepository) ListTimes(ctx context.Context, t TimeRange) ([]TimeRow, error) {
+	var rows []TimeRow
+	err := t.apply(r.db.WithContext(ctx).Model(&model.Rebalance{})).
+		Select("id", "created_at").
+		Order("created_at ASC, id ASC").
+		Scan(&rows).Error
+	return rows, err
+}
+
+// GetByIDs 按 ID 获取再平衡记录，按时间倒序
+func (r *RebalanceRepository) GetByIDs(ctx context.Context, ids []uint) ([]*model.Rebalance, error) {
+	var rebalances []*model.Rebalance
+	if len(ids) == 0 {
+		return rebalances, nil
+	}
+	err := r.db.WithContext(ctx).Where("id IN ?", ids).Order("created_at DESC").Find(&rebalances).Error
+	return rebalances, err
+}
+
+// GetOrdersByRebalanceIDs 获取多条再平衡记录的交易
+func (r *RebalanceRepository) GetOrdersByRebalanceIDs(ctx context.Context, ids []uint) ([]model.RebalanceOrder, error) {
+	var orders []model.RebalanceOrder
+	if len(ids) == 0 {
+		return orders, nil
+	}
+	err := r.db.WithContext(ctx).Where("rebalance_id IN ?", ids).Order("rebalance_id ASC, id ASC").Find(&orders).Error
+	return orders, err
+}
+
 // Delete 删除再平衡记录
 func (r *RebalanceRepository) Delete(ctx context.Context, id uint) error {
 	return r.db.WithContext(ctx).Delete(&model.Rebalance{}, id).Error
 }
+
+// CreateHoldings 批量保存再平衡持仓快照
+func (r *RebalanceRepository) CreateHoldings(ctx context.Context, holdings []model.RebalanceHolding) error {
+	if len(holdings) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Create(&holdings).Error
+}
+
+// GetHoldings 获取再平衡记录的持仓快照
+func (r *RebalanceRepository) GetHoldings(ctx context.Context, rebalanceID uint) ([]model.RebalanceHolding, error) {
+	var holdings []model.RebalanceHolding
+	err := r.db.WithContext(ctx).Where("rebalance_id = ?", rebalanceID).Order("type DESC, ratio DESC").Find(&holdings).Error
+	return holdings, err
+}
+
+// GetAllHoldings 获取全部持仓快照
+func (r *RebalanceRepository) GetAllHoldings(ctx context.Context) ([]model.RebalanceHolding, error) {
+	var holdings []model.RebalanceHolding
+	err := r.db.WithContext(ctx).Order("rebalance_id ASC, id ASC").Find(&holdings).Error
+	return holdings, err
+}
+
+// DeleteHoldings 删除再平衡记录的持仓快照
+func (r *RebalanceRepository) DeleteHoldings(ctx context.Context, rebalanceID uint) error {
+	return r.db.WithContext(ctx).Where("rebalance_id = ?", rebalanceID).Delete(&model.RebalanceHolding{}).Error
+}
+
+// CreateOrders 批量保存再平衡交易
+func (r *RebalanceRepository) CreateOrders(ctx context.Context, orders []model.RebalanceOrder) error {
+	if len(orders) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Create(&orders).Error
+}
+
+// GetOrders 获取再平衡记录的交易
+func (r *RebalanceRepository) GetOrders(ctx context.Context, rebalanceID uint) ([]model.RebalanceOrder, error) {
+	var orders []model.RebalanceOrder
+	err := r.db.WithContext(ctx).Where("rebalance_id = ?", rebalanceID).Order("id ASC").Find(&orders).Error
+	return orders, err
+}
+
+// GetAllOrders 获取全部再平衡交易
+func (r *RebalanceRepository) GetAllOrders(ctx context.Context) ([]model.RebalanceOrder, error) {
+	var orders []model.RebalanceOrder
+	err := r.db.WithContext(ctx).Order("rebalance_id ASC, id ASC").Find(&orders).Error
+	return orders, err
+}
+
+// GetOrder 获取单笔再平衡交易
+func (r *RebalanceRepository) GetOrder(ctx context.Context, id uint) (*model.RebalanceOrder, error) {
+	var order model.RebalanceOrder
+	if err := r.db.WithContext(ctx).First(&order, id).Error; err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// UpdateOrder 更新再平衡交易
+func (r *RebalanceRepository) UpdateOrder(ctx context.Context, order *model.RebalanceOrder) error {
+	return r.db.WithContext(ctx).Save(order).Error
+}
+
+// DeleteOrders 删除再平衡记录的交易
+func (r *RebalanceRepository) DeleteOrders(ctx context.Context, rebalanceID uint) error {
+	return r.db.WithContext(ctx).Where("rebalance_id = ?", rebalanceID).Delete(&model.RebalanceOrder{}).Error
+}
@@ -0,0 +1,56 @@
+package repo
+
+import (
+	"context"
+	"margin/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type ReviewRepository struct {
+	db *gorm.DB
+}
+
+func NewReviewRepository(db *gorm.DB) *ReviewRepository {
+	return &ReviewRepository{db: db}
+}
+
+// Create 创建检查记录
+func (r *ReviewRepository) Create(ctx context.Context, review *model.RebalanceReview) error {
+	return r.db.WithContext(ctx).Create(review).Error
+}
+
+// GetAll 获取所有检查记录
+func (r *ReviewRepository) GetAll(ctx context.Context) ([]model.RebalanceReview, error) {
+	var reviews []model.RebalanceReview
+	err := r.db.WithContext(ctx).Order("due_date DESC, id DESC").Find(&reviews).Error
+	return reviews, err
+}
+
+// GetLatest 获取计划日期最晚的检查记录
+func (r *ReviewRepository) GetLatest(ctx context.Context) (*model.RebalanceReview, error) {
+	var review model.RebalanceReview
+	if err := r.db.WithContext(ctx).Order("due_date DESC, id DESC").First(&review).Error; err != nil {
+		return nil, err
+	}
+	return &review, nil
+}
+
+// Get 获取检查记录
+func (r *ReviewRepository) Get(ctx context.Context, id uint) (*model.RebalanceReview, error) {
+	var review model.RebalanceReview
+	if err := r.db.WithContext(ctx).First(&review, id).Error; err != nil {
+		return nil, err
+	}
+	return &review, nil
+}
+
+// Update 更新检查记录
+func (r *ReviewRepository) Update(ctx context.Context, review *model.RebalanceReview) error {
+	return r.db.WithContext(ctx).Save(review).Error
+}
+
+// Delete 删除检查记录
+func (r *ReviewRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&model.RebalanceReview{}, id).Error
+}
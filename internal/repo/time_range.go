@@ -0,0 +1,29 @@
+package repo
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TimeRange 按创建时间筛选的区间 [From, To]，零值表示不限
+type TimeRange struct {
+	From time.Time
+	To   time.Time
+}
+
+func (t TimeRange) apply(db *gorm.DB) *gorm.DB {
+	if !t.From.IsZero() {
+		db = db.Where("created_at >= ?", t.From)
+	}
+	if !t.To.IsZero() {
+		db = db.Where("created_at <= ?", t.To)
+	}
+	return db
+}
+
+// TimeRow 记录的 ID 和创建时间，用于在解密前筛选、降采样和分页
+type TimeRow struct {
+	ID        uint
+	CreatedAt time.Time
+}
@@ -0,0 +1,290 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"margin/internal/crypto"
+	"margin/internal/model"
+	"margin/internal/repo"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AnalyticsService 基于历史快照和资金流水的收益分析
+type AnalyticsService struct {
+	db           *gorm.DB
+	historyRepo  *repo.HistoryRepository
+	cashFlowRepo *repo.CashFlowRepository
+	assetRepo    *repo.AssetRepository
+	configRepo   *repo.ConfigRepository
+}
+
+func NewAnalyticsService(db *gorm.DB) *AnalyticsService {
+	return &AnalyticsService{
+		db:           db,
+		historyRepo:  repo.NewHistoryRepository(db),
+		cashFlowRepo: repo.NewCashFlowRepository(db),
+		assetRepo:    repo.NewAssetRepository(db),
+		configRepo:   repo.NewConfigRepository(db),
+	}
+}
+
+// CashFlowInput 手动录入的资金流水
+type CashFlowInput struct {
+	AssetID uint   // 资金存入或取出的资产，为 0 时使用 Type 和 Source
+	Date    string // 格式 2006-01-02
+	Kind    string // deposit/withdrawal
+	Type    string // 资产类型，AssetID 为 0 时必填
+	Source  string
+	Amount  float64
+	Note    string
+}
+
+// AddCashFlow 录入资金流水
+func (s *AnalyticsService) AddCashFlow(ctx context.Context, in CashFlowInput) error {
+	if in.Kind != model.CashFlowDeposit && in.Kind != model.CashFlowWithdrawal {
+		return fmt.Errorf("不支持的流水类型: %s", in.Kind)
+	}
+	if in.Amount <= 0 {
+		return errors.New("金额必须大于 0")
+	}
+	date, err := time.ParseInLocation("2006-01-02", in.Date, time.Local)
+	if err != nil {
+		return fmt.Errorf("日期格式错误: %w", err)
+	}
+
+	flow := &model.CashFlow{
+		Date:   date,
+		Kind:   in.Kind,
+		Type:   in.Type,
+		Source: in.Source,
+		Note:   in.Note,
+	}
+	if in.AssetID > 0 {
+		asset, err := s.assetRepo.GetByID(ctx, in.AssetID)
+		if err != nil {
+			return err
+		}
+		flow.AssetID = asset.ID
+		flow.Code = asset.Code
+		flow.Name = asset.Name
+		flow.Type = asset.Type
+		flow.Source = asset.Source
+	}
+	if flow.Type != model.AssetTypeStock && flow.Type != model.AssetTypeBond {
+		return errors.New("请选择资产或资产类型")
+	}
+
+	encryptKey, err := s.configRepo.Get(ctx, model.ConfigKeyEncryptKey)
+	if err != nil {
+		return err
+	}
+	flow.EncryptedAmount, err = crypto.Encrypt(fmt.Sprintf("%.2f", in.Amount), encryptKey.Value)
+	if err != nil {
+		return err
+	}
+	return s.cashFlowRepo.Create(ctx, flow)
+}
+
+// GetCashFlows 获取全部资金流水（最新的在前）
+func (s *AnalyticsService) GetCashFlows(ctx context.Context) ([]map[string]interface{}, error) {
+	encryptKey, err := s.configRepo.Get(ctx, model.ConfigKeyEncryptKey)
+	if err != nil {
+		return nil, err
+	}
+	flows, err := s.cashFlowRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]map[string]interface{}, 0, len(flows))
+	for i := len(flows) - 1; i >= 0; i-- {
+		f := flows[i]
+		amount, err := decryptAmount(f.EncryptedAmount, encryptKey.Value)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, map[string]interface{}{
+			"id":       f.ID,
+			"date":     f.Date.Format("2006-01-02"),
+			"kind":     f.Kind,
+			"asset_id": f.AssetID,
+			"code":     f.Code,
+			"name":     f.Name,
+			"type":     f.Type,
+			"source":   f.Source,
+			"amount":   amount,
+			"order_id": f.OrderID,
+			"note":     f.Note,
+		})
+	}
+	return result, nil
+}
+
+// DeleteCashFlow 删除资金流水
+func (s *AnalyticsService) DeleteCashFlow(ctx context.Context, id uint) error {
+	return s.cashFlowRepo.Delete(ctx, id)
+}
+
+// segment 收益分析的一个维度（整体、某一类别或某一来源）
+type segment struct {
+	key, label string
+	points     []valuePoint
+	flows      []datedFlow
+}
+
+// GetPerformance 计算 [from, to] 之间的收益指标（日期格式 2006-01-02，空表示不限），
+// 按整体、资产类别和来源分别给出。riskFree 为无风险年收益率(%)，用于夏普比率
+func (s *AnalyticsService) GetPerformance(ctx context.Context, from, to string, riskFree float64) (map[string]interface{}, error) {
+	var start, end time.Time
+	var err error
+	if from != "" {
+		if start, err = time.ParseInLocation("2006-01-02", from, time.Local); err != nil {
+			return nil, fmt.Errorf("开始日期格式错误: %w", err)
+		}
+	}
+	if to != "" {
+		if end, err = time.ParseInLocation("2006-01-02", to, time.Local); err != nil {
+			return nil, fmt.Errorf("结束日期格式错误: %w", err)
+		}
+		end = end.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+
+	encryptKey, err := s.configRepo.Get(ctx, model.ConfigKeyEncryptKey)
+	if err != nil {
+		return nil, err
+	}
+	key := encryptKey.Value
+
+	histories, err := s.historyRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(histories, func(i, j int) bool { return histories[i].CreatedAt.Before(histories[j].CreatedAt) })
+
+	total := &segment{key: "total", label: "整体"}
+	classes := map[string]*segment{
+		model.AssetTypeStock: {key: model.AssetTypeStock, label: "股票"},
+		model.AssetTypeBond:  {key: model.AssetTypeBond, label: "债券"},
+	}
+	sources := map[string]*segment{}
+	var sourceOrder []string
+
+	var first, last time.Time
+	for _, h := range histories {
+		if (!start.IsZero() && h.CreatedAt.Before(start)) || (!end.IsZero() && h.CreatedAt.After(end)) {
+			continue
+		}
+		if first.IsZero() {
+			first = h.CreatedAt
+		}
+		last = h.CreatedAt
+
+		stock, err := decryptAmount(h.EncryptedStockTotal, key)
+		if err != nil {
+			return nil, err
+		}
+		bond, err := decryptAmount(h.EncryptedBondTotal, key)
+		if err != nil {
+			return nil, err
+		}
+		total.points = append(total.points, valuePoint{h.CreatedAt, stock + bond})
+		classes[model.AssetTypeStock].points = append(classes[model.AssetTypeStock].points, valuePoint{h.CreatedAt, stock})
+		classes[model.AssetTypeBond].points = append(classes[model.AssetTypeBond].points, valuePoint{h.CreatedAt, bond})
+
+		// 来源维度需要快照的资产明细，旧版本快照跳过
+		items, err := s.historyRepo.GetItems(ctx, h.ID)
+		if err != nil {
+			return nil, err
+		}
+		if len(items) == 0 {
+			continue
+		}
+		bySource := map[string]float64{}
+		for _, item := range items {
+			amount, err := decryptAmount(item.EncryptedAmount, key)
+			if err != nil {
+				return nil, err
+			}
+			bySource[item.Source] += amount
+		}
+		for name := range bySource {
+			if _, ok := sources[name]; !ok {
+				sources[name] = &segment{key: name, label: name}
+				sourceOrder = append(sourceOrder, name)
+			}
+		}
+		// 某来源在该快照中没有持仓时记为 0
+		for _, name := range sourceOrder {
+			sources[name].points = append(sources[name].points, valuePoint{h.CreatedAt, bySource[name]})
+		}
+	}
+	if len(total.points) < 2 {
+		return nil, errors.New("所选区间内至少需要两个快照")
+	}
+
+	flows, err := s.cashFlowRepo.GetRange(ctx, localDay(first), localDay(last))
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range flows {
+		flow, err := signedFlow(f, key)
+		if err != nil {
+			return nil, err
+		}
+
+		// 内部调仓不影响整体，只影响类别和来源
+		if f.Kind == model.CashFlowDeposit || f.Kind == model.CashFlowWithdrawal {
+			total.flows = append(total.flows, flow)
+		}
+		if c, ok := classes[f.Type]; ok {
+			c.flows = append(c.flows, flow)
+		}
+		if src, ok := sources[f.Source]; ok {
+			src.flows = append(src.flows, flow)
+		}
+	}
+
+	measure := func(seg *segment) map[string]interface{} {
+		item := map[string]interface{}{"key": seg.key, "label": seg.label}
+		p, err := measurePerformance(seg.points, seg.flows, riskFree)
+		if err != nil {
+			item["error"] = err.Error()
+			return item
+		}
+		for k, v := range p.ToMap() {
+			item[k] = v
+		}
+		return item
+	}
+
+	totalResult := measure(total)
+	classResults := []map[string]interface{}{measure(classes[model.AssetTypeStock]), measure(classes[model.AssetTypeBond])}
+	sourceResults := make([]map[string]interface{}, 0, len(sourceOrder))
+	for _, name := range sourceOrder {
+		sourceResults = append(sourceResults, measure(sources[name]))
+	}
+
+	return map[string]interface{}{
+		"risk_free":  riskFree,
+		"total":      totalResult,
+		"classes":    classResults,
+		"sources":    sourceResults,
+		"flow_count": len(flows),
+	}, nil
+}
+
+// signedFlow 解密资金流水，流出组合（取出、调仓转出）记为负数
+func signedFlow(f model.CashFlow, key string) (datedFlow, error) {
+	amount, err := decryptAmount(f.EncryptedAmount, key)
+	if err != nil {
+		return datedFlow{}, err
+	}
+	if f.Kind == model.CashFlowWithdrawal || f.Kind == model.CashFlowTransferOut {
+		amount = -amount
+	}
+	return datedFlow{Date: f.Date, Amount: amount}, nil
+}
@@ -8,6 +8,7 @@ import (
 	"margin/internal/model"
 	"margin/internal/repo"
 	"strconv"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -16,6 +17,8 @@ type AssetService struct {
 	db         *gorm.DB
 	assetRepo  *repo.AssetRepository
 	configRepo *repo.ConfigRepository
+	feeRepo    *repo.FeeRuleRepository
+	lotRepo    *repo.LotRepository
 }
 
 func NewAssetService(db *gorm.DB) *AssetService {
@@ -23,10 +26,19 @@ func NewAssetService(db *gorm.DB) *AssetService {
 		db:         db,
 		assetRepo:  repo.NewAssetRepository(db),
 		configRepo: repo.NewConfigRepository(db),
+		feeRepo:    repo.NewFeeRuleRepository(db),
+		lotRepo:    repo.NewLotRepository(db),
 	}
 }
 
-func (s *AssetService) GetAssets(ctx context.Context) ([]map[string]interface{}, error) {
+// Holding 解密后的资产持仓
+type Holding struct {
+	Asset  model.Asset
+	Amount float64
+}
+
+// LoadHoldings 读取全部资产并解密金额
+func (s *AssetService) LoadHoldings(ctx context.Context) ([]Holding, error) {
 	assets, err := s.assetRepo.GetAll(ctx)
 	if err != nil {
 		return nil, err
@@ -37,23 +49,50 @@ func (s *AssetService) GetAssets(ctx context.Context) ([]map[string]interface{},
 		return nil, err
 	}
 
-	result := make([]map[string]interface{}, 0, len(assets))
+	holdings := make([]Holding, 0, len(assets))
 	for _, asset := range assets {
 		amountStr, err := crypto.Decrypt(asset.EncryptedAmount, encryptKey.Value)
 		if err != nil {
 			return nil, err
 		}
 		amount, _ := strconv.ParseFloat(amountStr, 64)
+		holdings = append(holdings, Holding{Asset: asset, Amount: amount})
+	}
 
+	return holdings, nil
+}
+
+// portfolioTotals 汇总股票和债券金额（非股票类均计入债券）
+func portfolioTotals(holdings []Holding) (stockTotal, bondTotal float64) {
+	for _, h := range holdings {
+		if h.Asset.Type == model.AssetTypeStock {
+			stockTotal += h.Amount
+		} else {
+			bondTotal += h.Amount
+		}
+	}
+	return stockTotal, bondTotal
+}
+
+func (s *AssetService) GetAssets(ctx context.Context) ([]map[string]interface{}, error) {
+	holdings, err := s.LoadHoldings(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]map[string]interface{}, 0, len(holdings))
+	for _, h := range holdings {
+		asset := h.Asset
 		result = append(result, map[string]interface{}{
-			"id":      asset.ID,
-			"code":    asset.Code,
-			"name":    asset.Name,
-			"url":     asset.URL,
-			"type":    asset.Type,
-			"source":  asset.Source,
-			"amount":  amount,
-			"created": asset.CreatedAt,
+			"id":            asset.ID,
+			"code":          asset.Code,
+			"name":          asset.Name,
+			"url":           asset.URL,
+			"type":          asset.Type,
+			"source":        asset.Source,
+			"amount":        h.Amount,
+			"target_weight": asset.TargetWeight,
+			"created":       asset.CreatedAt,
 		})
 	}
 
@@ -107,20 +146,12 @@ func (s *AssetService) SaveAsset(ctx context.Context, code, name, url, assetType
 }
 
 func (s *AssetService) GetPortfolioRatio(ctx context.Context) (map[string]float64, error) {
-	assets, err := s.GetAssets(ctx)
+	holdings, err := s.LoadHoldings(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	var stockTotal, bondTotal float64
-	for _, asset := range assets {
-		amount := asset["amount"].(float64)
-		if asset["type"] == model.AssetTypeStock {
-			stockTotal += amount
-		} else {
-			bondTotal += amount
-		}
-	}
+	stockTotal, bondTotal := portfolioTotals(holdings)
 
 	total := stockTotal + bondTotal
 	if total == 0 {
@@ -134,20 +165,12 @@ func (s *AssetService) GetPortfolioRatio(ctx context.Context) (map[string]float6
 }
 
 func (s *AssetService) GetRebalanceAdvice(ctx context.Context, targetStockRatio float64) (map[string]interface{}, error) {
-	assets, err := s.GetAssets(ctx)
+	holdings, err := s.LoadHoldings(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	var stockTotal, bondTotal float64
-	for _, asset := range assets {
-		amount := asset["amount"].(float64)
-		if asset["type"] == model.AssetTypeStock {
-			stockTotal += amount
-		} else {
-			bondTotal += amount
-		}
-	}
+	stockTotal, bondTotal := portfolioTotals(holdings)
 
 	total := stockTotal + bondTotal
 	if total == 0 {
@@ -164,6 +187,20 @@ func (s *AssetService) GetRebalanceAdvice(ctx context.Context, targetStockRatio
 	stockAdjust := targetStockAmount - stockTotal
 	bondAdjust := targetBondAmount - bondTotal
 
+	bands, err := loadRebalanceBands(ctx, s.configRepo)
+	if err != nil {
+		return nil, err
+	}
+	checks := bands.check(currentStockRatio, targetStockRatio)
+	bandResults, reasons := bandChecksToMap(checks)
+
+	// 按基金级计划估算完整再平衡的交易费用
+	fees, err := s.loadFeeModel(ctx)
+	if err != nil {
+		return nil, err
+	}
+	plan := planRebalance(holdings, targetStockRatio, 0, fees)
+
 	return map[string]interface{}{
 		"total_assets":        total,
 		"current_stock_total": stockTotal,
@@ -176,20 +213,30 @@ func (s *AssetService) GetRebalanceAdvice(ctx context.Context, targetStockRatio
 		"target_bond_total":   targetBondAmount,
 		"stock_adjust":        stockAdjust,
 		"bond_adjust":         bondAdjust,
-		"need_rebalance":      abs(currentStockRatio-targetStockRatio) > 0.01,
+		"need_rebalance":      len(reasons) > 0,
+		"rebalance_reasons":   reasons,
+		"band_checks":         bandResults,
+		"estimated_fee":       plan.EstimatedFee,
+		"fee_known":           plan.FeeKnown,
 	}, nil
 }
 
-func abs(x float64) float64 {
-	if x < 0 {
-		return -x
-	}
-	return x
-}
-
-// DeleteAsset 删除资产
+// DeleteAsset 在同一事务中删除资产、持仓批次和账单导入记录
 func (s *AssetService) DeleteAsset(ctx context.Context, id uint) error {
-	return s.assetRepo.Delete(ctx, id)
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		assetRepo := repo.NewAssetRepository(tx)
+		asset, err := assetRepo.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := repo.NewLotRepository(tx).DeleteByAsset(ctx, id); err != nil {
+			return err
+		}
+		if err := repo.NewImportedTransactionRepository(tx).DeleteByAsset(ctx, asset.Code, asset.Source); err != nil {
+			return err
+		}
+		return assetRepo.Delete(ctx, id)
+	})
 }
 
 // UpdateAssetAmount 更新资产金额
@@ -256,3 +303,164 @@ func (s *AssetService) UpdateAsset(ctx context.Context, id uint, assetType, sour
 	asset.EncryptedAmount = encryptedAmount
 	return s.assetRepo.Update(ctx, &asset)
 }
+
+// SetAssetTargetWeight 设置资产在所属类别（股票/债券）内的目标权重（%），0 表示不设置
+func (s *AssetService) SetAssetTargetWeight(ctx context.Context, id uint, weight float64) error {
+	if weight < 0 || weight > 100 {
+		return errors.New("目标权重需在 0-100 之间")
+	}
+
+	var asset model.Asset
+	if err := s.db.WithContext(ctx).First(&asset, id).Error; err != nil {
+		return err
+	}
+
+	asset.TargetWeight = weight
+	return s.assetRepo.Update(ctx, &asset)
+}
+
+// GetRebalancePlan 生成基金级别的再平衡交易计划，minTrade 为单笔最小交易金额
+func (s *AssetService) GetRebalancePlan(ctx context.Context, targetStockRatio, minTrade float64) (map[string]interface{}, error) {
+	if targetStockRatio < 0 || targetStockRatio > 100 {
+		return nil, errors.New("目标股票比例需在 0-100 之间")
+	}
+
+	holdings, err := s.LoadHoldings(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	stockTotal, bondTotal := portfolioTotals(holdings)
+	if stockTotal+bondTotal == 0 {
+		return nil, errors.New("no assets found")
+	}
+
+	fees, err := s.loadFeeModel(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := planRebalance(holdings, targetStockRatio, minTrade, fees)
+	return plan.toMap(), nil
+}
+
+// PlanContribution 规划新增资金（amount 为负时表示取出）的分配，不产生反向交易
+func (s *AssetService) PlanContribution(ctx context.Context, amount, targetStockRatio float64) (map[string]interface{}, error) {
+	if targetStockRatio < 0 || targetStockRatio > 100 {
+		return nil, errors.New("目标股票比例需在 0-100 之间")
+	}
+	if amount == 0 {
+		return nil, errors.New("金额不能为 0")
+	}
+
+	holdings, err := s.LoadHoldings(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	stockTotal, bondTotal := portfolioTotals(holdings)
+	if amount < 0 && -amount > stockTotal+bondTotal {
+		return nil, errors.New("取出金额超过资产总额")
+	}
+
+	fees, err := s.loadFeeModel(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := planContribution(holdings, amount, targetStockRatio, fees)
+	result := plan.toMap()
+
+	// 按容忍带规则检查执行后是否仍需再平衡
+	bands, err := loadRebalanceBands(ctx, s.configRepo)
+	if err != nil {
+		return nil, err
+	}
+	bandResults, reasons := bandChecksToMap(bands.check(plan.AfterStockRatio, targetStockRatio))
+	result["band_checks"] = bandResults
+	result["rebalance_reasons"] = reasons
+	result["need_rebalance"] = len(reasons) > 0
+
+	return result, nil
+}
+
+// loadFeeModel 读取费率规则和持仓批次，用于估算交易费用
+func (s *AssetService) loadFeeModel(ctx context.Context) (*feeModel, error) {
+	encryptKey, err := s.configRepo.Get(ctx, model.ConfigKeyEncryptKey)
+	if err != nil {
+		return nil, err
+	}
+	return loadFeeModel(ctx, s.feeRepo, s.lotRepo, encryptKey.Value)
+}
+
+// GetAssetLots 获取资产的持仓批次，附带当前持有天数
+func (s *AssetService) GetAssetLots(ctx context.Context, assetID uint) ([]map[string]interface{}, error) {
+	lots, err := s.lotRepo.GetByAsset(ctx, assetID)
+	if err != nil {
+		return nil, err
+	}
+
+	encryptKey, err := s.configRepo.Get(ctx, model.ConfigKeyEncryptKey)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	result := make([]map[string]interface{}, 0, len(lots))
+	for _, lot := range lots {
+		amountStr, err := crypto.Decrypt(lot.EncryptedAmount, encryptKey.Value)
+		if err != nil {
+			return nil, err
+		}
+		amount, _ := strconv.ParseFloat(amountStr, 64)
+
+		result = append(result, map[string]interface{}{
+			"id":        lot.ID,
+			"asset_id":  lot.AssetID,
+			"buy_date":  lot.BuyDate.Format("2006-01-02"),
+			"held_days": int(now.Sub(lot.BuyDate).Hours() / 24),
+			"amount":    amount,
+		})
+	}
+
+	return result, nil
+}
+
+// AddAssetLot 添加持仓批次，buyDate 格式为 2006-01-02
+func (s *AssetService) AddAssetLot(ctx context.Context, assetID uint, buyDate string, amount float64) error {
+	if amount <= 0 {
+		return errors.New("买入金额必须大于 0")
+	}
+	date, err := time.ParseInLocation("2006-01-02", buyDate, time.Local)
+	if err != nil {
+		return fmt.Errorf("买入日期格式错误: %w", err)
+	}
+	if date.After(time.Now()) {
+		return errors.New("买入日期不能晚于今天")
+	}
+
+	var asset model.Asset
+	if err := s.db.WithContext(ctx).First(&asset, assetID).Error; err != nil {
+		return err
+	}
+
+	encryptKey, err := s.configRepo.Get(ctx, model.ConfigKeyEncryptKey)
+	if err != nil {
+		return err
+	}
+	encryptedAmount, err := crypto.Encrypt(fmt.Sprintf("%.2f", amount), encryptKey.Value)
+	if err != nil {
+		return err
+	}
+
+	return s.lotRepo.Create(ctx, &model.AssetLot{
+		AssetID:         asset.ID,
+		BuyDate:         date,
+		EncryptedAmount: encryptedAmount,
+	})
+}
+
+// DeleteAssetLot 删除持仓批次
+func (s *AssetService) DeleteAssetLot(ctx context.Context, id uint) error {
+	return s.lotRepo.Delete(ctx, id)
+}
@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"margin/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 自动快照的触发时机
+const (
+	SnapshotTriggerStartup = "startup" // 应用启动
+	SnapshotTriggerChange  = "change"  // 持仓变化（增删改资产、导入、成交确认）
+)
+
+// SnapshotPolicy 自动保存历史快照的策略
+type SnapshotPolicy struct {
+	Daily    bool `json:"daily"`     // 启动时如果今天还没有快照则保存一条
+	OnChange bool `json:"on_change"` // 持仓变化后保存一条（与最新快照相同时跳过）
+}
+
+// ToMap 转换为前端展示的结构
+func (p SnapshotPolicy) ToMap() map[string]interface{} {
+	return map[string]interface{}{
+		"daily":     p.Daily,
+		"on_change": p.OnChange,
+	}
+}
+
+// GetSnapshotPolicy 获取自动快照策略，未配置时全部关闭
+func (s *HistoryService) GetSnapshotPolicy(ctx context.Context) (SnapshotPolicy, error) {
+	config, err := s.configRepo.Get(ctx, model.ConfigKeySnapshotPolicy)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return SnapshotPolicy{}, nil
+	}
+	if err != nil {
+		return SnapshotPolicy{}, err
+	}
+
+	var p SnapshotPolicy
+	if err := json.Unmarshal([]byte(config.Value), &p); err != nil {
+		return SnapshotPolicy{}, fmt.Errorf("自动快照配置格式错误: %w", err)
+	}
+	return p, nil
+}
+
+// SaveSnapshotPolicy 保存自动快照策略
+func (s *HistoryService) SaveSnapshotPolicy(ctx context.Context, p SnapshotPolicy) error {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	return s.configRepo.Set(ctx, model.ConfigKeySnapshotPolicy, string(data))
+}
+
+// AutoSnapshot 按策略自动保存快照，返回是否保存了新快照。
+// 启动时今天已有快照则跳过；持仓变化时总额与最新快照相同则跳过
+func (s *HistoryService) AutoSnapshot(ctx context.Context, trigger string) (bool, error) {
+	policy, err := s.GetSnapshotPolicy(ctx)
+	if err != nil {
+		return false, err
+	}
+	switch trigger {
+	case SnapshotTriggerStartup:
+		if !policy.Daily {
+			return false, nil
+		}
+	case SnapshotTriggerChange:
+		if !policy.OnChange {
+			return false, nil
+		}
+	default:
+		return false, fmt.Errorf("不支持的触发时机: %s", trigger)
+	}
+
+	encryptKey, err := s.configRepo.Get(ctx, model.ConfigKeyEncryptKey)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		// 尚未设置密码，没有可保存的数据
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	holdings, err := s.currentHoldings(ctx, encryptKey.Value)
+	if err != nil {
+		return false, err
+	}
+	if stockTotal, bondTotal := snapshotTotals(holdings); stockTotal+bondTotal == 0 {
+		return false, nil
+	}
+
+	latest, err := s.historyRepo.GetLatest(ctx)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, err
+	}
+	if latest != nil {
+		switch trigger {
+		case SnapshotTriggerStartup:
+			y1, m1, d1 := latest.CreatedAt.Local().Date()
+			y2, m2, d2 := time.Now().Date()
+			if y1 == y2 && m1 == m2 && d1 == d2 {
+				return false, nil
+			}
+		case SnapshotTriggerChange:
+			same, err := s.sameAsSnapshot(ctx, latest, holdings, encryptKey.Value)
+			if err != nil {
+				return false, err
+			}
+			if same {
+				return false, nil
+			}
+		}
+	}
+
+	if err := s.createSnapshot(ctx, holdings, encryptKey.Value); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// sameAsSnapshot 判断当前持仓是否与快照相同（按保存精度比较）。
+// 快照有资产明细时逐个资产比较，旧快照只比较股债总额
+func (s *HistoryService) sameAsSnapshot(ctx context.Context, h *model.History, holdings []snapshotHolding, key string) (bool, error) {
+	items, err := s.historyRepo.GetItems(ctx, h.ID)
+	if err != nil {
+		return false, err
+	}
+
+	if len(items) == 0 {
+		stockTotal, bondTotal := snapshotTotals(holdings)
+		stock, err := decryptAmount(h.EncryptedStockTotal, key)
+		if err != nil {
+			return false, err
+		}
+		bond, err := decryptAmount(h.EncryptedBondTotal, key)
+		if err != nil {
+			return false, err
+		}
+		return fmt.Sprintf("%.2f|%.2f", stock, bond) == fmt.Sprintf("%.2f|%.2f", stockTotal, bondTotal), nil
+	}
+
+	// 同一代码和来源可能对应多个资产，两边都按代码和来源汇总后比较
+	previous := make(map[string]*historyPosition, len(items))
+	for _, item := range items {
+		amount, err := decryptAmount(item.EncryptedAmount, key)
+		if err != nil {
+			return false, err
+		}
+		k := item.Code + "|" + item.Source
+		if p, ok := previous[k]; ok {
+			p.amount += amount
+			continue
+		}
+		previous[k] = &historyPosition{assetType: item.Type, amount: amount}
+	}
+	current := make(map[string]*historyPosition, len(holdings))
+	for _, h := range holdings {
+		k := h.asset.Code + "|" + h.asset.Source
+		if p, ok := current[k]; ok {
+			p.amount += h.amount
+			continue
+		}
+		current[k] = &historyPosition{assetType: h.asset.Type, amount: h.amount}
+	}
+
+	if len(previous) != len(current) {
+		return false, nil
+	}
+	for k, p := range current {
+		q, ok := previous[k]
+		if !ok || q.assetType != p.assetType || fmt.Sprintf("%.2f", q.amount) != fmt.Sprintf("%.2f", p.amount) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,49 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"margin/internal/model"
+)
+
+func TestSameAsSnapshotSumsDuplicateCodes(t *testing.T) {
+	ctx := context.Background()
+	s := NewHistoryService(newTestDB(t))
+	holding := func(id uint, amount float64) snapshotHolding {
+		asset := model.Asset{Code: "000001", Name: "华夏成长混合", Type: model.AssetTypeStock, Source: "天天基金"}
+		asset.ID = id
+		return snapshotHolding{asset: asset, amount: amount}
+	}
+
+	// 同一代码和来源有两个资产
+	if err := s.createSnapshot(ctx, []snapshotHolding{holding(1, 100), holding(2, 200)}, testEncryptKey); err != nil {
+		t.Fatal(err)
+	}
+	latest, err := s.historyRepo.GetLatest(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		holdings []snapshotHolding
+		want     bool
+	}{
+		{name: "unchanged", holdings: []snapshotHolding{holding(1, 100), holding(2, 200)}, want: true},
+		{name: "merged into one asset", holdings: []snapshotHolding{holding(1, 300)}, want: true},
+		{name: "one asset changed", holdings: []snapshotHolding{holding(1, 200), holding(2, 200)}, want: false},
+		{name: "one asset removed", holdings: []snapshotHolding{holding(2, 200)}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			same, err := s.sameAsSnapshot(ctx, latest, tt.holdings, testEncryptKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if same != tt.want {
+				t.Errorf("sameAsSnapshot = %v, want %v", same, tt.want)
+			}
+		})
+	}
+}
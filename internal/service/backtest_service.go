@@ -0,0 +1,92 @@
+package service
+
+import "context"
+
+// BacktestService 基于指数日线回测不同的再平衡策略
+type BacktestService struct {
+	indexHistory *IndexHistoryService
+}
+
+func NewBacktestService(indexHistory *IndexHistoryService) *BacktestService {
+	return &BacktestService{
+		indexHistory: indexHistory,
+	}
+}
+
+// BacktestIndexes 可用于回测的指数，包括用户添加的指数
+func (s *BacktestService) BacktestIndexes(ctx context.Context) ([]map[string]interface{}, error) {
+	defs, err := s.indexHistory.Indexes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]map[string]interface{}, 0, len(defs))
+	for _, d := range defs {
+		result = append(result, map[string]interface{}{
+			"code": d.Code,
+			"name": d.Name,
+		})
+	}
+	return result, nil
+}
+
+// RunBacktest 回测买入持有、定期再平衡和容忍带再平衡三种策略，返回可直接绘图的序列
+func (s *BacktestService) RunBacktest(ctx context.Context, p BacktestParams) (map[string]interface{}, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	stock, err := s.indexHistory.Prices(ctx, p.StockCode, p.From, p.To)
+	if err != nil {
+		return nil, err
+	}
+	bond, err := s.indexHistory.Prices(ctx, p.BondCode, p.From, p.To)
+	if err != nil {
+		return nil, err
+	}
+
+	stockDef, err := s.indexHistory.resolve(ctx, p.StockCode)
+	if err != nil {
+		return nil, err
+	}
+	bondDef, err := s.indexHistory.resolve(ctx, p.BondCode)
+	if err != nil {
+		return nil, err
+	}
+
+	days := alignSeries(stock, bond)
+	results, err := runBacktest(days, p)
+	if err != nil {
+		return nil, err
+	}
+
+	dates := make([]string, len(days))
+	stockCurve := make([]float64, len(days))
+	bondCurve := make([]float64, len(days))
+	for i, d := range days {
+		dates[i] = d.Date.Format("2006-01-02")
+		stockCurve[i] = d.Stock / days[0].Stock
+		bondCurve[i] = d.Bond / days[0].Bond
+	}
+
+	strategies := make([]map[string]interface{}, 0, len(results))
+	for _, r := range results {
+		item := r.ToMap()
+		item["values"] = r.Values
+		item["stock_weights"] = r.StockWeights
+		strategies = append(strategies, item)
+	}
+
+	return map[string]interface{}{
+		"start_date":  dates[0],
+		"end_date":    dates[len(dates)-1],
+		"stock_code":  p.StockCode,
+		"stock_name":  stockDef.Name,
+		"bond_code":   p.BondCode,
+		"bond_name":   bondDef.Name,
+		"stock_ratio": p.StockRatio,
+		"dates":       dates,
+		"stock_curve": stockCurve,
+		"bond_curve":  bondCurve,
+		"strategies":  strategies,
+	}, nil
+}
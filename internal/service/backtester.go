@@ -0,0 +1,249 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"margin/internal/model"
+	"math"
+	"time"
+)
+
+// 回测策略
+const (
+	BacktestBuyAndHold = "buy_and_hold" // 买入持有，从不再平衡
+	BacktestCalendar   = "calendar"     // 按日历定期再平衡
+	BacktestBands      = "bands"        // 偏离超过容忍带时再平衡
+)
+
+// BacktestAnnual 每年再平衡一次（日历策略的频率，其余频率沿用定期再平衡日历的取值）
+const BacktestAnnual = "annual"
+
+// tradingDaysPerYear 年化波动率使用的年交易日数
+const tradingDaysPerYear = 252
+
+// BacktestParams 回测参数
+type BacktestParams struct {
+	StockCode  string  // 股票指数代码
+	BondCode   string  // 债券指数代码
+	From       string  // 开始日期，格式 2006-01-02，空表示最早
+	To         string  // 结束日期，空表示最新
+	StockRatio float64 // 目标股票比例(%)
+	Frequency  string  // 日历策略频率：monthly/quarterly/semiannual/annual
+	Band       float64 // 容忍带策略的绝对偏离(%)
+}
+
+// Validate 校验回测参数
+func (p BacktestParams) Validate() error {
+	if p.StockCode == "" || p.BondCode == "" {
+		return errors.New("请选择股票指数和债券指数")
+	}
+	if p.StockRatio <= 0 || p.StockRatio >= 100 {
+		return errors.New("目标股票比例需在 0-100 之间")
+	}
+	switch p.Frequency {
+	case ScheduleMonthly, ScheduleQuarterly, ScheduleSemiannual, BacktestAnnual:
+	default:
+		return fmt.Errorf("不支持的再平衡频率: %s", p.Frequency)
+	}
+	if p.Band <= 0 || p.Band >= 50 {
+		return errors.New("容忍带需在 0-50 之间")
+	}
+	return nil
+}
+
+// BacktestDay 股债两个指数同一交易日的收盘价
+type BacktestDay struct {
+	Date  time.Time
+	Stock float64
+	Bond  float64
+}
+
+// BacktestResult 单个策略的回测结果
+type BacktestResult struct {
+	Strategy         string
+	Label            string
+	FinalValue       float64 // 期末净值（期初为 1）
+	CAGR             float64 // 年化收益率(%)
+	Volatility       float64 // 年化波动率(%)
+	MaxDrawdown      float64 // 最大回撤(%)，为正数
+	DrawdownStart    time.Time
+	DrawdownEnd      time.Time
+	Trades           int     // 再平衡次数
+	RebalancingBonus float64 // 相对买入持有的年化超额收益(%)
+	Values           []float64
+	StockWeights     []float64
+	RebalanceDates   []string
+}
+
+// alignSeries 按交易日对齐股债两个指数，只保留两者都有收盘价的日期
+func alignSeries(stock, bond []model.IndexPrice) []BacktestDay {
+	bondByDate := make(map[string]float64, len(bond))
+	for _, p := range bond {
+		bondByDate[p.Date] = p.Close
+	}
+
+	days := make([]BacktestDay, 0, len(stock))
+	for _, p := range stock {
+		b, ok := bondByDate[p.Date]
+		if !ok {
+			continue
+		}
+		date, err := time.ParseInLocation("2006-01-02", p.Date, time.Local)
+		if err != nil {
+			continue
+		}
+		days = append(days, BacktestDay{Date: date, Stock: p.Close, Bond: b})
+	}
+	return days
+}
+
+// runBacktest 在对齐后的日线上模拟所有策略，第一个结果为买入持有
+func runBacktest(days []BacktestDay, p BacktestParams) ([]*BacktestResult, error) {
+	if len(days) < 2 {
+		return nil, errors.New("回测区间内的数据不足")
+	}
+
+	target := p.StockRatio / 100
+	strategies := []struct {
+		name, label string
+		rebalance   func(prev, cur time.Time, weight float64) bool
+	}{
+		{
+			name:      BacktestBuyAndHold,
+			label:     "买入持有",
+			rebalance: func(time.Time, time.Time, float64) bool { return false },
+		},
+		{
+			name:  BacktestCalendar,
+			label: fmt.Sprintf("定期再平衡（%s）", frequencyLabel(p.Frequency)),
+			rebalance: func(prev, cur time.Time, _ float64) bool {
+				return periodOf(prev, p.Frequency) != periodOf(cur, p.Frequency)
+			},
+		},
+		{
+			name:  BacktestBands,
+			label: fmt.Sprintf("容忍带再平衡（±%g%%）", p.Band),
+			rebalance: func(_, _ time.Time, weight float64) bool {
+				return math.Abs(weight-target)*100 > p.Band
+			},
+		},
+	}
+
+	results := make([]*BacktestResult, 0, len(strategies))
+	for _, st := range strategies {
+		r := &BacktestResult{
+			Strategy:     st.name,
+			Label:        st.label,
+			Values:       make([]float64, len(days)),
+			StockWeights: make([]float64, len(days)),
+		}
+
+		// 期初净值为 1，按目标比例买入
+		stockUnits := target / days[0].Stock
+		bondUnits := (1 - target) / days[0].Bond
+		for i, d := range days {
+			value := stockUnits*d.Stock + bondUnits*d.Bond
+			weight := stockUnits * d.Stock / value
+			// 按当日收盘价检查并再平衡
+			if i > 0 && st.rebalance(days[i-1].Date, d.Date, weight) {
+				stockUnits = value * target / d.Stock
+				bondUnits = value * (1 - target) / d.Bond
+				weight = target
+				r.Trades++
+				r.RebalanceDates = append(r.RebalanceDates, d.Date.Format("2006-01-02"))
+			}
+			r.Values[i] = value
+			r.StockWeights[i] = weight * 100
+		}
+		r.measure(days)
+		results = append(results, r)
+	}
+
+	for _, r := range results[1:] {
+		r.RebalancingBonus = r.CAGR - results[0].CAGR
+	}
+	return results, nil
+}
+
+// measure 计算年化收益、波动率和最大回撤
+func (r *BacktestResult) measure(days []BacktestDay) {
+	n := len(r.Values)
+	r.FinalValue = r.Values[n-1]
+
+	years := days[n-1].Date.Sub(days[0].Date).Hours() / 24 / 365.25
+	if years > 0 {
+		r.CAGR = (math.Pow(r.FinalValue/r.Values[0], 1/years) - 1) * 100
+	}
+
+	var sum, sumSq float64
+	for i := 1; i < n; i++ {
+		ret := math.Log(r.Values[i] / r.Values[i-1])
+		sum += ret
+		sumSq += ret * ret
+	}
+	if n > 2 {
+		mean := sum / float64(n-1)
+		variance := (sumSq - float64(n-1)*mean*mean) / float64(n-2)
+		r.Volatility = math.Sqrt(math.Max(variance, 0)*tradingDaysPerYear) * 100
+	}
+
+	peak, peakAt := r.Values[0], 0
+	for i, v := range r.Values {
+		if v > peak {
+			peak, peakAt = v, i
+		}
+		if dd := (1 - v/peak) * 100; dd > r.MaxDrawdown {
+			r.MaxDrawdown = dd
+			r.DrawdownStart = days[peakAt].Date
+			r.DrawdownEnd = days[i].Date
+		}
+	}
+}
+
+// periodOf 返回日期所在的再平衡周期编号
+func periodOf(t time.Time, frequency string) int {
+	month := int(t.Month()) - 1
+	switch frequency {
+	case ScheduleMonthly:
+		return t.Year()*12 + month
+	case ScheduleQuarterly:
+		return t.Year()*4 + month/3
+	case ScheduleSemiannual:
+		return t.Year()*2 + month/6
+	default:
+		return t.Year()
+	}
+}
+
+func frequencyLabel(frequency string) string {
+	switch frequency {
+	case ScheduleMonthly:
+		return "每月"
+	case ScheduleQuarterly:
+		return "每季度"
+	case ScheduleSemiannual:
+		return "每半年"
+	default:
+		return "每年"
+	}
+}
+
+// ToMap 转换为前端展示的结构（不含逐日序列）
+func (r *BacktestResult) ToMap() map[string]interface{} {
+	result := map[string]interface{}{
+		"strategy":          r.Strategy,
+		"label":             r.Label,
+		"final_value":       r.FinalValue,
+		"cagr":              r.CAGR,
+		"volatility":        r.Volatility,
+		"max_drawdown":      r.MaxDrawdown,
+		"trades":            r.Trades,
+		"rebalancing_bonus": r.RebalancingBonus,
+		"rebalance_dates":   r.RebalanceDates,
+	}
+	if r.MaxDrawdown > 0 {
+		result["drawdown_start"] = r.DrawdownStart.Format("2006-01-02")
+		result["drawdown_end"] = r.DrawdownEnd.Format("2006-01-02")
+	}
+	return result
+}
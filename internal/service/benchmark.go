@@ -0,0 +1,185 @@
+package service
+
+import (
+	"errors"
+	"margin/internal/model"
+	"math"
+	"time"
+)
+
+// 静态股债组合：股票指数和债券指数各占一半，每个快照日再平衡回初始比例
+const (
+	blendStockCode = "000300"
+	blendBondCode  = "000012"
+	blendWeight    = 0.5
+	blendCode      = "blend"
+)
+
+// benchmarkSeries 与快照日期对齐的一条基准序列，没有数据的日期为 0
+type benchmarkSeries struct {
+	Code   string
+	Name   string
+	Values []float64
+}
+
+// BenchmarkLine 一条基准的归一化净值及与组合的比较
+type BenchmarkLine struct {
+	Code                 string
+	Name                 string
+	Growth               []float64 // 期初为 1
+	Difference           []float64 // 组合累计收益减基准累计收益（百分点）
+	TotalReturn          float64
+	Annualized           float64
+	TrackingDifference   float64 // 期末累计收益之差（百分点）
+	AnnualizedDifference float64 // 年化收益之差（百分点）
+	TrackingError        float64 // 年化跟踪误差
+}
+
+// BenchmarkComparison 组合与各基准在快照日对齐后的净值曲线
+type BenchmarkComparison struct {
+	Dates      []time.Time
+	Skipped    int       // 早于基准数据、未参与比较的快照数
+	Portfolio  []float64 // 组合时间加权净值，期初为 1
+	Total      float64
+	Annualized float64
+	Lines      []BenchmarkLine
+}
+
+// closesAt 取每个日期当天或之前最近一个交易日的收盘价，之前没有数据时为 0
+func closesAt(prices []model.IndexPrice, dates []time.Time) []float64 {
+	closes := make([]float64, len(dates))
+	next := 0
+	var last float64
+	for i, d := range dates {
+		day := d.Local().Format("2006-01-02")
+		for next < len(prices) && prices[next].Date <= day {
+			last = prices[next].Close
+			next++
+		}
+		closes[i] = last
+	}
+	return closes
+}
+
+// blendValues 按固定比例混合两条收盘价序列，在每个日期再平衡，从两者都有数据起以 1 开始
+func blendValues(stock, bond []float64, weight float64) []float64 {
+	values := make([]float64, len(stock))
+	for i := range stock {
+		if stock[i] <= 0 || bond[i] <= 0 {
+			continue
+		}
+		if i == 0 || values[i-1] == 0 {
+			values[i] = 1
+			continue
+		}
+		values[i] = values[i-1] * (weight*stock[i]/stock[i-1] + (1-weight)*bond[i]/bond[i-1])
+	}
+	return values
+}
+
+// compareBenchmarks 从所有基准都有数据的第一个快照开始，将组合净值和基准归一化后逐期比较
+func compareBenchmarks(points []valuePoint, flows []datedFlow, series []benchmarkSeries) (*BenchmarkComparison, error) {
+	start := 0
+	for _, s := range series {
+		for start < len(points) && s.Values[start] <= 0 {
+			start++
+		}
+	}
+	if len(points)-start < 2 {
+		return nil, errors.New("与基准数据重叠的快照不足两个")
+	}
+
+	dates := make([]time.Time, 0, len(points)-start)
+	for _, p := range points[start:] {
+		dates = append(dates, p.Date)
+	}
+	perf, err := measurePerformance(points[start:], flows, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &BenchmarkComparison{
+		Dates:      dates,
+		Skipped:    start,
+		Portfolio:  perf.Growth,
+		Total:      perf.TWR,
+		Annualized: perf.AnnualizedTWR,
+	}
+	years := dates[len(dates)-1].Sub(dates[0]).Hours() / 24 / daysPerYear
+	for _, s := range series {
+		values := s.Values[start:]
+		line := BenchmarkLine{
+			Code:       s.Code,
+			Name:       s.Name,
+			Growth:     make([]float64, len(values)),
+			Difference: make([]float64, len(values)),
+		}
+		for i, v := range values {
+			line.Growth[i] = v / values[0]
+			line.Difference[i] = (c.Portfolio[i] - line.Growth[i]) * 100
+		}
+		end := line.Growth[len(line.Growth)-1]
+		line.TotalReturn = (end - 1) * 100
+		if years > 0 {
+			line.Annualized = (math.Pow(end, 1/years) - 1) * 100
+		}
+		line.TrackingDifference = c.Total - line.TotalReturn
+		line.AnnualizedDifference = c.Annualized - line.Annualized
+		line.TrackingError = trackingError(c.Portfolio, line.Growth, years)
+		c.Lines = append(c.Lines, line)
+	}
+	return c, nil
+}
+
+// trackingError 逐期超额收益的年化标准差，子区间长度不固定时按平均长度年化
+func trackingError(portfolio, benchmark []float64, years float64) float64 {
+	n := len(portfolio) - 1
+	if n < 2 || years <= 0 {
+		return 0
+	}
+	excess := make([]float64, n)
+	var sum float64
+	for i := 1; i <= n; i++ {
+		excess[i-1] = portfolio[i]/portfolio[i-1] - benchmark[i]/benchmark[i-1]
+		sum += excess[i-1]
+	}
+	mean := sum / float64(n)
+	var sq float64
+	for _, e := range excess {
+		sq += (e - mean) * (e - mean)
+	}
+	return math.Sqrt(sq/float64(n-1)/(years/float64(n))) * 100
+}
+
+func (c *BenchmarkComparison) ToMap() map[string]interface{} {
+	dates := make([]string, len(c.Dates))
+	for i, d := range c.Dates {
+		dates[i] = d.Local().Format("2006-01-02")
+	}
+
+	lines := make([]map[string]interface{}, 0, len(c.Lines))
+	for _, l := range c.Lines {
+		lines = append(lines, map[string]interface{}{
+			"code":                  l.Code,
+			"name":                  l.Name,
+			"growth":                l.Growth,
+			"difference":            l.Difference,
+			"total_return":          l.TotalReturn,
+			"annualized":            l.Annualized,
+			"tracking_difference":   l.TrackingDifference,
+			"annualized_difference": l.AnnualizedDifference,
+			"tracking_error":        l.TrackingError,
+		})
+	}
+
+	return map[string]interface{}{
+		"dates":   dates,
+		"skipped": c.Skipped,
+		"portfolio": map[string]interface{}{
+			"growth":       c.Portfolio,
+			"total_return": c.Total,
+			"annualized":   c.Annualized,
+		},
+		"benchmarks": lines,
+	}
+}
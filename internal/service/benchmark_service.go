@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"margin/internal/model"
+	"margin/internal/repo"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// BenchmarkService 将组合的历史快照与指数日线对比
+type BenchmarkService struct {
+	indexHistory *IndexHistoryService
+	historyRepo  *repo.HistoryRepository
+	cashFlowRepo *repo.CashFlowRepository
+	configRepo   *repo.ConfigRepository
+}
+
+func NewBenchmarkService(db *gorm.DB, indexHistory *IndexHistoryService) *BenchmarkService {
+	return &BenchmarkService{
+		indexHistory: indexHistory,
+		historyRepo:  repo.NewHistoryRepository(db),
+		cashFlowRepo: repo.NewCashFlowRepository(db),
+		configRepo:   repo.NewConfigRepository(db),
+	}
+}
+
+// BenchmarkIndexes 可用于对比的指数，包括用户添加的指数
+func (s *BenchmarkService) BenchmarkIndexes(ctx context.Context) ([]map[string]interface{}, error) {
+	defs, err := s.indexHistory.Indexes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]map[string]interface{}, 0, len(defs))
+	for _, d := range defs {
+		result = append(result, map[string]interface{}{
+			"code": d.Code,
+			"name": d.Name,
+		})
+	}
+	return result, nil
+}
+
+// CompareBenchmarks 在 [from, to] 的快照日期上对比组合与所选指数及 50/50 股债组合（日期格式 2006-01-02，空表示不限）。
+// 组合使用时间加权净值，剔除存入和取出的影响；海外指数按其本币计算
+func (s *BenchmarkService) CompareBenchmarks(ctx context.Context, codes []string, from, to string) (map[string]interface{}, error) {
+	selected := make([]string, 0, len(codes))
+	names := map[string]string{}
+	resolve := func(code string) error {
+		if _, ok := names[code]; ok {
+			return nil
+		}
+		def, err := s.indexHistory.resolve(ctx, code)
+		if err != nil {
+			return fmt.Errorf("不支持的基准指数: %s", code)
+		}
+		names[code] = def.Name
+		return nil
+	}
+	for _, code := range codes {
+		if _, ok := names[code]; ok {
+			continue
+		}
+		if err := resolve(code); err != nil {
+			return nil, err
+		}
+		selected = append(selected, code)
+	}
+	if err := resolve(blendStockCode); err != nil {
+		return nil, err
+	}
+	if err := resolve(blendBondCode); err != nil {
+		return nil, err
+	}
+
+	r, err := TimeQuery{From: from, To: to}.timeRange()
+	if err != nil {
+		return nil, err
+	}
+	encryptKey, err := s.configRepo.Get(ctx, model.ConfigKeyEncryptKey)
+	if err != nil {
+		return nil, err
+	}
+	key := encryptKey.Value
+
+	histories, err := s.historyRepo.GetInRange(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	if len(histories) < 2 {
+		return nil, errors.New("所选区间内至少需要两个快照")
+	}
+	points := make([]valuePoint, 0, len(histories))
+	dates := make([]time.Time, 0, len(histories))
+	for _, h := range histories {
+		stock, err := decryptAmount(h.EncryptedStockTotal, key)
+		if err != nil {
+			return nil, err
+		}
+		bond, err := decryptAmount(h.EncryptedBondTotal, key)
+		if err != nil {
+			return nil, err
+		}
+		points = append(points, valuePoint{h.CreatedAt, stock + bond})
+		dates = append(dates, h.CreatedAt)
+	}
+
+	// 只有存入和取出影响组合整体
+	first, last := dates[0], dates[len(dates)-1]
+	cashFlows, err := s.cashFlowRepo.GetRange(ctx, localDay(first), localDay(last))
+	if err != nil {
+		return nil, err
+	}
+	var flows []datedFlow
+	for _, f := range cashFlows {
+		if f.Kind != model.CashFlowDeposit && f.Kind != model.CashFlowWithdrawal {
+			continue
+		}
+		flow, err := signedFlow(f, key)
+		if err != nil {
+			return nil, err
+		}
+		flows = append(flows, flow)
+	}
+
+	// 多取一段，保证第一个快照之前有最近的收盘价
+	priceFrom := first.Local().AddDate(0, 0, -30).Format("2006-01-02")
+	priceTo := last.Local().Format("2006-01-02")
+	closes := map[string][]float64{}
+	for _, code := range append(selected, blendStockCode, blendBondCode) {
+		if _, ok := closes[code]; ok {
+			continue
+		}
+		prices, err := s.indexHistory.Prices(ctx, code, priceFrom, priceTo)
+		if err != nil {
+			return nil, err
+		}
+		closes[code] = closesAt(prices, dates)
+	}
+
+	series := make([]benchmarkSeries, 0, len(selected)+1)
+	for _, code := range selected {
+		series = append(series, benchmarkSeries{Code: code, Name: names[code], Values: closes[code]})
+	}
+	series = append(series, benchmarkSeries{
+		Code:   blendCode,
+		Name:   fmt.Sprintf("50/50 股债（%s + %s）", names[blendStockCode], names[blendBondCode]),
+		Values: blendValues(closes[blendStockCode], closes[blendBondCode], blendWeight),
+	})
+
+	comparison, err := compareBenchmarks(points, flows, series)
+	if err != nil {
+		return nil, err
+	}
+	return comparison.ToMap(), nil
+}
@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"margin/internal/model"
+	"margin/internal/repo"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 休市日历来源
+const (
+	HolidaySourceBuiltin  = "builtin"  // 随应用发布
+	HolidaySourceImported = "imported" // 用户导入
+)
+
+// marketOrder 市场状态的展示顺序
+var marketOrder = []string{model.MarketCN, model.MarketHK, model.MarketUS}
+
+// CalendarService 交易日历：判断各市场是否开市，休市日历可导入更新
+type CalendarService struct {
+	configRepo *repo.ConfigRepository
+
+	mu       sync.Mutex
+	calendar *TradingCalendar
+	source   string
+}
+
+func NewCalendarService(db *gorm.DB) *CalendarService {
+	return &CalendarService{
+		configRepo: repo.NewConfigRepository(db),
+	}
+}
+
+// Calendar 当前使用的交易日历。导入的日历版本不低于内置日历时优先使用，
+// 应用升级带来更新的内置日历后自动改用内置日历
+func (s *CalendarService) Calendar(ctx context.Context) *TradingCalendar {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.calendar != nil {
+		return s.calendar
+	}
+
+	builtin, err := parseHolidayTable(builtinHolidays)
+	if err != nil {
+		panic(fmt.Sprintf("内置休市日历无效: %v", err))
+	}
+	s.calendar, s.source = builtin, HolidaySourceBuiltin
+
+	imported, err := s.imported(ctx)
+	if err != nil {
+		println("Failed to load holiday table:", err.Error())
+		return s.calendar
+	}
+	if imported != nil && compareVersions(imported.Version, builtin.Version) >= 0 {
+		s.calendar, s.source = imported, HolidaySourceImported
+	}
+	return s.calendar
+}
+
+// imported 读取用户导入的休市日历，没有导入时返回 nil
+func (s *CalendarService) imported(ctx context.Context) (*TradingCalendar, error) {
+	config, err := s.configRepo.Get(ctx, model.ConfigKeyHolidayTable)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if config.Value == "" {
+		return nil, nil
+	}
+	return parseHolidayTable([]byte(config.Value))
+}
+
+// MarketStatus 市场当前的交易状态
+func (s *CalendarService) MarketStatus(ctx context.Context, market string, now time.Time) (MarketStatus, error) {
+	return s.Calendar(ctx).Status(market, now)
+}
+
+// MarketStatuses 所有市场当前的交易状态
+func (s *CalendarService) MarketStatuses(ctx context.Context) ([]map[string]interface{}, error) {
+	calendar := s.Calendar(ctx)
+	now := time.Now()
+
+	result := make([]map[string]interface{}, 0, len(marketOrder))
+	for _, market := range marketOrder {
+		status, err := calendar.Status(market, now)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, status.ToMap())
+	}
+	return result, nil
+}
+
+// HolidayTableInfo 当前休市日历的版本、来源和覆盖范围
+func (s *CalendarService) HolidayTableInfo(ctx context.Context) map[string]interface{} {
+	calendar := s.Calendar(ctx)
+	s.mu.Lock()
+	source := s.source
+	s.mu.Unlock()
+
+	return map[string]interface{}{
+		"version":       calendar.Version,
+		"source":        source,
+		"covered_until": calendar.CoveredUntil(),
+	}
+}
+
+// ImportHolidayTable 导入休市日历文件，版本低于内置日历时拒绝导入
+func (s *CalendarService) ImportHolidayTable(ctx context.Context, path string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取文件失败: %w", err)
+	}
+	calendar, err := parseHolidayTable(data)
+	if err != nil {
+		return nil, err
+	}
+
+	builtin, err := parseHolidayTable(builtinHolidays)
+	if err != nil {
+		return nil, err
+	}
+	if compareVersions(calendar.Version, builtin.Version) < 0 {
+		return nil, fmt.Errorf("休市日历版本 %s 低于内置版本 %s", calendar.Version, builtin.Version)
+	}
+
+	if err := s.configRepo.Set(ctx, model.ConfigKeyHolidayTable, string(data)); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.calendar, s.source = calendar, HolidaySourceImported
+	s.mu.Unlock()
+	return s.HolidayTableInfo(ctx), nil
+}
+
+// ResetHolidayTable 删除导入的休市日历，恢复使用内置日历
+func (s *CalendarService) ResetHolidayTable(ctx context.Context) error {
+	if err := s.configRepo.Set(ctx, model.ConfigKeyHolidayTable, ""); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.calendar = nil
+	s.mu.Unlock()
+	return nil
+}
+
+// compareVersions 按点分隔的数字比较版本号，如 2026.1 < 2026.10
+func compareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
@@ -2,7 +2,9 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"margin/internal/crypto"
 	"margin/internal/model"
 	"margin/internal/repo"
@@ -55,3 +57,44 @@ func (s *ConfigService) GetEncryptKey(ctx context.Context) (string, error) {
 	}
 	return config.Value, nil
 }
+
+// GetRebalanceBands 获取再平衡容忍带规则
+func (s *ConfigService) GetRebalanceBands(ctx context.Context) (RebalanceBands, error) {
+	return loadRebalanceBands(ctx, s.repo)
+}
+
+// SaveRebalanceBands 保存再平衡容忍带规则
+func (s *ConfigService) SaveRebalanceBands(ctx context.Context, bands RebalanceBands) error {
+	if err := bands.Stock.Validate(); err != nil {
+		return fmt.Errorf("股票规则: %w", err)
+	}
+	if err := bands.Bond.Validate(); err != nil {
+		return fmt.Errorf("债券规则: %w", err)
+	}
+
+	data, err := json.Marshal(bands)
+	if err != nil {
+		return err
+	}
+	return s.repo.Set(ctx, model.ConfigKeyRebalanceBands, string(data))
+}
+
+// GetGlidePath 获取目标股票比例的下滑路径
+func (s *ConfigService) GetGlidePath(ctx context.Context) (GlidePath, error) {
+	return loadGlidePath(ctx, s.repo)
+}
+
+// SaveGlidePath 保存目标股票比例的下滑路径
+func (s *ConfigService) SaveGlidePath(ctx context.Context, g GlidePath) error {
+	if g.Enabled {
+		if err := g.Validate(); err != nil {
+			return err
+		}
+	}
+
+	data, err := json.Marshal(g)
+	if err != nil {
+		return err
+	}
+	return s.repo.Set(ctx, model.ConfigKeyGlidePath, string(data))
+}
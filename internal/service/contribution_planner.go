@@ -0,0 +1,160 @@
+package service
+
+import (
+	"margin/internal/model"
+	"math"
+)
+
+// ContributionPlan 现金流再平衡计划：新增资金只买入、取出资金只卖出
+type ContributionPlan struct {
+	Amount           float64     // 存入为正，取出为负
+	TargetStockRatio float64     // 目标股票比例
+	StockAmount      float64     // 分配到股票的金额（取出时为负）
+	BondAmount       float64     // 分配到债券的金额（取出时为负）
+	Orders           []PlanOrder // 基金级交易
+	Unallocated      float64     // 没有可买入基金而未分配的金额
+	BeforeStockRatio float64     // 执行前股票比例
+	AfterStockRatio  float64     // 执行后股票比例
+	AfterStockTotal  float64
+	AfterBondTotal   float64
+	EstimatedFee     float64 // 预估交易费用合计
+	FeeKnown         bool
+}
+
+// planContribution 将存入或取出的资金在股债之间分配，使组合尽量接近目标比例。
+// 存入时只买入低配的类别，取出时只卖出超配的类别；类别内按目标权重
+// 优先补足（或削减）偏离最多的基金，未设置权重时集中到持仓最大的基金
+func planContribution(holdings []Holding, amount, targetStockRatio float64, fees *feeModel) *ContributionPlan {
+	stockTotal, bondTotal := portfolioTotals(holdings)
+	total := stockTotal + bondTotal
+
+	plan := &ContributionPlan{
+		Amount:           amount,
+		TargetStockRatio: targetStockRatio,
+		Orders:           []PlanOrder{},
+	}
+	if total > 0 {
+		plan.BeforeStockRatio = stockTotal / total * 100
+	}
+
+	newTotal := total + amount
+	targetStock := newTotal * targetStockRatio / 100
+
+	if amount >= 0 {
+		plan.StockAmount = math.Min(math.Max(targetStock-stockTotal, 0), amount)
+		plan.BondAmount = amount - plan.StockAmount
+	} else {
+		withdraw := -amount
+		sellStock := math.Min(math.Max(stockTotal-targetStock, 0), withdraw)
+		sellBond := math.Min(withdraw-sellStock, bondTotal)
+		// 债券不足时继续从股票中取出
+		sellStock = math.Min(withdraw-sellBond, stockTotal)
+		plan.StockAmount = -sellStock
+		plan.BondAmount = -sellBond
+	}
+
+	for _, class := range []string{model.AssetTypeStock, model.AssetTypeBond} {
+		classAmount := plan.StockAmount
+		if class == model.AssetTypeBond {
+			classAmount = plan.BondAmount
+		}
+		orders, unallocated := allocateCashFlow(classHoldings(holdings, class), classAmount, fees)
+		plan.Orders = append(plan.Orders, orders...)
+		plan.Unallocated += unallocated
+	}
+
+	plan.AfterStockTotal = stockTotal
+	plan.AfterBondTotal = bondTotal
+	for _, o := range plan.Orders {
+		signed := o.Amount
+		if o.Action == OrderActionSell {
+			signed = -signed
+		}
+		if o.Type == model.AssetTypeStock {
+			plan.AfterStockTotal += signed
+		} else {
+			plan.AfterBondTotal += signed
+		}
+	}
+	if after := plan.AfterStockTotal + plan.AfterBondTotal; after > 0 {
+		plan.AfterStockRatio = plan.AfterStockTotal / after * 100
+	}
+	plan.EstimatedFee, plan.FeeKnown = totalFees(plan.Orders)
+
+	return plan
+}
+
+// allocateCashFlow 将类别内的现金流分配到具体基金，返回交易和无法分配的金额
+func allocateCashFlow(members []*Holding, amount float64, fees *feeModel) ([]PlanOrder, float64) {
+	if math.Abs(amount) < 0.01 {
+		return nil, 0
+	}
+	if len(members) == 0 {
+		return nil, amount
+	}
+
+	var current, weightSum float64
+	for _, h := range members {
+		current += h.Amount
+		weightSum += h.Asset.TargetWeight
+	}
+
+	deltas := make([]float64, len(members))
+	if weightSum > 0 {
+		// 按调整后的目标金额计算每只基金的缺口（存入）或超出（取出），按比例分配
+		newTotal := current + amount
+		gaps := make([]float64, len(members))
+		var gapSum float64
+		for i, h := range members {
+			gap := newTotal*h.Asset.TargetWeight/weightSum - h.Amount
+			if amount < 0 {
+				gap = -gap
+			}
+			gaps[i] = math.Max(gap, 0)
+			gapSum += gaps[i]
+		}
+		if gapSum > 0 {
+			for i := range members {
+				deltas[i] = amount * gaps[i] / gapSum
+			}
+		} else {
+			concentrate(members, amount, deltas, fees)
+		}
+	} else {
+		concentrate(members, amount, deltas, fees)
+	}
+
+	var orders []PlanOrder
+	var allocated float64
+	for i, d := range deltas {
+		if d < 0 && -d > members[i].Amount {
+			d = -members[i].Amount
+		}
+		if math.Abs(d) < 0.01 {
+			continue
+		}
+
+		orders = append(orders, newPlanOrder(members[i], d, fees))
+		allocated += d
+	}
+
+	return orders, amount - allocated
+}
+
+func (p *ContributionPlan) toMap() map[string]interface{} {
+	return map[string]interface{}{
+		"amount":             p.Amount,
+		"target_stock_ratio": p.TargetStockRatio,
+		"stock_amount":       p.StockAmount,
+		"bond_amount":        p.BondAmount,
+		"orders":             ordersToMaps(p.Orders),
+		"unallocated":        p.Unallocated,
+		"before_stock_ratio": p.BeforeStockRatio,
+		"after_stock_ratio":  p.AfterStockRatio,
+		"after_stock_total":  p.AfterStockTotal,
+		"after_bond_total":   p.AfterBondTotal,
+		"remaining_drift":    p.AfterStockRatio - p.TargetStockRatio,
+		"estimated_fee":      p.EstimatedFee,
+		"fee_known":          p.FeeKnown,
+	}
+}
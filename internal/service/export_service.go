@@ -0,0 +1,866 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"margin/internal/crypto"
+	"margin/internal/model"
+	"margin/internal/repo"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm"
+)
+
+// 导出格式
+const (
+	ExportFormatCSV  = "csv"
+	ExportFormatJSON = "json"
+	ExportFormatXLSX = "xlsx"
+)
+
+// ExportSchemaVersion 导出 JSON 文档的结构版本
+// v2: 金额改为字符串，支持口令加密
+// v3: 新增持仓批次和费率规则
+// v4: 新增再平衡持仓快照
+// v5: 新增再平衡交易及执行情况
+// v6: 新增定期检查记录
+// v7: 新增历史快照的资产明细
+// v8: 新增资金流水
+// v9: 新增已导入的账单交易记录
+// v10: 新增行情栏指数（日线和行情缓存可重新下载，不导出）
+const ExportSchemaVersion = 10
+
+// ExportDocument 导出文档，金额为明文或口令加密后的密文（见 Encryption）
+type ExportDocument struct {
+	SchemaVersion int               `json:"schema_version"`
+	ExportedAt    time.Time         `json:"exported_at"`
+	Encryption    *ExportEncryption `json:"encryption,omitempty"`
+	Assets        []ExportAsset     `json:"assets"`
+	Sources       []ExportSource    `json:"sources"`
+	History       []ExportHistory   `json:"history"`
+	Rebalances    []ExportRebalance `json:"rebalances"`
+	Lots          []ExportLot       `json:"lots"`
+	FeeRules      []ExportFeeRule   `json:"fee_rules"`
+
+	HistoryItems      []ExportHistoryItem      `json:"history_items"`
+	RebalanceHoldings []ExportRebalanceHolding `json:"rebalance_holdings"`
+	RebalanceOrders   []ExportRebalanceOrder   `json:"rebalance_orders"`
+	Reviews           []ExportReview           `json:"reviews"`
+	CashFlows         []ExportCashFlow         `json:"cash_flows"`
+
+	ImportedTransactions []ExportImportedTransaction `json:"imported_transactions"`
+	IndexDefs            []ExportIndexDef            `json:"index_defs"`
+}
+
+type ExportAsset struct {
+	ID           uint         `json:"id"`
+	Code         string       `json:"code"`
+	Name         string       `json:"name"`
+	URL          string       `json:"url"`
+	Type         string       `json:"type"`
+	Source       string       `json:"source"`
+	Amount       ExportAmount `json:"amount"`
+	TargetWeight float64      `json:"target_weight"`
+	CreatedAt    time.Time    `json:"created_at"`
+	UpdatedAt    time.Time    `json:"updated_at"`
+}
+
+type ExportSource struct {
+	ID        uint      `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type ExportHistory struct {
+	ID         uint         `json:"id"`
+	StockTotal ExportAmount `json:"stock_total"`
+	BondTotal  ExportAmount `json:"bond_total"`
+	StockRatio float64      `json:"stock_ratio"`
+	BondRatio  float64      `json:"bond_ratio"`
+	CreatedAt  time.Time    `json:"created_at"`
+}
+
+type ExportHistoryItem struct {
+	ID        uint         `json:"id"`
+	HistoryID uint         `json:"history_id"`
+	AssetID   uint         `json:"asset_id"`
+	Code      string       `json:"code"`
+	Name      string       `json:"name"`
+	Type      string       `json:"type"`
+	Source    string       `json:"source"`
+	Amount    ExportAmount `json:"amount"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+type ExportRebalance struct {
+	ID               uint         `json:"id"`
+	StockRatio       float64      `json:"stock_ratio"`
+	BondRatio        float64      `json:"bond_ratio"`
+	TotalAmount      ExportAmount `json:"total_amount"`
+	StockAmount      ExportAmount `json:"stock_amount"`
+	BondAmount       ExportAmount `json:"bond_amount"`
+	TargetStockRatio float64      `json:"target_stock_ratio"`
+	TargetBondRatio  float64      `json:"target_bond_ratio"`
+	Note             string       `json:"note"`
+	CreatedAt        time.Time    `json:"created_at"`
+}
+
+type ExportRebalanceHolding struct {
+	ID          uint         `json:"id"`
+	RebalanceID uint         `json:"rebalance_id"`
+	AssetID     uint         `json:"asset_id"`
+	Code        string       `json:"code"`
+	Name        string       `json:"name"`
+	Type        string       `json:"type"`
+	Source      string       `json:"source"`
+	Amount      ExportAmount `json:"amount"`
+	Ratio       float64      `json:"ratio"`
+	CreatedAt   time.Time    `json:"created_at"`
+}
+
+type ExportRebalanceOrder struct {
+	ID           uint         `json:"id"`
+	RebalanceID  uint         `json:"rebalance_id"`
+	AssetID      uint         `json:"asset_id"`
+	Code         string       `json:"code"`
+	Name         string       `json:"name"`
+	Type         string       `json:"type"`
+	Source       string       `json:"source"`
+	Action       string       `json:"action"`
+	Amount       ExportAmount `json:"amount"`
+	FilledAmount ExportAmount `json:"filled_amount"` // 未成交时为空
+	Status       string       `json:"status"`
+	SubmittedAt  *time.Time   `json:"submitted_at"`
+	FilledAt     *time.Time   `json:"filled_at"`
+	CreatedAt    time.Time    `json:"created_at"`
+	UpdatedAt    time.Time    `json:"updated_at"`
+}
+
+type ExportReview struct {
+	ID               uint      `json:"id"`
+	DueDate          time.Time `json:"due_date"`
+	TargetStockRatio float64   `json:"target_stock_ratio"`
+	StockRatio       float64   `json:"stock_ratio"`
+	Breached         bool      `json:"breached"`
+	Reasons          string    `json:"reasons"`
+	Outcome          string    `json:"outcome"`
+	Note             string    `json:"note"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+type ExportCashFlow struct {
+	ID        uint         `json:"id"`
+	Date      time.Time    `json:"date"`
+	Kind      string       `json:"kind"`
+	AssetID   uint         `json:"asset_id"`
+	Code      string       `json:"code"`
+	Name      string       `json:"name"`
+	Type      string       `json:"type"`
+	Source    string       `json:"source"`
+	Amount    ExportAmount `json:"amount"`
+	OrderID   uint         `json:"order_id"`
+	Note      string       `json:"note"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+type ExportImportedTransaction struct {
+	ID        uint      `json:"id"`
+	Hash      string    `json:"hash"`
+	Source    string    `json:"source"`
+	Code      string    `json:"code"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ExportIndexDef struct {
+	Code      string    `json:"code"`
+	Name      string    `json:"name"`
+	SecID     string    `json:"secid"`
+	Market    string    `json:"market"`
+	Currency  string    `json:"currency"`
+	Sort      int       `json:"sort"`
+	Watch     bool      `json:"watch"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type ExportLot struct {
+	ID        uint         `json:"id"`
+	AssetID   uint         `json:"asset_id"`
+	BuyDate   time.Time    `json:"buy_date"`
+	Amount    ExportAmount `json:"amount"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+type ExportFeeRule struct {
+	ID        uint      `json:"id"`
+	Code      string    `json:"code"`
+	Source    string    `json:"source"`
+	Kind      string    `json:"kind"`
+	MinDays   int       `json:"min_days"`
+	MaxDays   int       `json:"max_days"`
+	MinAmount float64   `json:"min_amount"`
+	MaxAmount float64   `json:"max_amount"`
+	Rate      float64   `json:"rate"`
+	Fixed     float64   `json:"fixed"`
+	Origin    string    `json:"origin"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// exportTable 表格形式的导出数据（CSV / XLSX）
+type exportTable struct {
+	name    string
+	headers []string
+	numeric []string // XLSX 中写为数字的列（金额、比例、天数），其余列一律按文本写入
+	rows    [][]string
+}
+
+// ExportService 数据导出服务
+type ExportService struct {
+	db            *gorm.DB
+	assetRepo     *repo.AssetRepository
+	sourceRepo    *repo.SourceRepository
+	historyRepo   *repo.HistoryRepository
+	rebalanceRepo *repo.RebalanceRepository
+	reviewRepo    *repo.ReviewRepository
+	lotRepo       *repo.LotRepository
+	feeRepo       *repo.FeeRuleRepository
+	cashFlowRepo  *repo.CashFlowRepository
+	importRepo    *repo.ImportedTransactionRepository
+	indexDefRepo  *repo.IndexDefRepository
+	configRepo    *repo.ConfigRepository
+}
+
+func NewExportService(db *gorm.DB) *ExportService {
+	return &ExportService{
+		db:            db,
+		assetRepo:     repo.NewAssetRepository(db),
+		sourceRepo:    repo.NewSourceRepository(db),
+		historyRepo:   repo.NewHistoryRepository(db),
+		rebalanceRepo: repo.NewRebalanceRepository(db),
+		reviewRepo:    repo.NewReviewRepository(db),
+		lotRepo:       repo.NewLotRepository(db),
+		feeRepo:       repo.NewFeeRuleRepository(db),
+		cashFlowRepo:  repo.NewCashFlowRepository(db),
+		importRepo:    repo.NewImportedTransactionRepository(db),
+		indexDefRepo:  repo.NewIndexDefRepository(db),
+		configRepo:    repo.NewConfigRepository(db),
+	}
+}
+
+// Export 按格式导出全部数据到指定路径
+func (s *ExportService) Export(ctx context.Context, format, path string) error {
+	doc, err := s.BuildDocument(ctx)
+	if err != nil {
+		return err
+	}
+
+	var data []byte
+	switch format {
+	case ExportFormatCSV:
+		data, err = exportCSVZip(doc)
+	case ExportFormatJSON:
+		data, err = json.MarshalIndent(doc, "", "  ")
+	case ExportFormatXLSX:
+		data, err = exportXLSX(doc)
+	default:
+		return fmt.Errorf("不支持的导出格式: %s", format)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write export file: %w", err)
+	}
+	return nil
+}
+
+// BuildDocument 读取并解密全部数据
+func (s *ExportService) BuildDocument(ctx context.Context) (*ExportDocument, error) {
+	encryptKey, err := s.configRepo.Get(ctx, model.ConfigKeyEncryptKey)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &ExportDocument{
+		SchemaVersion: ExportSchemaVersion,
+		ExportedAt:    time.Now(),
+	}
+
+	assets, err := s.assetRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range assets {
+		amount, err := decryptExportAmount(a.EncryptedAmount, encryptKey.Value)
+		if err != nil {
+			return nil, err
+		}
+		doc.Assets = append(doc.Assets, ExportAsset{
+			ID:           a.ID,
+			Code:         a.Code,
+			Name:         a.Name,
+			URL:          a.URL,
+			Type:         a.Type,
+			Source:       a.Source,
+			Amount:       amount,
+			TargetWeight: a.TargetWeight,
+			CreatedAt:    a.CreatedAt,
+			UpdatedAt:    a.UpdatedAt,
+		})
+	}
+
+	sources, err := s.sourceRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, src := range sources {
+		doc.Sources = append(doc.Sources, ExportSource{
+			ID:        src.ID,
+			Name:      src.Name,
+			CreatedAt: src.CreatedAt,
+			UpdatedAt: src.UpdatedAt,
+		})
+	}
+
+	histories, err := s.historyRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range histories {
+		stockTotal, err := decryptExportAmount(h.EncryptedStockTotal, encryptKey.Value)
+		if err != nil {
+			return nil, err
+		}
+		bondTotal, err := decryptExportAmount(h.EncryptedBondTotal, encryptKey.Value)
+		if err != nil {
+			return nil, err
+		}
+		doc.History = append(doc.History, ExportHistory{
+			ID:         h.ID,
+			StockTotal: stockTotal,
+			BondTotal:  bondTotal,
+			StockRatio: h.StockRatio,
+			BondRatio:  h.BondRatio,
+			CreatedAt:  h.CreatedAt,
+		})
+	}
+
+	historyItems, err := s.historyRepo.GetAllItems(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range historyItems {
+		amount, err := decryptExportAmount(item.EncryptedAmount, encryptKey.Value)
+		if err != nil {
+			return nil, err
+		}
+		doc.HistoryItems = append(doc.HistoryItems, ExportHistoryItem{
+			ID:        item.ID,
+			HistoryID: item.HistoryID,
+			AssetID:   item.AssetID,
+			Code:      item.Code,
+			Name:      item.Name,
+			Type:      item.Type,
+			Source:    item.Source,
+			Amount:    amount,
+			CreatedAt: item.CreatedAt,
+		})
+	}
+
+	rebalances, err := s.rebalanceRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range rebalances {
+		total, err := decryptExportAmount(r.EncryptedTotalAmount, encryptKey.Value)
+		if err != nil {
+			return nil, err
+		}
+		stock, err := decryptExportAmount(r.EncryptedStockAmount, encryptKey.Value)
+		if err != nil {
+			return nil, err
+		}
+		bond, err := decryptExportAmount(r.EncryptedBondAmount, encryptKey.Value)
+		if err != nil {
+			return nil, err
+		}
+		doc.Rebalances = append(doc.Rebalances, ExportRebalance{
+			ID:               r.ID,
+			StockRatio:       r.StockRatio,
+			BondRatio:        r.BondRatio,
+			TotalAmount:      total,
+			StockAmount:      stock,
+			BondAmount:       bond,
+			TargetStockRatio: r.TargetStockRatio,
+			TargetBondRatio:  r.TargetBondRatio,
+			Note:             r.Note,
+			CreatedAt:        r.CreatedAt,
+		})
+	}
+
+	rebalanceHoldings, err := s.rebalanceRepo.GetAllHoldings(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range rebalanceHoldings {
+		amount, err := decryptExportAmount(h.EncryptedAmount, encryptKey.Value)
+		if err != nil {
+			return nil, err
+		}
+		doc.RebalanceHoldings = append(doc.RebalanceHoldings, ExportRebalanceHolding{
+			ID:          h.ID,
+			RebalanceID: h.RebalanceID,
+			AssetID:     h.AssetID,
+			Code:        h.Code,
+			Name:        h.Name,
+			Type:        h.Type,
+			Source:      h.Source,
+			Amount:      amount,
+			Ratio:       h.Ratio,
+			CreatedAt:   h.CreatedAt,
+		})
+	}
+
+	rebalanceOrders, err := s.rebalanceRepo.GetAllOrders(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range rebalanceOrders {
+		amount, err := decryptExportAmount(o.EncryptedAmount, encryptKey.Value)
+		if err != nil {
+			return nil, err
+		}
+		var filled ExportAmount
+		if o.EncryptedFilledAmount != "" {
+			if filled, err = decryptExportAmount(o.EncryptedFilledAmount, encryptKey.Value); err != nil {
+				return nil, err
+			}
+		}
+		doc.RebalanceOrders = append(doc.RebalanceOrders, ExportRebalanceOrder{
+			ID:           o.ID,
+			RebalanceID:  o.RebalanceID,
+			AssetID:      o.AssetID,
+			Code:         o.Code,
+			Name:         o.Name,
+			Type:         o.Type,
+			Source:       o.Source,
+			Action:       o.Action,
+			Amount:       amount,
+			FilledAmount: filled,
+			Status:       o.Status,
+			SubmittedAt:  o.SubmittedAt,
+			FilledAt:     o.FilledAt,
+			CreatedAt:    o.CreatedAt,
+			UpdatedAt:    o.UpdatedAt,
+		})
+	}
+
+	reviews, err := s.reviewRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range reviews {
+		doc.Reviews = append(doc.Reviews, ExportReview{
+			ID:               r.ID,
+			DueDate:          r.DueDate,
+			TargetStockRatio: r.TargetStockRatio,
+			StockRatio:       r.StockRatio,
+			Breached:         r.Breached,
+			Reasons:          r.Reasons,
+			Outcome:          r.Outcome,
+			Note:             r.Note,
+			CreatedAt:        r.CreatedAt,
+			UpdatedAt:        r.UpdatedAt,
+		})
+	}
+
+	cashFlows, err := s.cashFlowRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range cashFlows {
+		amount, err := decryptExportAmount(f.EncryptedAmount, encryptKey.Value)
+		if err != nil {
+			return nil, err
+		}
+		doc.CashFlows = append(doc.CashFlows, ExportCashFlow{
+			ID:        f.ID,
+			Date:      f.Date,
+			Kind:      f.Kind,
+			AssetID:   f.AssetID,
+			Code:      f.Code,
+			Name:      f.Name,
+			Type:      f.Type,
+			Source:    f.Source,
+			Amount:    amount,
+			OrderID:   f.OrderID,
+			Note:      f.Note,
+			CreatedAt: f.CreatedAt,
+		})
+	}
+
+	imported, err := s.importRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range imported {
+		doc.ImportedTransactions = append(doc.ImportedTransactions, ExportImportedTransaction{
+			ID:        t.ID,
+			Hash:      t.Hash,
+			Source:    t.Source,
+			Code:      t.Code,
+			CreatedAt: t.CreatedAt,
+		})
+	}
+
+	defs, err := s.indexDefRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range defs {
+		doc.IndexDefs = append(doc.IndexDefs, ExportIndexDef{
+			Code:      d.Code,
+			Name:      d.Name,
+			SecID:     d.SecID,
+			Market:    d.Market,
+			Currency:  d.Currency,
+			Sort:      d.Sort,
+			Watch:     d.Watch,
+			CreatedAt: d.CreatedAt,
+			UpdatedAt: d.UpdatedAt,
+		})
+	}
+
+	lots, err := s.lotRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, l := range lots {
+		amount, err := decryptExportAmount(l.EncryptedAmount, encryptKey.Value)
+		if err != nil {
+			return nil, err
+		}
+		doc.Lots = append(doc.Lots, ExportLot{
+			ID:        l.ID,
+			AssetID:   l.AssetID,
+			BuyDate:   l.BuyDate,
+			Amount:    amount,
+			CreatedAt: l.CreatedAt,
+		})
+	}
+
+	rules, err := s.feeRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range rules {
+		doc.FeeRules = append(doc.FeeRules, ExportFeeRule{
+			ID:        r.ID,
+			Code:      r.Code,
+			Source:    r.Source,
+			Kind:      r.Kind,
+			MinDays:   r.MinDays,
+			MaxDays:   r.MaxDays,
+			MinAmount: r.MinAmount,
+			MaxAmount: r.MaxAmount,
+			Rate:      r.Rate,
+			Fixed:     r.Fixed,
+			Origin:    r.Origin,
+			CreatedAt: r.CreatedAt,
+			UpdatedAt: r.UpdatedAt,
+		})
+	}
+
+	return doc, nil
+}
+
+// decryptExportAmount 解密金额字段，保留数据库中存储的原始字符串
+func decryptExportAmount(encrypted, key string) (ExportAmount, error) {
+	amountStr, err := crypto.Decrypt(encrypted, key)
+	if err != nil {
+		return "", err
+	}
+	return ExportAmount(amountStr), nil
+}
+
+// tables 将导出文档转换为表格
+func (d *ExportDocument) tables() []exportTable {
+	const timeLayout = "2006-01-02 15:04:05"
+	ratio := func(v float64) string { return strconv.FormatFloat(v, 'f', 4, 64) }
+	id := func(v uint) string { return strconv.FormatUint(uint64(v), 10) }
+
+	assets := exportTable{
+		name:    "assets",
+		headers: []string{"id", "code", "name", "url", "type", "source", "amount", "target_weight", "created_at", "updated_at"},
+		numeric: []string{"amount", "target_weight"},
+	}
+	for _, a := range d.Assets {
+		assets.rows = append(assets.rows, []string{
+			id(a.ID), a.Code, a.Name, a.URL, a.Type, a.Source, string(a.Amount), ratio(a.TargetWeight),
+			a.CreatedAt.Format(timeLayout), a.UpdatedAt.Format(timeLayout),
+		})
+	}
+
+	sources := exportTable{
+		name:    "sources",
+		headers: []string{"id", "name", "created_at", "updated_at"},
+	}
+	for _, src := range d.Sources {
+		sources.rows = append(sources.rows, []string{
+			id(src.ID), src.Name, src.CreatedAt.Format(timeLayout), src.UpdatedAt.Format(timeLayout),
+		})
+	}
+
+	history := exportTable{
+		name:    "history",
+		headers: []string{"id", "stock_total", "bond_total", "stock_ratio", "bond_ratio", "created_at"},
+		numeric: []string{"stock_total", "bond_total", "stock_ratio", "bond_ratio"},
+	}
+	for _, h := range d.History {
+		history.rows = append(history.rows, []string{
+			id(h.ID), string(h.StockTotal), string(h.BondTotal), ratio(h.StockRatio), ratio(h.BondRatio),
+			h.CreatedAt.Format(timeLayout),
+		})
+	}
+
+	historyItems := exportTable{
+		name:    "history_items",
+		headers: []string{"id", "history_id", "asset_id", "code", "name", "type", "source", "amount", "created_at"},
+		numeric: []string{"amount"},
+	}
+	for _, item := range d.HistoryItems {
+		historyItems.rows = append(historyItems.rows, []string{
+			id(item.ID), id(item.HistoryID), id(item.AssetID), item.Code, item.Name, item.Type, item.Source,
+			string(item.Amount), item.CreatedAt.Format(timeLayout),
+		})
+	}
+
+	rebalances := exportTable{
+		name: "rebalances",
+		headers: []string{"id", "stock_ratio", "bond_ratio", "total_amount", "stock_amount", "bond_amount",
+			"target_stock_ratio", "target_bond_ratio", "note", "created_at"},
+		numeric: []string{"stock_ratio", "bond_ratio", "total_amount", "stock_amount", "bond_amount",
+			"target_stock_ratio", "target_bond_ratio"},
+	}
+	for _, r := range d.Rebalances {
+		rebalances.rows = append(rebalances.rows, []string{
+			id(r.ID), ratio(r.StockRatio), ratio(r.BondRatio), string(r.TotalAmount), string(r.StockAmount),
+			string(r.BondAmount), ratio(r.TargetStockRatio), ratio(r.TargetBondRatio), r.Note,
+			r.CreatedAt.Format(timeLayout),
+		})
+	}
+
+	rebalanceHoldings := exportTable{
+		name:    "rebalance_holdings",
+		headers: []string{"id", "rebalance_id", "asset_id", "code", "name", "type", "source", "amount", "ratio", "created_at"},
+		numeric: []string{"amount", "ratio"},
+	}
+	for _, h := range d.RebalanceHoldings {
+		rebalanceHoldings.rows = append(rebalanceHoldings.rows, []string{
+			id(h.ID), id(h.RebalanceID), id(h.AssetID), h.Code, h.Name, h.Type, h.Source, string(h.Amount),
+			ratio(h.Ratio), h.CreatedAt.Format(timeLayout),
+		})
+	}
+
+	optionalTime := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.Format(timeLayout)
+	}
+	rebalanceOrders := exportTable{
+		name: "rebalance_orders",
+		headers: []string{"id", "rebalance_id", "asset_id", "code", "name", "type", "source", "action", "amount",
+			"filled_amount", "status", "submitted_at", "filled_at", "created_at"},
+		numeric: []string{"amount", "filled_amount"},
+	}
+	for _, o := range d.RebalanceOrders {
+		rebalanceOrders.rows = append(rebalanceOrders.rows, []string{
+			id(o.ID), id(o.RebalanceID), id(o.AssetID), o.Code, o.Name, o.Type, o.Source, o.Action, string(o.Amount),
+			string(o.FilledAmount), o.Status, optionalTime(o.SubmittedAt), optionalTime(o.FilledAt),
+			o.CreatedAt.Format(timeLayout),
+		})
+	}
+
+	reviews := exportTable{
+		name: "reviews",
+		headers: []string{"id", "due_date", "target_stock_ratio", "stock_ratio", "breached", "reasons", "outcome",
+			"note", "created_at"},
+		numeric: []string{"target_stock_ratio", "stock_ratio"},
+	}
+	for _, r := range d.Reviews {
+		reviews.rows = append(reviews.rows, []string{
+			id(r.ID), r.DueDate.Format("2006-01-02"), ratio(r.TargetStockRatio), ratio(r.StockRatio),
+			strconv.FormatBool(r.Breached), r.Reasons, r.Outcome, r.Note, r.CreatedAt.Format(timeLayout),
+		})
+	}
+
+	cashFlows := exportTable{
+		name: "cash_flows",
+		headers: []string{"id", "date", "kind", "asset_id", "code", "name", "type", "source", "amount", "order_id",
+			"note", "created_at"},
+		numeric: []string{"amount"},
+	}
+	for _, f := range d.CashFlows {
+		cashFlows.rows = append(cashFlows.rows, []string{
+			id(f.ID), f.Date.Format("2006-01-02"), f.Kind, id(f.AssetID), f.Code, f.Name, f.Type, f.Source,
+			string(f.Amount), id(f.OrderID), f.Note, f.CreatedAt.Format(timeLayout),
+		})
+	}
+
+	lots := exportTable{
+		name:    "lots",
+		headers: []string{"id", "asset_id", "buy_date", "amount", "created_at"},
+		numeric: []string{"amount"},
+	}
+	for _, l := range d.Lots {
+		lots.rows = append(lots.rows, []string{
+			id(l.ID), id(l.AssetID), l.BuyDate.Format("2006-01-02"), string(l.Amount), l.CreatedAt.Format(timeLayout),
+		})
+	}
+
+	feeRules := exportTable{
+		name: "fee_rules",
+		headers: []string{"id", "code", "source", "kind", "min_days", "max_days", "min_amount", "max_amount",
+			"rate", "fixed", "origin", "updated_at"},
+		numeric: []string{"min_days", "max_days", "min_amount", "max_amount", "rate", "fixed"},
+	}
+	for _, r := range d.FeeRules {
+		feeRules.rows = append(feeRules.rows, []string{
+			id(r.ID), r.Code, r.Source, r.Kind, strconv.Itoa(r.MinDays), strconv.Itoa(r.MaxDays),
+			ratio(r.MinAmount), ratio(r.MaxAmount), ratio(r.Rate), ratio(r.Fixed), r.Origin,
+			r.UpdatedAt.Format(timeLayout),
+		})
+	}
+
+	importedTransactions := exportTable{
+		name:    "imported_transactions",
+		headers: []string{"id", "hash", "source", "code", "created_at"},
+	}
+	for _, t := range d.ImportedTransactions {
+		importedTransactions.rows = append(importedTransactions.rows, []string{
+			id(t.ID), t.Hash, t.Source, t.Code, t.CreatedAt.Format(timeLayout),
+		})
+	}
+
+	indexDefs := exportTable{
+		name:    "index_defs",
+		headers: []string{"code", "name", "secid", "market", "currency", "sort", "watch", "updated_at"},
+		numeric: []string{"sort"},
+	}
+	for _, def := range d.IndexDefs {
+		indexDefs.rows = append(indexDefs.rows, []string{
+			def.Code, def.Name, def.SecID, def.Market, def.Currency, strconv.Itoa(def.Sort), strconv.FormatBool(def.Watch),
+			def.UpdatedAt.Format(timeLayout),
+		})
+	}
+
+	return []exportTable{assets, sources, history, historyItems, rebalances, rebalanceHoldings, rebalanceOrders, reviews, cashFlows, lots, feeRules, importedTransactions, indexDefs}
+}
+
+// exportCSVZip 每张表一个 CSV 文件，打包为 zip
+func exportCSVZip(doc *ExportDocument) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	for _, table := range doc.tables() {
+		w, err := zw.Create(table.name + ".csv")
+		if err != nil {
+			return nil, err
+		}
+		// 写入 UTF-8 BOM，方便 Excel 正确识别中文
+		if _, err := w.Write([]byte("\xef\xbb\xbf")); err != nil {
+			return nil, err
+		}
+
+		cw := csv.NewWriter(w)
+		if err := cw.Write(table.headers); err != nil {
+			return nil, err
+		}
+		if err := cw.WriteAll(table.rows); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// exportXLSX 每张表一个工作表
+func exportXLSX(doc *ExportDocument) ([]byte, error) {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	for i, table := range doc.tables() {
+		if i == 0 {
+			if err := f.SetSheetName(f.GetSheetName(0), table.name); err != nil {
+				return nil, err
+			}
+		} else if _, err := f.NewSheet(table.name); err != nil {
+			return nil, err
+		}
+
+		sw, err := f.NewStreamWriter(table.name)
+		if err != nil {
+			return nil, err
+		}
+
+		header := make([]interface{}, len(table.headers))
+		for j, h := range table.headers {
+			header[j] = h
+		}
+		if err := sw.SetRow("A1", header); err != nil {
+			return nil, err
+		}
+
+		numeric := make([]bool, len(table.headers))
+		for j, h := range table.headers {
+			numeric[j] = slices.Contains(table.numeric, h)
+		}
+
+		for r, row := range table.rows {
+			values := make([]interface{}, len(row))
+			for j, v := range row {
+				// 金额和比例列写为数字，便于在 Excel 中计算；代码、名称、备注等即使像数字也保留文本
+				n, err := strconv.ParseFloat(v, 64)
+				if numeric[j] && err == nil && !math.IsNaN(n) && !math.IsInf(n, 0) {
+					values[j] = n
+				} else {
+					values[j] = v
+				}
+			}
+			cell, err := excelize.CoordinatesToCellName(1, r+2)
+			if err != nil {
+				return nil, err
+			}
+			if err := sw.SetRow(cell, values); err != nil {
+				return nil, err
+			}
+		}
+
+		if err := sw.Flush(); err != nil {
+			return nil, err
+		}
+	}
+
+	buf, err := f.WriteToBuffer()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
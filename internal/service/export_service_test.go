@@ -0,0 +1,47 @@
+package service
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+func TestExportXLSXNumericColumns(t *testing.T) {
+	doc := &ExportDocument{
+		Assets:     []ExportAsset{{ID: 1, Code: "000001", Name: "12345", Source: "2024", Amount: "1234.50", TargetWeight: 0.3}},
+		Rebalances: []ExportRebalance{{ID: 1, TotalAmount: "100.00", Note: "NaN"}},
+	}
+	data, err := exportXLSX(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := excelize.OpenReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	tests := []struct {
+		sheet, cell string
+		want        excelize.CellType
+	}{
+		{"assets", "B2", excelize.CellTypeInlineString}, // code
+		{"assets", "C2", excelize.CellTypeInlineString}, // name
+		{"assets", "F2", excelize.CellTypeInlineString}, // source
+		{"assets", "G2", excelize.CellTypeUnset},        // amount，数字单元格没有类型标记
+		{"assets", "H2", excelize.CellTypeUnset},        // target_weight
+		{"rebalances", "D2", excelize.CellTypeUnset},    // total_amount
+		{"rebalances", "I2", excelize.CellTypeInlineString},
+	}
+	for _, tt := range tests {
+		got, err := f.GetCellType(tt.sheet, tt.cell)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			v, _ := f.GetCellValue(tt.sheet, tt.cell)
+			t.Errorf("%s!%s (%q) type = %v, want %v", tt.sheet, tt.cell, v, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,278 @@
+package service
+
+import (
+	"context"
+	"margin/internal/crypto"
+	"margin/internal/model"
+	"margin/internal/repo"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// LotSale 卖出时从某一持仓批次赎回的金额
+type LotSale struct {
+	LotID    uint      `json:"lot_id"`    // 0 表示未录入批次，全部持仓视为一个买入日期未知的批次
+	BuyDate  time.Time `json:"buy_date"`  // 买入日期未知时为零值
+	HeldDays int       `json:"held_days"` // 买入日期未知时为 -1
+	Amount   float64   `json:"amount"`
+	Rate     float64   `json:"rate"` // 赎回费率(%)
+	Fee      float64   `json:"fee"`
+}
+
+// lotValue 持仓批次按当前市值折算后的金额
+type lotValue struct {
+	LotID   uint
+	BuyDate time.Time
+	Value   float64
+}
+
+// feeModel 估算交易费用：申购费按来源和金额分档，赎回费按每个批次的持有天数分档
+type feeModel struct {
+	rules map[string][]model.FeeRule // 按基金代码
+	lots  map[uint][]lotValue        // 按资产 ID，金额为买入成本
+	now   time.Time
+}
+
+// loadFeeModel 读取费率规则和持仓批次
+func loadFeeModel(ctx context.Context, feeRepo *repo.FeeRuleRepository, lotRepo *repo.LotRepository, key string) (*feeModel, error) {
+	rules, err := feeRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	lots, err := lotRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &feeModel{
+		rules: map[string][]model.FeeRule{},
+		lots:  map[uint][]lotValue{},
+		now:   time.Now(),
+	}
+	for _, r := range rules {
+		m.rules[r.Code] = append(m.rules[r.Code], r)
+	}
+	for _, l := range lots {
+		amountStr, err := crypto.Decrypt(l.EncryptedAmount, key)
+		if err != nil {
+			return nil, err
+		}
+		amount, _ := strconv.ParseFloat(amountStr, 64)
+		m.lots[l.AssetID] = append(m.lots[l.AssetID], lotValue{LotID: l.ID, BuyDate: l.BuyDate, Value: amount})
+	}
+	return m, nil
+}
+
+// matchRules 返回适用于该持仓的规则：优先使用来源专属规则，否则使用通用规则
+func (m *feeModel) matchRules(h *Holding, kind string) []model.FeeRule {
+	var specific, general []model.FeeRule
+	for _, r := range m.rules[h.Asset.Code] {
+		if r.Kind != kind {
+			continue
+		}
+		switch r.Source {
+		case h.Asset.Source:
+			specific = append(specific, r)
+		case "":
+			general = append(general, r)
+		}
+	}
+	if len(specific) > 0 {
+		return specific
+	}
+	return general
+}
+
+// subscriptionFee 估算买入费用，申购费按净额计算：费用 = 金额 - 金额/(1+费率)
+func (m *feeModel) subscriptionFee(h *Holding, amount float64) (fee, rate float64, known bool) {
+	for _, r := range m.matchRules(h, model.FeeKindSubscription) {
+		if amount < r.MinAmount || r.MaxAmount > 0 && amount >= r.MaxAmount {
+			continue
+		}
+		if r.Fixed > 0 {
+			return r.Fixed, 0, true
+		}
+		return amount - amount/(1+r.Rate/100), r.Rate, true
+	}
+	return 0, 0, false
+}
+
+// redemptionRate 按持有天数查找赎回费率，持有天数未知（-1）时费率未知
+func (m *feeModel) redemptionRate(h *Holding, days int) (rate, fixed float64, known bool) {
+	if days < 0 {
+		return 0, 0, false
+	}
+	for _, r := range m.matchRules(h, model.FeeKindRedemption) {
+		if days < r.MinDays || r.MaxDays > 0 && days >= r.MaxDays {
+			continue
+		}
+		return r.Rate, r.Fixed, true
+	}
+	return 0, 0, false
+}
+
+// lotsFor 返回按当前市值折算的批次；未录入批次时返回一个买入日期未知（零值）的批次。
+// 资产的创建时间通常是录入或导入的时间而不是买入时间，不能用来推算持有天数
+func (m *feeModel) lotsFor(h *Holding) []lotValue {
+	lots := m.lots[h.Asset.ID]
+	var cost float64
+	for _, l := range lots {
+		cost += l.Value
+	}
+	if cost <= 0 {
+		return []lotValue{{Value: h.Amount}}
+	}
+
+	scaled := make([]lotValue, len(lots))
+	for i, l := range lots {
+		scaled[i] = l
+		scaled[i].Value = h.Amount * l.Value / cost
+	}
+	return scaled
+}
+
+// heldDays 持有天数，买入日期未知时返回 -1
+func (m *feeModel) heldDays(buyDate time.Time) int {
+	if buyDate.IsZero() {
+		return -1
+	}
+	days := int(m.now.Sub(buyDate).Hours() / 24)
+	if days < 0 {
+		return 0
+	}
+	return days
+}
+
+// planSale 优先卖出赎回费率最低的批次，费率相同时先卖持有最久的
+func (m *feeModel) planSale(h *Holding, amount float64) (sales []LotSale, fee float64, known bool) {
+	type candidate struct {
+		lot   lotValue
+		days  int
+		rate  float64
+		fixed float64
+		known bool
+	}
+
+	known = true
+	var candidates []candidate
+	for _, l := range m.lotsFor(h) {
+		days := m.heldDays(l.BuyDate)
+		rate, fixed, ok := m.redemptionRate(h, days)
+		candidates = append(candidates, candidate{lot: l, days: days, rate: rate, fixed: fixed, known: ok})
+	}
+	// 费率未知的批次排在已知批次之后
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].known != candidates[j].known {
+			return candidates[i].known
+		}
+		if candidates[i].rate != candidates[j].rate {
+			return candidates[i].rate < candidates[j].rate
+		}
+		return candidates[i].lot.BuyDate.Before(candidates[j].lot.BuyDate)
+	})
+
+	var fixed float64
+	remaining := amount
+	for _, c := range candidates {
+		if remaining < 0.01 {
+			break
+		}
+		sell := math.Min(c.lot.Value, remaining)
+		if sell <= 0 {
+			continue
+		}
+		sale := LotSale{
+			LotID:    c.lot.LotID,
+			BuyDate:  c.lot.BuyDate,
+			HeldDays: c.days,
+			Amount:   math.Round(sell*100) / 100,
+			Rate:     c.rate,
+			Fee:      math.Round(sell*c.rate) / 100,
+		}
+		sales = append(sales, sale)
+		fee += sale.Fee
+		fixed = math.Max(fixed, c.fixed)
+		known = known && c.known
+		remaining -= sell
+	}
+
+	// 固定费用按笔收取
+	return sales, fee + fixed, known
+}
+
+// cheapestRedemptionRate 持仓中最便宜批次的赎回费率，用于选择卖出的基金。
+// 买入日期未知的批次按最高一档估计，避免被误当作免赎回费而优先卖出
+func (m *feeModel) cheapestRedemptionRate(h *Holding) float64 {
+	best := math.Inf(1)
+	for _, l := range m.lotsFor(h) {
+		if l.Value <= 0 {
+			continue
+		}
+		var rate float64
+		if days := m.heldDays(l.BuyDate); days < 0 {
+			for _, r := range m.matchRules(h, model.FeeKindRedemption) {
+				rate = math.Max(rate, r.Rate)
+			}
+		} else {
+			rate, _, _ = m.redemptionRate(h, days)
+		}
+		best = math.Min(best, rate)
+	}
+	if math.IsInf(best, 1) {
+		return 0
+	}
+	return best
+}
+
+// annotate 为交易估算费用，卖出时给出批次明细
+func (m *feeModel) annotate(o *PlanOrder, h *Holding) {
+	if m == nil {
+		return
+	}
+	if o.Action == OrderActionBuy {
+		fee, rate, known := m.subscriptionFee(h, o.Amount)
+		o.EstimatedFee = math.Round(fee*100) / 100
+		o.FeeRate = rate
+		o.FeeKnown = known
+		return
+	}
+
+	sales, fee, known := m.planSale(h, o.Amount)
+	o.EstimatedFee = math.Round(fee*100) / 100
+	if o.Amount > 0 {
+		o.FeeRate = fee / o.Amount * 100
+	}
+	o.FeeKnown = known
+	o.Lots = sales
+}
+
+// totalFees 汇总交易费用，known 表示所有交易都找到了费率
+func totalFees(orders []PlanOrder) (total float64, known bool) {
+	known = true
+	for _, o := range orders {
+		total += o.EstimatedFee
+		known = known && o.FeeKnown
+	}
+	return math.Round(total*100) / 100, known
+}
+
+func lotSalesToMaps(sales []LotSale) []map[string]interface{} {
+	result := make([]map[string]interface{}, 0, len(sales))
+	for _, s := range sales {
+		item := map[string]interface{}{
+			"lot_id":    s.LotID,
+			"buy_date":  "",
+			"held_days": s.HeldDays,
+			"amount":    s.Amount,
+			"rate":      s.Rate,
+			"fee":       s.Fee,
+		}
+		if !s.BuyDate.IsZero() {
+			item["buy_date"] = s.BuyDate.Format("2006-01-02")
+		}
+		result = append(result, item)
+	}
+	return result
+}
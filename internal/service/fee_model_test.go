@@ -0,0 +1,48 @@
+package service
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"margin/internal/model"
+)
+
+func TestPlanSaleBuyDate(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.Local)
+	rules := []model.FeeRule{
+		{Code: "000001", Kind: model.FeeKindRedemption, MinDays: 0, MaxDays: 7, Rate: 1.5},
+		{Code: "000001", Kind: model.FeeKindRedemption, MinDays: 7, MaxDays: 365, Rate: 0.5},
+		{Code: "000001", Kind: model.FeeKindRedemption, MinDays: 365, Rate: 0},
+	}
+	// 资产刚录入，创建时间不代表买入时间
+	holding := &Holding{Asset: model.Asset{ID: 1, Code: "000001", CreatedAt: now.AddDate(0, 0, -1)}, Amount: 1000}
+
+	tests := []struct {
+		name      string
+		lots      []lotValue
+		wantFee   float64
+		wantKnown bool
+		wantDays  int
+	}{
+		{name: "no lots", wantFee: 0, wantKnown: false, wantDays: -1},
+		{name: "lot held two years", lots: []lotValue{{LotID: 1, BuyDate: now.AddDate(-2, 0, 0), Value: 800}}, wantFee: 0, wantKnown: true, wantDays: 731},
+		{name: "lot held three days", lots: []lotValue{{LotID: 1, BuyDate: now.AddDate(0, 0, -3), Value: 800}}, wantFee: 7.5, wantKnown: true, wantDays: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &feeModel{
+				rules: map[string][]model.FeeRule{"000001": rules},
+				lots:  map[uint][]lotValue{1: tt.lots},
+				now:   now,
+			}
+			sales, fee, known := m.planSale(holding, 500)
+			if math.Abs(fee-tt.wantFee) > 0.005 || known != tt.wantKnown {
+				t.Errorf("fee = %.2f known = %v, want %.2f %v", fee, known, tt.wantFee, tt.wantKnown)
+			}
+			if len(sales) != 1 || sales[0].HeldDays != tt.wantDays {
+				t.Errorf("sales = %+v, want held days %d", sales, tt.wantDays)
+			}
+		})
+	}
+}
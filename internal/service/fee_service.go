@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"margin/internal/model"
+	"margin/internal/repo"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// FeeService 基金费率管理
+type FeeService struct {
+	db          *gorm.DB
+	feeRepo     *repo.FeeRuleRepository
+	fundService *FundService
+}
+
+func NewFeeService(db *gorm.DB, fundService *FundService) *FeeService {
+	return &FeeService{
+		db:          db,
+		feeRepo:     repo.NewFeeRuleRepository(db),
+		fundService: fundService,
+	}
+}
+
+// GetFeeRules 获取基金的费率规则
+func (s *FeeService) GetFeeRules(ctx context.Context, code string) ([]map[string]interface{}, error) {
+	rules, err := s.feeRepo.GetByCode(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]map[string]interface{}, 0, len(rules))
+	for _, r := range rules {
+		result = append(result, map[string]interface{}{
+			"id":         r.ID,
+			"code":       r.Code,
+			"source":     r.Source,
+			"kind":       r.Kind,
+			"min_days":   r.MinDays,
+			"max_days":   r.MaxDays,
+			"min_amount": r.MinAmount,
+			"max_amount": r.MaxAmount,
+			"rate":       r.Rate,
+			"fixed":      r.Fixed,
+			"origin":     r.Origin,
+			"updated_at": r.UpdatedAt.Format("2006-01-02 15:04:05"),
+		})
+	}
+	return result, nil
+}
+
+// FetchFeeRules 从基金费率页抓取费率，替换之前抓取的规则（手动录入的保留）
+func (s *FeeService) FetchFeeRules(ctx context.Context, code string) (int, error) {
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return 0, errors.New("基金代码不能为空")
+	}
+
+	rules, err := s.fundService.GetFundFees(ctx, code)
+	if err != nil {
+		return 0, err
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		feeRepo := repo.NewFeeRuleRepository(tx)
+		if err := feeRepo.DeleteFetched(ctx, code); err != nil {
+			return err
+		}
+		for i := range rules {
+			if err := feeRepo.Create(ctx, &rules[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(rules), nil
+}
+
+// SaveFeeRule 手动录入一条费率规则。source 为空表示适用于所有平台；
+// 申购费使用金额区间，赎回费使用持有天数区间，上限为 0 表示无上限
+func (s *FeeService) SaveFeeRule(ctx context.Context, rule model.FeeRule) error {
+	rule.Code = strings.TrimSpace(rule.Code)
+	if rule.Code == "" {
+		return errors.New("基金代码不能为空")
+	}
+	switch rule.Kind {
+	case model.FeeKindSubscription:
+		if rule.MaxAmount > 0 && rule.MaxAmount <= rule.MinAmount {
+			return errors.New("金额上限必须大于下限")
+		}
+	case model.FeeKindRedemption:
+		if rule.MinDays < 0 || rule.MaxDays > 0 && rule.MaxDays <= rule.MinDays {
+			return errors.New("持有天数上限必须大于下限")
+		}
+	default:
+		return fmt.Errorf("不支持的费率类型: %s", rule.Kind)
+	}
+	if rule.Rate < 0 || rule.Rate > 100 || rule.Fixed < 0 {
+		return errors.New("费率需在 0-100 之间")
+	}
+
+	rule.ID = 0
+	rule.Origin = model.FeeOriginManual
+	return s.feeRepo.Create(ctx, &rule)
+}
+
+// DeleteFeeRule 删除费率规则
+func (s *FeeService) DeleteFeeRule(ctx context.Context, id uint) error {
+	return s.feeRepo.Delete(ctx, id)
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FixtureTransport 行情请求的回放/录制传输层。
+// 回放时按请求的主机、secid 和起始日期从目录读取响应文件，不访问网络；录制时照常请求并把响应写入同名文件
+type FixtureTransport struct {
+	Dir    string
+	Record bool
+	Next   http.RoundTripper // 录制时实际发出请求，为空使用 http.DefaultTransport
+}
+
+func NewFixtureTransport(dir string, record bool) *FixtureTransport {
+	return &FixtureTransport{
+		Dir:    dir,
+		Record: record,
+	}
+}
+
+// fixtureName 请求对应的响应文件名，例如 push2his.eastmoney.com_1.000300_19900101.json。
+// 日线请求带上起始日期，增量下载的响应不会覆盖全部历史的录制文件
+func fixtureName(req *http.Request) string {
+	query := req.URL.Query()
+	key := query.Get("secid")
+	if key == "" {
+		key = strings.Trim(strings.ReplaceAll(req.URL.Path, "/", "_"), "_")
+	}
+	if begin := query.Get("beg"); begin != "" {
+		key += "_" + begin
+	}
+	return req.URL.Hostname() + "_" + key + ".json"
+}
+
+func (t *FixtureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	path := filepath.Join(t.Dir, fixtureName(req))
+	if t.Record {
+		return t.record(req, path)
+	}
+
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("回放数据不存在: %w", err)
+	}
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": {"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// record 发出真实请求，成功时保存响应内容
+func (t *FixtureTransport) record(req *http.Request, path string) (*http.Response, error) {
+	next := t.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	resp, err := next.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return resp, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(t.Dir, 0755); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, body, 0644); err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return resp, nil
+}
@@ -4,8 +4,10 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"margin/internal/model"
 	"net/http"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -36,7 +38,7 @@ func NewFundService() *FundService {
 
 // GetFundInfo 获取基金信息
 func (s *FundService) GetFundInfo(ctx context.Context, fundCode string) (*FundInfo, error) {
-	url := fmt.Sprintf("https://fund.eastmoney.com/%s.html", fundCode)
+	url := FundURL(fundCode)
 
 	// 创建请求
 	req, err := http.NewRequest("GET", url, nil)
@@ -138,3 +140,159 @@ func (s *FundService) GetFundInfo(ctx context.Context, fundCode string) (*FundIn
 
 	return fundInfo, nil
 }
+
+// FundURL 基金详情页 URL
+func FundURL(fundCode string) string {
+	return fmt.Sprintf("https://fund.eastmoney.com/%s.html", fundCode)
+}
+
+// FundFeeURL 基金费率页 URL
+func FundFeeURL(fundCode string) string {
+	return fmt.Sprintf("https://fundf10.eastmoney.com/jjfl_%s.html", fundCode)
+}
+
+// GetFundFees 从基金费率页抓取申购费（按金额分档）和赎回费（按持有期分档）。
+// 页面中的“天天基金优惠费率”一并保存为来源“天天基金”的申购费率
+func (s *FundService) GetFundFees(ctx context.Context, fundCode string) ([]model.FeeRule, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", FundFeeURL(fundCode), nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %w", err)
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/131.0.0.0 Safari/537.36")
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,image/webp,*/*;q=0.8")
+	req.Header.Set("Accept-Language", "zh-CN,zh;q=0.9,en;q=0.8")
+	req.Header.Set("Referer", FundURL(fundCode))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP 状态码错误: %d", resp.StatusCode)
+	}
+
+	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("解析 HTML 失败: %w", err)
+	}
+
+	rules := parseFundFees(doc, fundCode)
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("未找到基金费率信息，请手动录入")
+	}
+	return rules, nil
+}
+
+// parseFundFees 解析费率页中的“申购费率（前端）”和“赎回费率”表格
+func parseFundFees(doc *goquery.Document, fundCode string) []model.FeeRule {
+	var rules []model.FeeRule
+
+	doc.Find(".boxitem").Each(func(_ int, box *goquery.Selection) {
+		title := strings.TrimSpace(box.Find("h4").First().Text())
+		var kind string
+		switch {
+		case strings.Contains(title, "申购费率") && !strings.Contains(title, "后端"):
+			kind = model.FeeKindSubscription
+		case strings.Contains(title, "赎回费率"):
+			kind = model.FeeKindRedemption
+		default:
+			return
+		}
+
+		box.Find("table tbody tr").Each(func(_ int, tr *goquery.Selection) {
+			var cells []string
+			tr.Find("td").Each(func(_ int, td *goquery.Selection) {
+				cells = append(cells, strings.TrimSpace(td.Text()))
+			})
+			if len(cells) < 3 {
+				return
+			}
+
+			rule := model.FeeRule{Code: fundCode, Kind: kind, Origin: model.FeeOriginFetched}
+			if kind == model.FeeKindSubscription {
+				rule.MinAmount, rule.MaxAmount = parseFeeRange(cells[0])
+			} else {
+				rule.MinDays, rule.MaxDays = parseHoldingDays(cells[1])
+			}
+
+			rate, fixed, ok := parseFeeRate(cells[2])
+			if !ok {
+				return
+			}
+			rule.Rate, rule.Fixed = rate, fixed
+			rules = append(rules, rule)
+
+			// 优惠费率单元格中包含原费率（划线）和优惠费率，取最后一个
+			if kind == model.FeeKindSubscription && len(cells) > 3 {
+				if rate, fixed, ok := parseFeeRate(cells[3]); ok {
+					discount := rule
+					discount.Source = "天天基金"
+					discount.Rate, discount.Fixed = rate, fixed
+					rules = append(rules, discount)
+				}
+			}
+		})
+	})
+
+	return rules
+}
+
+var (
+	feeBoundPattern = regexp.MustCompile(`(大于等于|大于|小于等于|小于|≥|>|≤|<)\s*([\d.]+)\s*(天|日|个月|月|年|亿元|万元|元)`)
+	feeRatePattern  = regexp.MustCompile(`([\d.]+)\s*%`)
+	feeFixedPattern = regexp.MustCompile(`每笔\s*([\d.]+)\s*元`)
+)
+
+// parseFeeRange 解析区间描述，如“大于等于100万元，小于500万元”，返回 [min, max)，max 为 0 表示无上限
+func parseFeeRange(text string) (min, max float64) {
+	for _, m := range feeBoundPattern.FindAllStringSubmatch(text, -1) {
+		v, err := strconv.ParseFloat(m[2], 64)
+		if err != nil {
+			continue
+		}
+		switch m[3] {
+		case "亿元":
+			v *= 100000000
+		case "万元":
+			v *= 10000
+		case "年":
+			v *= 365
+		case "个月", "月":
+			v *= 30
+		}
+
+		switch m[1] {
+		case "大于等于", "≥":
+			min = v
+		case "大于", ">":
+			min = v + 1
+		case "小于等于", "≤":
+			max = v + 1
+		case "小于", "<":
+			max = v
+		}
+	}
+	return min, max
+}
+
+// parseHoldingDays 解析持有期描述，如“大于等于7天，小于1年”
+func parseHoldingDays(text string) (minDays, maxDays int) {
+	min, max := parseFeeRange(text)
+	return int(min), int(max)
+}
+
+// parseFeeRate 解析费率单元格，支持“1.50%”和“每笔1000元”，多个费率时取最后一个（优惠后）
+func parseFeeRate(text string) (rate, fixed float64, ok bool) {
+	if m := feeFixedPattern.FindStringSubmatch(text); m != nil {
+		v, err := strconv.ParseFloat(m[1], 64)
+		return 0, v, err == nil
+	}
+	matches := feeRatePattern.FindAllStringSubmatch(text, -1)
+	if len(matches) == 0 {
+		return 0, 0, false
+	}
+	v, err := strconv.ParseFloat(matches[len(matches)-1][1], 64)
+	return v, 0, err == nil
+}
@@ -0,0 +1,211 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"margin/internal/model"
+	"margin/internal/repo"
+	"math"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 下滑路径的参照
+const (
+	GlideAnchorBirthYear  = "birth_year"  // 按年龄计算
+	GlideAnchorTargetDate = "target_date" // 按距目标日期的剩余年数计算
+)
+
+// 下滑路径公式
+const (
+	GlideFormulaAgeRule     = "age_rule"    // 基数 − 年龄，如 110 − 年龄
+	GlideFormulaLinear      = "linear"      // 从起始比例线性下降到最低比例
+	GlideFormulaBreakpoints = "breakpoints" // 自定义拐点，拐点之间线性插值
+)
+
+// GlidePath 随时间变化的目标股票比例。
+// 参照为出生年份时 X 为年龄；参照为目标日期时 X 为距目标日期的剩余年数
+type GlidePath struct {
+	Enabled     bool         `json:"enabled"`
+	Anchor      string       `json:"anchor"`
+	BirthYear   int          `json:"birth_year"`
+	TargetDate  string       `json:"target_date"` // 格式 2006-01-02
+	Formula     string       `json:"formula"`
+	AgeBase     float64      `json:"age_base"`    // age_rule 的基数，如 110
+	StartX      float64      `json:"start_x"`     // linear 的起点（年龄或剩余年数）
+	StartRatio  float64      `json:"start_ratio"` // linear 起点的股票比例
+	EndX        float64      `json:"end_x"`       // linear 的终点
+	FloorRatio  float64      `json:"floor_ratio"` // linear 终点（最低）的股票比例
+	Breakpoints []CurvePoint `json:"breakpoints"`
+}
+
+// DefaultGlidePath 默认为 110 − 年龄（关闭）
+var DefaultGlidePath = GlidePath{
+	Anchor:  GlideAnchorBirthYear,
+	Formula: GlideFormulaAgeRule,
+	AgeBase: 110,
+}
+
+// Validate 校验下滑路径
+func (g GlidePath) Validate() error {
+	switch g.Anchor {
+	case GlideAnchorBirthYear:
+		if g.BirthYear < 1900 || g.BirthYear > time.Now().Year() {
+			return errors.New("出生年份不正确")
+		}
+	case GlideAnchorTargetDate:
+		if _, err := time.Parse("2006-01-02", g.TargetDate); err != nil {
+			return fmt.Errorf("目标日期格式错误: %w", err)
+		}
+	default:
+		return fmt.Errorf("不支持的参照: %s", g.Anchor)
+	}
+
+	ratioOK := func(v float64) bool { return v >= 0 && v <= 100 }
+	switch g.Formula {
+	case GlideFormulaAgeRule:
+		if g.Anchor != GlideAnchorBirthYear {
+			return errors.New("“基数 − 年龄”公式需要设置出生年份")
+		}
+		if g.AgeBase <= 0 || g.AgeBase > 200 {
+			return errors.New("基数需在 1-200 之间")
+		}
+	case GlideFormulaLinear:
+		if g.StartX == g.EndX {
+			return errors.New("起点和终点不能相同")
+		}
+		if !ratioOK(g.StartRatio) || !ratioOK(g.FloorRatio) {
+			return errors.New("股票比例需在 0-100 之间")
+		}
+	case GlideFormulaBreakpoints:
+		if len(g.Breakpoints) < 2 {
+			return errors.New("请至少设置两个拐点")
+		}
+		for _, p := range g.Breakpoints {
+			if !ratioOK(p.Stock) {
+				return errors.New("股票比例需在 0-100 之间")
+			}
+		}
+	default:
+		return fmt.Errorf("不支持的公式: %s", g.Formula)
+	}
+	return nil
+}
+
+// position 返回 t 时刻的年龄或距目标日期的剩余年数
+func (g GlidePath) position(t time.Time) float64 {
+	const daysPerYear = 365.25
+	if g.Anchor == GlideAnchorTargetDate {
+		target, _ := time.ParseInLocation("2006-01-02", g.TargetDate, time.Local)
+		return math.Max(target.Sub(t).Hours()/24/daysPerYear, 0)
+	}
+	born := time.Date(g.BirthYear, time.January, 1, 0, 0, 0, 0, time.Local)
+	return t.Sub(born).Hours() / 24 / daysPerYear
+}
+
+// RatioAt 计算 t 时刻的目标股票比例
+func (g GlidePath) RatioAt(t time.Time) float64 {
+	x := g.position(t)
+
+	var ratio float64
+	switch g.Formula {
+	case GlideFormulaAgeRule:
+		ratio = g.AgeBase - x
+	case GlideFormulaLinear:
+		ratio = interpolateCurve(sortedCurve([]CurvePoint{
+			{X: g.StartX, Stock: g.StartRatio},
+			{X: g.EndX, Stock: g.FloorRatio},
+		}), x)
+	case GlideFormulaBreakpoints:
+		ratio = interpolateCurve(sortedCurve(g.Breakpoints), x)
+	}
+	return math.Min(math.Max(ratio, 0), 100)
+}
+
+// Describe 说明当前目标比例的来源
+func (g GlidePath) Describe(t time.Time) string {
+	x := g.position(t)
+	ratio := g.RatioAt(t)
+
+	where := fmt.Sprintf("当前年龄 %.1f 岁", x)
+	if g.Anchor == GlideAnchorTargetDate {
+		where = fmt.Sprintf("距目标日期 %s 还有 %.1f 年", g.TargetDate, x)
+	}
+
+	switch g.Formula {
+	case GlideFormulaAgeRule:
+		return fmt.Sprintf("%s，按 %.0f − 年龄，目标股票比例 %.1f%%", where, g.AgeBase, ratio)
+	case GlideFormulaLinear:
+		return fmt.Sprintf("%s，按线性下滑至最低 %.1f%%，目标股票比例 %.1f%%", where, g.FloorRatio, ratio)
+	default:
+		return fmt.Sprintf("%s，按自定义拐点，目标股票比例 %.1f%%", where, ratio)
+	}
+}
+
+// ToMap 转换为前端展示的结构
+func (g GlidePath) ToMap() map[string]interface{} {
+	breakpoints := make([]map[string]interface{}, 0, len(g.Breakpoints))
+	for _, p := range g.Breakpoints {
+		breakpoints = append(breakpoints, map[string]interface{}{"x": p.X, "stock": p.Stock})
+	}
+	result := map[string]interface{}{
+		"enabled":     g.Enabled,
+		"anchor":      g.Anchor,
+		"birth_year":  g.BirthYear,
+		"target_date": g.TargetDate,
+		"formula":     g.Formula,
+		"age_base":    g.AgeBase,
+		"start_x":     g.StartX,
+		"start_ratio": g.StartRatio,
+		"end_x":       g.EndX,
+		"floor_ratio": g.FloorRatio,
+		"breakpoints": breakpoints,
+	}
+	if g.Enabled {
+		now := time.Now()
+		result["current_ratio"] = g.RatioAt(now)
+		result["description"] = g.Describe(now)
+	}
+	return result
+}
+
+// Series 按月生成 [from, to] 之间的目标比例序列
+func (g GlidePath) Series(from, to time.Time) []map[string]interface{} {
+	var series []map[string]interface{}
+	t := time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, time.Local)
+	for !t.After(to) {
+		series = append(series, map[string]interface{}{
+			"date":               t.Format("2006-01-02"),
+			"target_stock_ratio": g.RatioAt(t),
+		})
+		t = t.AddDate(0, 1, 0)
+	}
+	return series
+}
+
+func sortedCurve(points []CurvePoint) []CurvePoint {
+	curve := append([]CurvePoint(nil), points...)
+	sort.Slice(curve, func(i, j int) bool { return curve[i].X < curve[j].X })
+	return curve
+}
+
+// loadGlidePath 读取下滑路径配置，未配置时返回默认配置（关闭）
+func loadGlidePath(ctx context.Context, configRepo *repo.ConfigRepository) (GlidePath, error) {
+	config, err := configRepo.Get(ctx, model.ConfigKeyGlidePath)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return DefaultGlidePath, nil
+	}
+	if err != nil {
+		return GlidePath{}, err
+	}
+
+	var g GlidePath
+	if err := json.Unmarshal([]byte(config.Value), &g); err != nil {
+		return GlidePath{}, fmt.Errorf("下滑路径配置格式错误: %w", err)
+	}
+	return g, nil
+}
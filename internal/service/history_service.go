@@ -2,11 +2,13 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"margin/internal/crypto"
 	"margin/internal/model"
 	"margin/internal/repo"
 	"strconv"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -28,54 +30,104 @@ func NewHistoryService(db *gorm.DB) *HistoryService {
 }
 
 func (s *HistoryService) SaveSnapshot(ctx context.Context) error {
-	assets, err := s.assetRepo.GetAll(ctx)
+	encryptKey, err := s.configRepo.Get(ctx, model.ConfigKeyEncryptKey)
 	if err != nil {
 		return err
 	}
 
-	encryptKey, err := s.configRepo.Get(ctx, model.ConfigKeyEncryptKey)
+	holdings, err := s.currentHoldings(ctx, encryptKey.Value)
 	if err != nil {
 		return err
 	}
+	return s.createSnapshot(ctx, holdings, encryptKey.Value)
+}
 
-	var stockTotal, bondTotal float64
+// snapshotHolding 当前持仓中的一个资产及其金额
+type snapshotHolding struct {
+	asset  model.Asset
+	amount float64
+}
+
+// currentHoldings 解密当前持仓的金额
+func (s *HistoryService) currentHoldings(ctx context.Context, key string) ([]snapshotHolding, error) {
+	assets, err := s.assetRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	holdings := make([]snapshotHolding, 0, len(assets))
 	for _, asset := range assets {
-		amountStr, err := crypto.Decrypt(asset.EncryptedAmount, encryptKey.Value)
+		amountStr, err := crypto.Decrypt(asset.EncryptedAmount, key)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		amount, _ := strconv.ParseFloat(amountStr, 64)
+		holdings = append(holdings, snapshotHolding{asset: asset, amount: amount})
+	}
+	return holdings, nil
+}
 
-		if asset.Type == model.AssetTypeStock {
-			stockTotal += amount
+// snapshotTotals 计算股票和债券总额
+func snapshotTotals(holdings []snapshotHolding) (float64, float64) {
+	var stockTotal, bondTotal float64
+	for _, h := range holdings {
+		if h.asset.Type == model.AssetTypeStock {
+			stockTotal += h.amount
 		} else {
-			bondTotal += amount
+			bondTotal += h.amount
 		}
 	}
+	return stockTotal, bondTotal
+}
 
+// createSnapshot 加密保存一条快照及其资产明细，总额为 0 时不保存
+func (s *HistoryService) createSnapshot(ctx context.Context, holdings []snapshotHolding, key string) error {
+	stockTotal, bondTotal := snapshotTotals(holdings)
 	total := stockTotal + bondTotal
 	if total == 0 {
 		return nil
 	}
 
-	encryptedStock, err := crypto.Encrypt(fmt.Sprintf("%.2f", stockTotal), encryptKey.Value)
+	encryptedStock, err := crypto.Encrypt(fmt.Sprintf("%.2f", stockTotal), key)
 	if err != nil {
 		return err
 	}
 
-	encryptedBond, err := crypto.Encrypt(fmt.Sprintf("%.2f", bondTotal), encryptKey.Value)
+	encryptedBond, err := crypto.Encrypt(fmt.Sprintf("%.2f", bondTotal), key)
 	if err != nil {
 		return err
 	}
 
-	history := &model.History{
-		EncryptedStockTotal: encryptedStock,
-		EncryptedBondTotal:  encryptedBond,
-		StockRatio:          stockTotal / total * 100,
-		BondRatio:           bondTotal / total * 100,
-	}
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		historyRepo := repo.NewHistoryRepository(tx)
+		history := &model.History{
+			EncryptedStockTotal: encryptedStock,
+			EncryptedBondTotal:  encryptedBond,
+			StockRatio:          stockTotal / total * 100,
+			BondRatio:           bondTotal / total * 100,
+		}
+		if err := historyRepo.Create(ctx, history); err != nil {
+			return err
+		}
 
-	return s.historyRepo.Create(ctx, history)
+		items := make([]model.HistoryItem, 0, len(holdings))
+		for _, h := range holdings {
+			encrypted, err := crypto.Encrypt(fmt.Sprintf("%.2f", h.amount), key)
+			if err != nil {
+				return err
+			}
+			items = append(items, model.HistoryItem{
+				HistoryID:       history.ID,
+				AssetID:         h.asset.ID,
+				Code:            h.asset.Code,
+				Name:            h.asset.Name,
+				Type:            h.asset.Type,
+				Source:          h.asset.Source,
+				EncryptedAmount: encrypted,
+			})
+		}
+		return historyRepo.CreateItems(ctx, items)
+	})
 }
 
 func (s *HistoryService) GetHistory(ctx context.Context) ([]map[string]interface{}, error) {
@@ -83,12 +135,49 @@ func (s *HistoryService) GetHistory(ctx context.Context) ([]map[string]interface
 	if err != nil {
 		return nil, err
 	}
+	return s.historiesToMaps(ctx, histories)
+}
+
+// QueryHistory 按时间区间查询快照，可按周期降采样（每个周期取最后一条）并分页，结果按时间倒序
+func (s *HistoryService) QueryHistory(ctx context.Context, q TimeQuery) (map[string]interface{}, error) {
+	r, err := q.timeRange()
+	if err != nil {
+		return nil, err
+	}
+
+	// 先只读取 ID 和时间完成筛选，只解密本页的记录
+	rows, err := s.historyRepo.ListTimes(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	ids, total := q.selectPage(rows)
 
+	histories, err := s.historyRepo.GetByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	items, err := s.historiesToMaps(ctx, histories)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"items": items,
+		"total": total,
+	}, nil
+}
+
+// historiesToMaps 解密快照并转换为前端展示的结构
+func (s *HistoryService) historiesToMaps(ctx context.Context, histories []model.History) ([]map[string]interface{}, error) {
 	encryptKey, err := s.configRepo.Get(ctx, model.ConfigKeyEncryptKey)
 	if err != nil {
 		return nil, err
 	}
 
+	glide, err := loadGlidePath(ctx, s.configRepo)
+	if err != nil {
+		return nil, err
+	}
+
 	result := make([]map[string]interface{}, 0, len(histories))
 	for _, h := range histories {
 		stockStr, err := crypto.Decrypt(h.EncryptedStockTotal, encryptKey.Value)
@@ -103,19 +192,201 @@ func (s *HistoryService) GetHistory(ctx context.Context) ([]map[string]interface
 		stockTotal, _ := strconv.ParseFloat(stockStr, 64)
 		bondTotal, _ := strconv.ParseFloat(bondStr, 64)
 
-		result = append(result, map[string]interface{}{
+		item := map[string]interface{}{
 			"id":          h.ID,
 			"stock_total": stockTotal,
 			"bond_total":  bondTotal,
 			"stock_ratio": h.StockRatio,
 			"bond_ratio":  h.BondRatio,
 			"created_at":  h.CreatedAt,
-		})
+		}
+		// 开启下滑路径时附带快照当时的目标比例，便于图表展示目标的变化
+		if glide.Enabled {
+			item["target_stock_ratio"] = glide.RatioAt(h.CreatedAt)
+		}
+		result = append(result, item)
 	}
 
 	return result, nil
 }
 
 func (s *HistoryService) DeleteHistory(ctx context.Context, id int64) error {
-	return s.historyRepo.Delete(ctx, id)
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		historyRepo := repo.NewHistoryRepository(tx)
+		if err := historyRepo.DeleteItems(ctx, uint(id)); err != nil {
+			return err
+		}
+		return historyRepo.Delete(ctx, id)
+	})
+}
+
+// GetGlidePathSeries 获取下滑路径的目标比例序列（按月），从最早的历史记录开始，向后延伸 years 年
+func (s *HistoryService) GetGlidePathSeries(ctx context.Context, years int) ([]map[string]interface{}, error) {
+	glide, err := loadGlidePath(ctx, s.configRepo)
+	if err != nil {
+		return nil, err
+	}
+	if !glide.Enabled {
+		return []map[string]interface{}{}, nil
+	}
+	if years < 0 || years > 100 {
+		return nil, errors.New("年数需在 0-100 之间")
+	}
+
+	now := time.Now()
+	from := now
+	histories, err := s.historyRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range histories {
+		if h.CreatedAt.Before(from) {
+			from = h.CreatedAt
+		}
+	}
+	return glide.Series(from, now.AddDate(years, 0, 0)), nil
+}
+
+// GetHistoryItems 获取快照的资产明细
+func (s *HistoryService) GetHistoryItems(ctx context.Context, id uint) ([]map[string]interface{}, error) {
+	encryptKey, err := s.configRepo.Get(ctx, model.ConfigKeyEncryptKey)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := s.historyRepo.GetItems(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]map[string]interface{}, 0, len(items))
+	for _, item := range items {
+		amount, err := decryptAmount(item.EncryptedAmount, encryptKey.Value)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, map[string]interface{}{
+			"id":       item.ID,
+			"asset_id": item.AssetID,
+			"code":     item.Code,
+			"name":     item.Name,
+			"type":     item.Type,
+			"source":   item.Source,
+			"amount":   amount,
+		})
+	}
+	return result, nil
+}
+
+// historyPosition 快照中按代码和来源汇总的一个持仓
+type historyPosition struct {
+	code, name, source, assetType string
+	amount                        float64
+}
+
+// loadPositions 读取快照及其资产明细，旧版本保存的快照没有明细时返回错误
+func (s *HistoryService) loadPositions(ctx context.Context, id uint, key string) (*model.History, map[string]*historyPosition, []string, error) {
+	history, err := s.historyRepo.Get(ctx, id)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("快照 #%d 不存在: %w", id, err)
+	}
+	items, err := s.historyRepo.GetItems(ctx, id)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if len(items) == 0 {
+		return nil, nil, nil, fmt.Errorf("快照 #%d 没有资产明细（旧版本保存的快照）", id)
+	}
+
+	positions := make(map[string]*historyPosition, len(items))
+	var order []string
+	for _, item := range items {
+		amount, err := decryptAmount(item.EncryptedAmount, key)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		k := item.Code + "|" + item.Source
+		if p, ok := positions[k]; ok {
+			p.amount += amount
+			continue
+		}
+		positions[k] = &historyPosition{code: item.Code, name: item.Name, source: item.Source, assetType: item.Type, amount: amount}
+		order = append(order, k)
+	}
+	return history, positions, order, nil
+}
+
+// DiffHistory 逐个资产比较两个快照（按代码和来源匹配），返回新增、清仓和金额变化的持仓
+func (s *HistoryService) DiffHistory(ctx context.Context, fromID, toID uint) (map[string]interface{}, error) {
+	encryptKey, err := s.configRepo.Get(ctx, model.ConfigKeyEncryptKey)
+	if err != nil {
+		return nil, err
+	}
+
+	fromHistory, from, fromOrder, err := s.loadPositions(ctx, fromID, encryptKey.Value)
+	if err != nil {
+		return nil, err
+	}
+	toHistory, to, toOrder, err := s.loadPositions(ctx, toID, encryptKey.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := func(p *historyPosition, fromAmount, toAmount float64) map[string]interface{} {
+		e := map[string]interface{}{
+			"code":        p.code,
+			"name":        p.name,
+			"source":      p.source,
+			"type":        p.assetType,
+			"from_amount": fromAmount,
+			"to_amount":   toAmount,
+			"change":      toAmount - fromAmount,
+		}
+		if fromAmount != 0 {
+			e["change_rate"] = (toAmount - fromAmount) / fromAmount * 100
+		}
+		return e
+	}
+
+	added := []map[string]interface{}{}
+	removed := []map[string]interface{}{}
+	changed := []map[string]interface{}{}
+	unchanged := 0
+	var fromTotal, toTotal float64
+	for _, k := range fromOrder {
+		p := from[k]
+		fromTotal += p.amount
+		next, ok := to[k]
+		switch {
+		case !ok:
+			removed = append(removed, entry(p, p.amount, 0))
+		case fmt.Sprintf("%.2f", p.amount) != fmt.Sprintf("%.2f", next.amount) || p.assetType != next.assetType:
+			e := entry(next, p.amount, next.amount)
+			e["from_type"] = p.assetType
+			changed = append(changed, e)
+		default:
+			unchanged++
+		}
+	}
+	for _, k := range toOrder {
+		p := to[k]
+		toTotal += p.amount
+		if _, ok := from[k]; !ok {
+			added = append(added, entry(p, 0, p.amount))
+		}
+	}
+
+	return map[string]interface{}{
+		"from_id":         fromHistory.ID,
+		"from_date":       fromHistory.CreatedAt,
+		"from_total":      fromTotal,
+		"to_id":           toHistory.ID,
+		"to_date":         toHistory.CreatedAt,
+		"to_total":        toTotal,
+		"total_change":    toTotal - fromTotal,
+		"added":           added,
+		"removed":         removed,
+		"changed":         changed,
+		"unchanged_count": unchanged,
+	}, nil
 }
@@ -0,0 +1,541 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"margin/internal/crypto"
+	"margin/internal/importer"
+	"margin/internal/model"
+	"margin/internal/repo"
+	"math"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+// 导入动作
+const (
+	ImportActionCreate    = "create"
+	ImportActionUpdate    = "update"
+	ImportActionUnchanged = "unchanged"
+	ImportActionConflict  = "conflict"
+	ImportActionError     = "error"
+)
+
+// ImportService 持仓导入服务
+type ImportService struct {
+	db          *gorm.DB
+	assetRepo   *repo.AssetRepository
+	configRepo  *repo.ConfigRepository
+	fundService *FundService
+}
+
+// importItem 导入计划中的一条记录
+type importItem struct {
+	importer.Record
+	URL       string
+	Action    string
+	Existing  *model.Asset
+	OldAmount float64
+	Changes   []string
+	Reason    string
+}
+
+func NewImportService(db *gorm.DB, fundService *FundService) *ImportService {
+	return &ImportService{
+		db:          db,
+		assetRepo:   repo.NewAssetRepository(db),
+		configRepo:  repo.NewConfigRepository(db),
+		fundService: fundService,
+	}
+}
+
+// GetColumns 读取文件表头，供前端选择列映射
+func (s *ImportService) GetColumns(ctx context.Context, path string) ([]string, error) {
+	table, err := importer.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return table.Headers, nil
+}
+
+// PreviewImport 预览导入结果（不写入数据库）
+func (s *ImportService) PreviewImport(ctx context.Context, path string, mapping importer.Mapping, defaultSource string) (map[string]interface{}, error) {
+	records, err := s.readRecords(path, mapping, defaultSource)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := s.plan(ctx, records)
+	if err != nil {
+		return nil, err
+	}
+
+	return importResult(items), nil
+}
+
+// ApplyImport 执行导入：在单个事务中创建和更新资产，冲突和错误行会被跳过
+func (s *ImportService) ApplyImport(ctx context.Context, path string, mapping importer.Mapping, defaultSource string) (map[string]interface{}, error) {
+	records, err := s.readRecords(path, mapping, defaultSource)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.ApplyRecords(ctx, records)
+}
+
+// ApplyRecords 在单个事务中写入已解析的持仓记录
+func (s *ImportService) ApplyRecords(ctx context.Context, records []importer.Record) (map[string]interface{}, error) {
+	return s.applyRecords(ctx, records, nil)
+}
+
+// applyRecords 写入持仓记录，onApplied 不为空时在同一事务中以写入计划回调
+func (s *ImportService) applyRecords(ctx context.Context, records []importer.Record, onApplied func(tx *gorm.DB, items []*importItem) error) (map[string]interface{}, error) {
+	items, err := s.plan(ctx, records)
+	if err != nil {
+		return nil, err
+	}
+
+	encryptKey, err := s.configRepo.Get(ctx, model.ConfigKeyEncryptKey)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		assetRepo := repo.NewAssetRepository(tx)
+		sourceRepo := repo.NewSourceRepository(tx)
+
+		for _, item := range items {
+			if item.Action != ImportActionCreate && item.Action != ImportActionUpdate {
+				continue
+			}
+
+			if err := ensureSource(ctx, sourceRepo, item.Source); err != nil {
+				return err
+			}
+
+			encryptedAmount, err := crypto.Encrypt(fmt.Sprintf("%.2f", item.Amount), encryptKey.Value)
+			if err != nil {
+				return err
+			}
+
+			if item.Action == ImportActionUpdate {
+				asset := item.Existing
+				asset.Name = item.Name
+				asset.Type = item.Type
+				if asset.URL == "" {
+					asset.URL = item.URL
+				}
+				asset.EncryptedAmount = encryptedAmount
+				if err := assetRepo.Update(ctx, asset); err != nil {
+					return fmt.Errorf("第 %d 行更新失败: %w", item.Row, err)
+				}
+				continue
+			}
+
+			asset := &model.Asset{
+				Code:            item.Code,
+				Name:            item.Name,
+				URL:             item.URL,
+				Type:            item.Type,
+				Source:          item.Source,
+				EncryptedAmount: encryptedAmount,
+			}
+			if err := assetRepo.Create(ctx, asset); err != nil {
+				return fmt.Errorf("第 %d 行创建失败: %w", item.Row, err)
+			}
+		}
+		if onApplied != nil {
+			return onApplied(tx, items)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return importResult(items), nil
+}
+
+// GetStatementParsers 获取支持的平台账单解析器
+func (s *ImportService) GetStatementParsers(ctx context.Context) []map[string]interface{} {
+	parsers := importer.Parsers()
+	result := make([]map[string]interface{}, 0, len(parsers))
+	for _, p := range parsers {
+		result = append(result, map[string]interface{}{
+			"name":   p.Name(),
+			"source": p.Source(),
+		})
+	}
+	return result
+}
+
+// PreviewStatement 预览平台账单导入，parserName 为空时自动识别平台
+func (s *ImportService) PreviewStatement(ctx context.Context, path, parserName string) (map[string]interface{}, error) {
+	read, err := s.readStatement(ctx, path, parserName)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := s.plan(ctx, read.records)
+	if err != nil {
+		return nil, err
+	}
+
+	result := importResult(items)
+	result["statement"] = read.info()
+	return result, nil
+}
+
+// ApplyStatement 导入平台账单：持仓账单直接覆盖金额，交易流水在现有持仓上累加净买入。
+// 之前导入过的交易会被跳过，同一份流水重复导入或按月导出的区间重叠时不会重复计入
+func (s *ImportService) ApplyStatement(ctx context.Context, path, parserName string) (map[string]interface{}, error) {
+	read, err := s.readStatement(ctx, path, parserName)
+	if err != nil {
+		return nil, err
+	}
+
+	// 只记录成功写入的基金的交易，冲突或出错的基金下次导入时仍会计入
+	onApplied := func(tx *gorm.DB, items []*importItem) error {
+		var imported []model.ImportedTransaction
+		for _, item := range items {
+			if item.Action == ImportActionError || item.Action == ImportActionConflict {
+				continue
+			}
+			for _, hash := range read.hashes[item.Code] {
+				imported = append(imported, model.ImportedTransaction{Hash: hash, Source: item.Source, Code: item.Code})
+			}
+		}
+		return repo.NewImportedTransactionRepository(tx).Create(ctx, imported)
+	}
+
+	result, err := s.applyRecords(ctx, read.records, onApplied)
+	if err != nil {
+		return nil, err
+	}
+	result["statement"] = read.info()
+	return result, nil
+}
+
+// statementRead 解析后的平台账单
+type statementRead struct {
+	statement *importer.Statement
+	records   []importer.Record
+	hashes    map[string][]string // 基金代码 → 本次计入的交易哈希
+	skipped   int                 // 之前已导入而跳过的交易数
+}
+
+func (r *statementRead) info() map[string]interface{} {
+	info := statementInfo(r.statement)
+	info["skipped_transactions"] = r.skipped
+	return info
+}
+
+// readStatement 解析平台账单并转换为持仓记录
+func (s *ImportService) readStatement(ctx context.Context, path, parserName string) (*statementRead, error) {
+	doc, err := importer.LoadDocument(path)
+	if err != nil {
+		return nil, err
+	}
+
+	statement, err := importer.ParseStatement(doc, parserName)
+	if err != nil {
+		return nil, err
+	}
+
+	if statement.Kind == importer.StatementHoldings {
+		return &statementRead{statement: statement, records: statement.Holdings}, nil
+	}
+	return s.applyTransactions(ctx, statement)
+}
+
+// transactionHash 交易的唯一标识。同一文件中内容完全相同的交易按出现次序区分，
+// 因此重叠的导出文件中同一笔交易得到相同的哈希
+func transactionHash(parser string, t importer.Transaction, occurrence int) string {
+	key := fmt.Sprintf("%s|%s|%s|%s|%.2f|%.2f|%d", parser, t.Date.Format("2006-01-02 15:04:05"), t.Code, t.Action, t.Amount, t.Fee, occurrence)
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// applyTransactions 将尚未导入过的交易按基金汇总，叠加到该来源现有持仓金额上
+func (s *ImportService) applyTransactions(ctx context.Context, statement *importer.Statement) (*statementRead, error) {
+	encryptKey, err := s.configRepo.Get(ctx, model.ConfigKeyEncryptKey)
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := make([]string, len(statement.Transactions))
+	occurrences := make(map[string]int)
+	for i, t := range statement.Transactions {
+		first := transactionHash(statement.Parser, t, 0)
+		hashes[i] = transactionHash(statement.Parser, t, occurrences[first])
+		occurrences[first]++
+	}
+	imported, err := repo.NewImportedTransactionRepository(s.db).Existing(ctx, hashes)
+	if err != nil {
+		return nil, err
+	}
+
+	read := &statementRead{statement: statement, hashes: make(map[string][]string)}
+	var records []importer.Record
+	index := make(map[string]int)
+	for n, t := range statement.Transactions {
+		if imported[hashes[n]] {
+			read.skipped++
+			continue
+		}
+		read.hashes[t.Code] = append(read.hashes[t.Code], hashes[n])
+
+		i, ok := index[t.Code]
+		if !ok {
+			record := importer.Record{
+				Row:    t.Row,
+				Code:   t.Code,
+				Name:   t.Name,
+				Source: statement.Source,
+				Type:   importer.NormalizeAssetType(t.Name),
+			}
+
+			asset, err := s.assetRepo.GetByCodeAndSource(ctx, t.Code, statement.Source)
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, err
+			}
+			if asset != nil {
+				amountStr, err := crypto.Decrypt(asset.EncryptedAmount, encryptKey.Value)
+				if err != nil {
+					return nil, err
+				}
+				record.Amount, _ = strconv.ParseFloat(amountStr, 64)
+			}
+
+			records = append(records, record)
+			i = len(records) - 1
+			index[t.Code] = i
+		}
+
+		// 买入按扣除手续费后的净额计入；卖出的确认金额是赎回的总额，手续费从到账金额中扣除，持仓只减少确认金额
+		if t.Action == importer.ActionBuy {
+			records[i].Amount += t.Amount - t.Fee
+		} else {
+			records[i].Amount -= t.Amount
+		}
+	}
+
+	for i := range records {
+		if records[i].Amount < 0 {
+			records[i].Amount = 0
+		}
+	}
+
+	read.records = records
+	return read, nil
+}
+
+func (s *ImportService) readRecords(path string, mapping importer.Mapping, defaultSource string) ([]importer.Record, error) {
+	table, err := importer.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return mapping.Apply(table, defaultSource)
+}
+
+// plan 计算导入计划：补全名称/类型，识别新增、更新和冲突
+func (s *ImportService) plan(ctx context.Context, records []importer.Record) ([]*importItem, error) {
+	assets, err := s.assetRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	encryptKey, err := s.configRepo.Get(ctx, model.ConfigKeyEncryptKey)
+	if err != nil {
+		return nil, err
+	}
+
+	existing := make(map[string]*model.Asset, len(assets))
+	for i := range assets {
+		existing[assetKey(assets[i].Code, assets[i].Source)] = &assets[i]
+	}
+
+	// 同一文件内 代码+来源 重复的行视为冲突
+	counts := make(map[string]int, len(records))
+	for _, r := range records {
+		if r.Error == "" {
+			counts[assetKey(r.Code, r.Source)]++
+		}
+	}
+
+	fundInfos := make(map[string]*FundInfo)
+	items := make([]*importItem, 0, len(records))
+	for _, r := range records {
+		item := &importItem{Record: r}
+		items = append(items, item)
+
+		if r.Error != "" {
+			item.Action = ImportActionError
+			item.Reason = r.Error
+			continue
+		}
+
+		key := assetKey(r.Code, r.Source)
+		if counts[key] > 1 {
+			item.Action = ImportActionConflict
+			item.Reason = fmt.Sprintf("文件中基金 %s 在来源\"%s\"下出现 %d 次", r.Code, r.Source, counts[key])
+			continue
+		}
+
+		if err := s.enrich(ctx, item, existing[key], fundInfos); err != nil {
+			item.Action = ImportActionError
+			item.Reason = err.Error()
+			continue
+		}
+
+		asset, ok := existing[key]
+		if !ok {
+			item.Action = ImportActionCreate
+			continue
+		}
+
+		amountStr, err := crypto.Decrypt(asset.EncryptedAmount, encryptKey.Value)
+		if err != nil {
+			return nil, err
+		}
+		item.OldAmount, _ = strconv.ParseFloat(amountStr, 64)
+		item.Existing = asset
+
+		if asset.Name != item.Name {
+			item.Changes = append(item.Changes, "name")
+		}
+		if asset.Type != item.Type {
+			item.Changes = append(item.Changes, "type")
+		}
+		if math.Abs(item.OldAmount-item.Amount) >= 0.005 {
+			item.Changes = append(item.Changes, "amount")
+		}
+
+		if len(item.Changes) == 0 {
+			item.Action = ImportActionUnchanged
+		} else {
+			item.Action = ImportActionUpdate
+		}
+	}
+
+	return items, nil
+}
+
+// enrich 补全缺失的名称和类型：优先使用已有资产，其次查询基金信息
+func (s *ImportService) enrich(ctx context.Context, item *importItem, asset *model.Asset, cache map[string]*FundInfo) error {
+	if asset != nil {
+		if item.Name == "" {
+			item.Name = asset.Name
+		}
+		if item.Type == "" {
+			item.Type = asset.Type
+		}
+	}
+
+	if item.Name == "" || item.Type == "" {
+		info, ok := cache[item.Code]
+		if !ok {
+			var err error
+			info, err = s.fundService.GetFundInfo(ctx, item.Code)
+			if err != nil {
+				info = nil
+			}
+			cache[item.Code] = info
+		}
+		if info != nil {
+			if item.Name == "" {
+				item.Name = info.Name
+			}
+			if item.Type == "" {
+				item.Type = importer.NormalizeAssetType(info.Type)
+			}
+			item.URL = info.URL
+		}
+	}
+
+	if item.Name == "" {
+		return errors.New("无法获取基金名称")
+	}
+	if item.Type == "" {
+		return errors.New("无法识别资产类型，请在文件中指定股票/债券")
+	}
+	if item.URL == "" {
+		item.URL = FundURL(item.Code)
+	}
+	return nil
+}
+
+func statementInfo(statement *importer.Statement) map[string]interface{} {
+	return map[string]interface{}{
+		"parser":       statement.Parser,
+		"source":       statement.Source,
+		"kind":         statement.Kind,
+		"holdings":     len(statement.Holdings),
+		"transactions": len(statement.Transactions),
+	}
+}
+
+// ensureSource 确保来源存在
+func ensureSource(ctx context.Context, sourceRepo *repo.SourceRepository, name string) error {
+	_, err := sourceRepo.GetByName(ctx, name)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	return sourceRepo.Create(ctx, &model.Source{Name: name})
+}
+
+func assetKey(code, source string) string {
+	return code + "\x00" + source
+}
+
+// importResult 将导入计划转换为前端展示的结构
+func importResult(items []*importItem) map[string]interface{} {
+	groups := map[string][]map[string]interface{}{
+		ImportActionCreate:    {},
+		ImportActionUpdate:    {},
+		ImportActionUnchanged: {},
+		ImportActionConflict:  {},
+		ImportActionError:     {},
+	}
+
+	for _, item := range items {
+		entry := map[string]interface{}{
+			"row":    item.Row,
+			"code":   item.Code,
+			"name":   item.Name,
+			"source": item.Source,
+			"type":   item.Type,
+			"amount": item.Amount,
+		}
+		if item.Existing != nil {
+			entry["id"] = item.Existing.ID
+			entry["old_amount"] = item.OldAmount
+			entry["changes"] = item.Changes
+		}
+		if item.Reason != "" {
+			entry["reason"] = item.Reason
+		}
+		groups[item.Action] = append(groups[item.Action], entry)
+	}
+
+	summary := make(map[string]int, len(groups))
+	for action, entries := range groups {
+		summary[action] = len(entries)
+	}
+
+	return map[string]interface{}{
+		"creates":   groups[ImportActionCreate],
+		"updates":   groups[ImportActionUpdate],
+		"unchanged": groups[ImportActionUnchanged],
+		"conflicts": groups[ImportActionConflict],
+		"errors":    groups[ImportActionError],
+		"summary":   summary,
+	}
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestApplyStatementSkipsImportedTransactions(t *testing.T) {
+	g := newTestDB(t)
+	ctx := context.Background()
+	assets := NewAssetService(g)
+	imports := NewImportService(g, NewFundService())
+
+	// 资产已存在时名称和类型取自资产，不需要联网查询基金信息
+	if err := assets.SaveAsset(ctx, "110020", "易方达沪深300ETF联接A", "", "stock", "支付宝", 10000); err != nil {
+		t.Fatal(err)
+	}
+	if err := assets.SaveAsset(ctx, "217022", "招商产业债券A", "", "bond", "支付宝", 5000); err != nil {
+		t.Fatal(err)
+	}
+
+	amounts := func() map[string]float64 {
+		holdings, err := assets.LoadHoldings(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result := make(map[string]float64)
+		for _, h := range holdings {
+			result[h.Asset.Code] = h.Amount
+		}
+		return result
+	}
+
+	statement := filepath.Join("..", "importer", "testdata", "alipay_transactions.csv")
+	// 买入扣除手续费后计入；卖出的确认金额已包含手续费，持仓只减少确认金额
+	want := map[string]float64{"110020": 10000 + 1000 - 1.2, "217022": 5000 - 500}
+	for run := 1; run <= 2; run++ {
+		result, err := imports.ApplyStatement(ctx, statement, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		skipped := result["statement"].(map[string]interface{})["skipped_transactions"]
+		if wantSkipped := (run - 1) * 2; skipped != wantSkipped {
+			t.Errorf("run %d: skipped %v transactions, want %d", run, skipped, wantSkipped)
+		}
+		for code, amount := range amounts() {
+			if math.Abs(amount-want[code]) > 0.005 {
+				t.Errorf("run %d: %s amount = %.2f, want %.2f", run, code, amount, want[code])
+			}
+		}
+	}
+
+	// 与上一份导出重叠的下个月流水只计入新增的交易
+	data, err := os.ReadFile(statement)
+	if err != nil {
+		t.Fatal(err)
+	}
+	next := filepath.Join(t.TempDir(), "alipay_next.csv")
+	data = append(data, []byte("2024-06-03 09:30:00,易方达沪深300ETF联接A,110020,买入,200.00,0.24,交易成功\n")...)
+	if err := os.WriteFile(next, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := imports.ApplyStatement(ctx, next, ""); err != nil {
+		t.Fatal(err)
+	}
+	want["110020"] += 200 - 0.24
+	for code, amount := range amounts() {
+		if math.Abs(amount-want[code]) > 0.005 {
+			t.Errorf("overlapping export: %s amount = %.2f, want %.2f", code, amount, want[code])
+		}
+	}
+}
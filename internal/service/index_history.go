@@ -0,0 +1,308 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"margin/internal/model"
+	"margin/internal/repo"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const klineURL = "https://push2his.eastmoney.com/api/qt/stock/kline/get"
+
+// indexHistoryTTL 同一指数两次同步的最小间隔
+const indexHistoryTTL = 6 * time.Hour
+
+// IndexHistoryService 指数日线的下载与本地缓存。首次使用时下载全部历史，之后只增量补齐
+type IndexHistoryService struct {
+	client    *http.Client
+	priceRepo *repo.IndexPriceRepository
+	defRepo   *repo.IndexDefRepository
+
+	mu      sync.Mutex
+	synced  map[string]time.Time   // 最近一次同步成功的时间
+	failed  map[string]string      // 最近一次同步失败的原因，成功后清除
+	syncing map[string]*sync.Mutex // 每个指数的同步锁，同一指数同时只有一次下载
+}
+
+func NewIndexHistoryService(db *gorm.DB) *IndexHistoryService {
+	return &IndexHistoryService{
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		priceRepo: repo.NewIndexPriceRepository(db),
+		defRepo:   repo.NewIndexDefRepository(db),
+		synced:    make(map[string]time.Time),
+		failed:    make(map[string]string),
+		syncing:   make(map[string]*sync.Mutex),
+	}
+}
+
+// UseTransport 替换下载使用的传输层，用于回放或录制行情数据
+func (s *IndexHistoryService) UseTransport(rt http.RoundTripper) {
+	s.client.Transport = rt
+}
+
+// Prices 获取指数在 [from, to] 之间的日线（格式 2006-01-02，空表示不限）。
+// 下载失败但本地已有缓存时使用缓存数据
+func (s *IndexHistoryService) Prices(ctx context.Context, code, from, to string) ([]model.IndexPrice, error) {
+	if err := s.sync(ctx, code); err != nil {
+		latest, cacheErr := s.priceRepo.GetLatest(ctx, code)
+		if cacheErr != nil || latest == nil {
+			return nil, fmt.Errorf("下载指数 %s 日线失败: %w", code, err)
+		}
+	}
+	return s.priceRepo.GetRange(ctx, code, from, to)
+}
+
+// Refresh 忽略同步间隔，立即增量下载指数日线
+func (s *IndexHistoryService) Refresh(ctx context.Context, code string) error {
+	s.mu.Lock()
+	delete(s.synced, code)
+	s.mu.Unlock()
+	return s.sync(ctx, code)
+}
+
+// Series 获取指数日线序列用于绘图。下载失败时使用本地缓存，并标记为离线数据
+func (s *IndexHistoryService) Series(ctx context.Context, code, from, to string) (map[string]interface{}, error) {
+	prices, err := s.Prices(ctx, code, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	dates := make([]string, len(prices))
+	closes := make([]float64, len(prices))
+	for i, p := range prices {
+		dates[i] = p.Date
+		closes[i] = p.Close
+	}
+
+	def, err := s.resolve(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	result := map[string]interface{}{
+		"code":   code,
+		"name":   def.Name,
+		"dates":  dates,
+		"closes": closes,
+	}
+	s.mu.Lock()
+	if reason, ok := s.failed[code]; ok {
+		result["offline"] = true
+		result["error"] = reason
+	}
+	s.mu.Unlock()
+	return result, nil
+}
+
+// Status 各指数日线缓存的概况和最近一次同步情况
+func (s *IndexHistoryService) Status(ctx context.Context) ([]map[string]interface{}, error) {
+	stats, err := s.priceRepo.Stats(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byCode := make(map[string]repo.IndexPriceStats, len(stats))
+	for _, st := range stats {
+		byCode[st.Code] = st
+	}
+
+	defs, err := s.Indexes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result := make([]map[string]interface{}, 0, len(defs))
+	for _, def := range defs {
+		st := byCode[def.Code]
+		item := map[string]interface{}{
+			"code":  def.Code,
+			"name":  def.Name,
+			"count": st.Count,
+			"first": st.First,
+			"last":  st.Last,
+		}
+		if at, ok := s.synced[def.Code]; ok {
+			item["synced_at"] = at.Format("2006-01-02 15:04:05")
+		}
+		if reason, ok := s.failed[def.Code]; ok {
+			item["error"] = reason
+		}
+		result = append(result, item)
+	}
+	return result, nil
+}
+
+// sync 增量下载指数日线到本地缓存，同一指数在同步间隔内只下载一次
+func (s *IndexHistoryService) sync(ctx context.Context, code string) error {
+	def, err := s.resolve(ctx, code)
+	if err != nil {
+		return err
+	}
+
+	// 只在读写状态时持有 s.mu，下载期间其他指数的同步和状态查询不受影响
+	s.mu.Lock()
+	lock, ok := s.syncing[code]
+	if !ok {
+		lock = &sync.Mutex{}
+		s.syncing[code] = lock
+	}
+	s.mu.Unlock()
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	s.mu.Lock()
+	fresh := time.Since(s.synced[code]) < indexHistoryTTL
+	s.mu.Unlock()
+	if fresh {
+		return nil
+	}
+
+	err = s.download(ctx, code, def.SecID)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		s.failed[code] = err.Error()
+		return err
+	}
+	delete(s.failed, code)
+	s.synced[code] = time.Now()
+	return nil
+}
+
+// resolve 查找指数的名称和 secid，先查用户添加的指数，再查默认和预置指数
+func (s *IndexHistoryService) resolve(ctx context.Context, code string) (model.IndexDef, error) {
+	def, err := s.defRepo.GetByCode(ctx, code)
+	if err == nil {
+		return *def, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return model.IndexDef{}, err
+	}
+	if preset, ok := model.FindIndexPreset(code); ok {
+		return preset, nil
+	}
+	return model.IndexDef{}, fmt.Errorf("不支持的指数: %s", code)
+}
+
+// Indexes 可下载日线的指数：先是用户添加的指数（按显示顺序），再是尚未添加的默认和预置指数
+func (s *IndexHistoryService) Indexes(ctx context.Context) ([]model.IndexDef, error) {
+	defs, err := s.defRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	added := make(map[string]bool, len(defs))
+	for _, d := range defs {
+		added[d.Code] = true
+	}
+	for _, presets := range [][]model.IndexDef{model.DefaultIndexes, model.IndexPresets} {
+		for _, p := range presets {
+			if !added[p.Code] {
+				added[p.Code] = true
+				defs = append(defs, p)
+			}
+		}
+	}
+	return defs, nil
+}
+
+// download 从缓存的最新交易日开始下载（含当日，以更新盘中写入的收盘价），没有缓存时下载全部历史
+func (s *IndexHistoryService) download(ctx context.Context, code, secID string) error {
+	begin := "19900101"
+	latest, err := s.priceRepo.GetLatest(ctx, code)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if latest != nil {
+		begin = strings.ReplaceAll(latest.Date, "-", "")
+	}
+
+	prices, err := s.fetchKline(ctx, code, secID, begin)
+	if err != nil {
+		return err
+	}
+	return s.priceRepo.Upsert(ctx, prices)
+}
+
+// fetchKline 请求东方财富日线接口，返回 begin（YYYYMMDD）之后的收盘价
+func (s *IndexHistoryService) fetchKline(ctx context.Context, code, secID, begin string) ([]model.IndexPrice, error) {
+	params := url.Values{
+		"secid":   {secID},
+		"fields1": {"f1,f2,f3"},
+		"fields2": {"f51,f53"}, // 日期、收盘
+		"klt":     {"101"},     // 日线
+		"fqt":     {"0"},
+		"beg":     {begin},
+		"end":     {"20500101"},
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", klineURL+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36")
+	req.Header.Set("Referer", "https://quote.eastmoney.com/")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP status: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return parseKlines(body, code)
+}
+
+// parseKlines 解析日线接口响应
+// 返回格式: {"rc":0,"data":{"code":"000300","name":"沪深300","klines":["2005-01-04,982.79",...]}}
+func parseKlines(body []byte, code string) ([]model.IndexPrice, error) {
+	var payload struct {
+		RC   int `json:"rc"`
+		Data *struct {
+			Klines []string `json:"klines"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("解析响应失败: %w", err)
+	}
+	if payload.RC != 0 || payload.Data == nil {
+		return nil, fmt.Errorf("接口返回错误: rc=%d", payload.RC)
+	}
+
+	prices := make([]model.IndexPrice, 0, len(payload.Data.Klines))
+	for _, line := range payload.Data.Klines {
+		fields := strings.Split(line, ",")
+		if len(fields) < 2 {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", fields[0]); err != nil {
+			continue
+		}
+		closePrice, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil || closePrice <= 0 {
+			continue
+		}
+		prices = append(prices, model.IndexPrice{Code: code, Date: fields[0], Close: closePrice})
+	}
+	return prices, nil
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"margin/internal/model"
+	"margin/internal/repo"
+)
+
+// countingTransport 统计经过的请求数
+type countingTransport struct {
+	next     http.RoundTripper
+	requests []string
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.requests = append(t.requests, req.URL.Query().Get("secid")+" beg="+req.URL.Query().Get("beg"))
+	return t.next.RoundTrip(req)
+}
+
+func TestIndexHistoryReplay(t *testing.T) {
+	ctx := context.Background()
+	s := NewIndexHistoryService(newTestDB(t))
+	transport := &countingTransport{next: NewFixtureTransport(filepath.Join("testdata", "push2his"), false)}
+	s.UseTransport(transport)
+
+	// 首次使用下载全部历史
+	prices, err := s.Prices(ctx, "000300", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prices) != 5 || prices[0].Date != "2024-05-06" || prices[4].Date != "2024-05-10" || prices[4].Close != 3665.01 {
+		t.Fatalf("got prices %+v", prices)
+	}
+
+	// 同步间隔内不再请求
+	if _, err := s.Prices(ctx, "000300", "2024-05-08", ""); err != nil {
+		t.Fatal(err)
+	}
+	if len(transport.requests) != 1 {
+		t.Fatalf("got requests %v, want one full download", transport.requests)
+	}
+
+	// 刷新从缓存的最新交易日开始增量下载，并更新当日收盘价
+	if err := s.Refresh(ctx, "000300"); err != nil {
+		t.Fatal(err)
+	}
+	if got := transport.requests[len(transport.requests)-1]; got != "1.000300 beg=20240510" {
+		t.Fatalf("refresh requested %s", got)
+	}
+	prices, err = s.Prices(ctx, "000300", "2024-05-10", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prices) != 2 || prices[0].Close != 3666.02 || prices[1].Date != "2024-05-13" {
+		t.Fatalf("got prices after refresh %+v", prices)
+	}
+
+	// 没有回放数据的指数下载失败，状态中记录原因
+	if _, err := s.Prices(ctx, "000012", "", ""); err == nil {
+		t.Fatal("expected error for index without fixture")
+	}
+
+	status, err := s.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byCode := map[string]map[string]interface{}{}
+	for _, item := range status {
+		byCode[item["code"].(string)] = item
+	}
+	hs300 := byCode["000300"]
+	if hs300["count"] != 6 || hs300["first"] != "2024-05-06" || hs300["last"] != "2024-05-13" ||
+		hs300["synced_at"] == nil || hs300["error"] != nil {
+		t.Errorf("000300 status = %v", hs300)
+	}
+	if bond := byCode["000012"]; bond["count"] != 0 || bond["error"] == nil {
+		t.Errorf("000012 status = %v", bond)
+	}
+}
+
+func TestFixtureTransportRecordKeepsFullHistory(t *testing.T) {
+	dir := t.TempDir()
+	upstream := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		body := `{"rc":0,"data":{"klines":["beg ` + req.URL.Query().Get("beg") + `"]}}`
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body)), Request: req}, nil
+	})
+	client := &http.Client{Transport: &FixtureTransport{Dir: dir, Record: true, Next: upstream}}
+
+	for _, begin := range []string{"19900101", "20240510"} {
+		resp, err := client.Get(klineURL + "?secid=1.000300&beg=" + begin)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+
+	for _, begin := range []string{"19900101", "20240510"} {
+		body, err := os.ReadFile(filepath.Join(dir, "push2his.eastmoney.com_1.000300_"+begin+".json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(body), "beg "+begin) {
+			t.Errorf("fixture for beg=%s contains %s", begin, body)
+		}
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestIndexHistoryIncludesUserIndexes(t *testing.T) {
+	ctx := context.Background()
+	database := newTestDB(t)
+	s := NewIndexHistoryService(database)
+	if err := repo.NewIndexDefRepository(database).Create(ctx, &model.IndexDef{
+		Code: "931151", Name: "光伏产业", SecID: "2.931151", Market: model.MarketCN, Currency: "CNY",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	def, err := s.resolve(ctx, "931151")
+	if err != nil || def.SecID != "2.931151" {
+		t.Fatalf("resolve user index = %+v, %v", def, err)
+	}
+	// 未添加的预置指数也可以下载
+	if def, err := s.resolve(ctx, "000012"); err != nil || def.Name != "国债指数" {
+		t.Fatalf("resolve preset = %+v, %v", def, err)
+	}
+	if _, err := s.resolve(ctx, "999999"); err == nil {
+		t.Fatal("expected error for unknown index")
+	}
+
+	status, err := s.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(status) == 0 || status[0]["code"] != "931151" || status[0]["name"] != "光伏产业" {
+		t.Fatalf("status does not start with the user index: %v", status)
+	}
+	indexes, err := NewBenchmarkService(database, s).BenchmarkIndexes(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(indexes) != len(status) || indexes[0]["code"] != "931151" {
+		t.Errorf("benchmark indexes = %v", indexes)
+	}
+}
@@ -2,115 +2,424 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"margin/internal/model"
+	"margin/internal/repo"
 	"net/http"
+	"net/url"
 	"regexp"
-	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"gorm.io/gorm"
 )
 
 type IndexService struct {
-	db *gorm.DB
+	db        *gorm.DB
+	defRepo   *repo.IndexDefRepository
+	quoteRepo *repo.IndexQuoteRepository
+	calendar  *CalendarService
+	client    *http.Client
+
+	mu    sync.Mutex
+	cache map[string]IndexData // 最近一次获取成功的行情
 }
 
 type IndexData struct {
-	Code       string  `json:"code"`        // 指数代码
-	Name       string  `json:"name"`        // 指数名称
-	Price      float64 `json:"price"`       // 当前点位
-	Change     float64 `json:"change"`      // 涨跌点
-	ChangeRate float64 `json:"change_rate"` // 涨跌幅(%)
-	UpdateTime string  `json:"update_time"` // 更新时间
+	Code        string    `json:"code"`         // 指数代码
+	Name        string    `json:"name"`         // 指数名称
+	Price       float64   `json:"price"`        // 当前点位
+	Change      float64   `json:"change"`       // 涨跌点
+	ChangeRate  float64   `json:"change_rate"`  // 涨跌幅(%)
+	Open        float64   `json:"open"`         // 今开
+	High        float64   `json:"high"`         // 最高
+	Low         float64   `json:"low"`          // 最低
+	PrevClose   float64   `json:"prev_close"`   // 昨收
+	Volume      float64   `json:"volume"`       // 成交量
+	Amount      float64   `json:"amount"`       // 成交额
+	UpdateTime  string    `json:"update_time"`  // 行情时间，有交易所时间戳时为交易所时间
+	Status      string    `json:"status"`       // 行情状态，见 QuoteStatus*
+	Error       string    `json:"error"`        // 获取失败的原因
+	MarketOpen  bool      `json:"market_open"`  // 所在市场是否处于交易时段
+	MarketState string    `json:"market_state"` // 所在市场的交易状态，见 MarketState*
+	QuoteTime   time.Time `json:"-"`            // 交易所行情时间，接口没有返回时为零值
+	FetchedAt   time.Time `json:"-"`            // 获取时间
 }
 
-func NewIndexService(db *gorm.DB) *IndexService {
+// 行情状态
+const (
+	QuoteStatusOK    = "ok"    // 有效期内的行情
+	QuoteStatusStale = "stale" // 获取失败，显示上次的行情
+	QuoteStatusError = "error" // 获取失败且没有缓存
+)
+
+// quoteTimeout 一次刷新所有指数的最长等待时间
+const quoteTimeout = 8 * time.Second
+
+// 行情缓存有效期：交易时段内行情持续变化；休市后收盘价稳定下来，获取一次即可
+const (
+	quoteTTLOpen     = time.Minute
+	quoteTTLClosed   = 30 * time.Minute // 无法判断市场状态时使用
+	quoteSettleDelay = 15 * time.Minute // 收盘后行情定格（收盘集合竞价、数据校正）所需时间
+)
+
+// quoteURL 东方财富实时行情接口
+const quoteURL = "https://push2.eastmoney.com/api/qt/stock/get"
+
+// quoteFields 请求的行情字段
+const quoteFields = "f43,f44,f45,f46,f47,f48,f49,f50,f51,f52,f57,f58,f60,f86,f107,f152,f162,f169,f170,f171"
+
+var secIDPattern = regexp.MustCompile(`^\d+\.[0-9A-Za-z]+$`)
+
+func NewIndexService(db *gorm.DB, calendar *CalendarService) *IndexService {
 	return &IndexService{
-		db: db,
+		db:        db,
+		defRepo:   repo.NewIndexDefRepository(db),
+		quoteRepo: repo.NewIndexQuoteRepository(db),
+		calendar:  calendar,
+		client: &http.Client{
+			Timeout: 10 * time.Second,
+		},
+		cache: make(map[string]IndexData),
 	}
 }
 
 // GetIndexData 获取指数数据
 func (s *IndexService) GetIndexData(ctx context.Context, indexCode string) (*IndexData, error) {
-	var url string
-	var name string
-
-	switch indexCode {
-	case "000001":
-		url = "https://quote.eastmoney.com/zs000001.html"
-		name = "上证指数"
-	case "000300":
-		url = "https://quote.eastmoney.com/zs000300.html"
-		name = "沪深300"
-	case "SPX":
-		url = "https://quote.eastmoney.com/gb/zsSPX.html"
-		name = "标普500"
-	case "NDX":
-		url = "https://quote.eastmoney.com/gb/zsNDX.html"
-		name = "纳斯达克"
-	default:
+	def, err := s.defRepo.GetByCode(ctx, indexCode)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, fmt.Errorf("unsupported index code: %s", indexCode)
 	}
-
-	data, err := s.fetchIndexData(url, indexCode, name)
 	if err != nil {
 		return nil, err
 	}
 
+	data := s.quotes(ctx, []model.IndexDef{*def}, false)[0]
+	if data.Status == QuoteStatusError {
+		return nil, errors.New(data.Error)
+	}
 	return data, nil
 }
 
-// GetAllIndexes 获取所有指数数据
+// GetAllIndexes 获取行情栏中所有指数的数据，有效期内使用缓存，休市期间不重复请求。
+// 获取失败的指数不会被丢弃，而是带上失败原因和上次的行情
 func (s *IndexService) GetAllIndexes(ctx context.Context) ([]*IndexData, error) {
-	indexes := []string{"000001", "000300", "SPX", "NDX"}
-	result := make([]*IndexData, 0, len(indexes))
+	return s.watchedQuotes(ctx, false)
+}
+
+// RefreshIndexes 忽略缓存有效期，重新获取行情栏中所有指数的数据
+func (s *IndexService) RefreshIndexes(ctx context.Context) ([]*IndexData, error) {
+	return s.watchedQuotes(ctx, true)
+}
+
+func (s *IndexService) watchedQuotes(ctx context.Context, force bool) ([]*IndexData, error) {
+	defs, err := s.defRepo.GetWatched(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.quotes(ctx, defs, force), nil
+}
+
+// quotes 并发获取指数行情，结果顺序与 defs 一致。
+// 缓存仍然有效的指数不发请求；整体耗时受 ctx 和 quoteTimeout 限制
+func (s *IndexService) quotes(ctx context.Context, defs []model.IndexDef, force bool) []*IndexData {
+	s.loadCache(ctx, defs)
+	now := time.Now()
+	calendar := s.calendar.Calendar(ctx)
 
-	for _, code := range indexes {
-		data, err := s.GetIndexData(ctx, code)
+	result := make([]*IndexData, len(defs))
+	statuses := make([]MarketStatus, len(defs))
+	fetchCtx, cancel := context.WithTimeout(ctx, quoteTimeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	fresh := make([]bool, len(defs))
+	for i := range defs {
+		def := &defs[i]
+		status, err := calendar.Status(def.Market, now)
 		if err != nil {
+			println("Failed to get market status:", err.Error())
+		}
+		statuses[i] = status
+
+		cached, ok := s.cached(def.Code)
+		if ok && !force && quoteFresh(cached, status, err == nil, now) {
+			cached.Name = def.Name
+			cached.Status = QuoteStatusOK
+			result[i] = &cached
 			continue
 		}
-		result = append(result, data)
+
+		wg.Add(1)
+		go func(i int, def *model.IndexDef) {
+			defer wg.Done()
+			result[i], fresh[i] = s.fetchQuote(fetchCtx, def)
+		}(i, def)
 	}
+	wg.Wait()
 
+	// SQLite 不适合并发写入，全部获取完成后再依次保存
+	for i, data := range result {
+		if fresh[i] {
+			s.store(ctx, data)
+		}
+		data.MarketOpen = statuses[i].Open
+		data.MarketState = statuses[i].State
+	}
+	return result
+}
+
+// quoteFresh 判断缓存的行情是否仍然有效：交易时段内按 quoteTTLOpen 过期；
+// 休市时只要是在最近一次收盘并等待 quoteSettleDelay 之后获取的，就不必再请求
+func quoteFresh(cached IndexData, status MarketStatus, known bool, now time.Time) bool {
+	switch {
+	case !known:
+		return now.Sub(cached.FetchedAt) < quoteTTLClosed
+	case status.Open:
+		return now.Sub(cached.FetchedAt) < quoteTTLOpen
+	default:
+		return cached.FetchedAt.After(status.LastClose.Add(quoteSettleDelay))
+	}
+}
+
+// fetchQuote 获取单个指数的行情，失败时退回缓存；获取成功时 fresh 为 true
+func (s *IndexService) fetchQuote(ctx context.Context, def *model.IndexDef) (data *IndexData, fresh bool) {
+	data, err := s.fetchIndexData(ctx, def)
+	if err == nil {
+		data.Status = QuoteStatusOK
+		return data, true
+	}
+
+	// 请求失败时不显示冗长的请求地址
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		err = urlErr.Err
+	}
+	if cached, ok := s.cached(def.Code); ok {
+		cached.Name = def.Name
+		cached.Status = QuoteStatusStale
+		cached.Error = err.Error()
+		return &cached, false
+	}
+	return &IndexData{
+		Code:   def.Code,
+		Name:   def.Name,
+		Status: QuoteStatusError,
+		Error:  err.Error(),
+	}, false
+}
+
+func (s *IndexService) cached(code string) (IndexData, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.cache[code]
+	return data, ok
+}
+
+// store 保存获取成功的行情到内存和数据库
+func (s *IndexService) store(ctx context.Context, data *IndexData) {
+	s.mu.Lock()
+	s.cache[data.Code] = *data
+	s.mu.Unlock()
+
+	err := s.quoteRepo.Upsert(ctx, &model.IndexQuote{
+		Code:       data.Code,
+		Price:      data.Price,
+		Change:     data.Change,
+		ChangeRate: data.ChangeRate,
+		Open:       data.Open,
+		High:       data.High,
+		Low:        data.Low,
+		PrevClose:  data.PrevClose,
+		Volume:     data.Volume,
+		Amount:     data.Amount,
+		QuoteTime:  data.QuoteTime,
+		FetchedAt:  data.FetchedAt,
+	})
+	if err != nil {
+		println("Failed to cache index quote:", err.Error())
+	}
+}
+
+// loadCache 内存中没有的指数从数据库读取上次的行情
+func (s *IndexService) loadCache(ctx context.Context, defs []model.IndexDef) {
+	s.mu.Lock()
+	missing := make([]string, 0, len(defs))
+	for _, d := range defs {
+		if _, ok := s.cache[d.Code]; !ok {
+			missing = append(missing, d.Code)
+		}
+	}
+	s.mu.Unlock()
+	if len(missing) == 0 {
+		return
+	}
+
+	quotes, err := s.quoteRepo.GetByCodes(ctx, missing)
+	if err != nil {
+		println("Failed to load index quotes:", err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, q := range quotes {
+		if _, ok := s.cache[q.Code]; ok {
+			continue
+		}
+		updated := q.FetchedAt
+		if !q.QuoteTime.IsZero() {
+			updated = q.QuoteTime
+		}
+		s.cache[q.Code] = IndexData{
+			Code:       q.Code,
+			Price:      q.Price,
+			Change:     q.Change,
+			ChangeRate: q.ChangeRate,
+			Open:       q.Open,
+			High:       q.High,
+			Low:        q.Low,
+			PrevClose:  q.PrevClose,
+			Volume:     q.Volume,
+			Amount:     q.Amount,
+			UpdateTime: updated.Local().Format("2006-01-02 15:04:05"),
+			QuoteTime:  q.QuoteTime,
+			FetchedAt:  q.FetchedAt,
+		}
+	}
+}
+
+// GetIndexDefs 获取所有已添加的指数
+func (s *IndexService) GetIndexDefs(ctx context.Context) ([]map[string]interface{}, error) {
+	defs, err := s.defRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]map[string]interface{}, 0, len(defs))
+	for _, d := range defs {
+		result = append(result, indexDefToMap(d))
+	}
 	return result, nil
 }
 
-// fetchIndexData 爬取指数数据
-func (s *IndexService) fetchIndexData(url, code, name string) (*IndexData, error) {
-	// 东方财富网的数据是通过API获取的，不是直接在HTML中
-	// 使用他们的行情API
-	var apiURL string
+// IndexPresets 尚未添加的常见指数
+func (s *IndexService) IndexPresets(ctx context.Context) ([]map[string]interface{}, error) {
+	defs, err := s.defRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	added := make(map[string]bool, len(defs))
+	for _, d := range defs {
+		added[d.Code] = true
+	}
+
+	presets := make([]map[string]interface{}, 0, len(model.IndexPresets))
+	for _, p := range model.IndexPresets {
+		if !added[p.Code] {
+			presets = append(presets, indexDefToMap(p))
+		}
+	}
+	return presets, nil
+}
+
+func indexDefToMap(d model.IndexDef) map[string]interface{} {
+	return map[string]interface{}{
+		"code":     d.Code,
+		"name":     d.Name,
+		"secid":    d.SecID,
+		"market":   d.Market,
+		"currency": d.Currency,
+		"watch":    d.Watch,
+	}
+}
+
+// AddIndex 添加指数并显示在行情栏，货币为空时按市场推断
+func (s *IndexService) AddIndex(ctx context.Context, code, name, secID, market, currency string) error {
+	code = strings.TrimSpace(code)
+	name = strings.TrimSpace(name)
+	secID = strings.TrimSpace(secID)
+	if code == "" || name == "" {
+		return errors.New("指数代码和名称不能为空")
+	}
+	if !secIDPattern.MatchString(secID) {
+		return fmt.Errorf("行情 ID 格式错误: %s（应为 市场编号.代码，如 1.000300）", secID)
+	}
+	defaultCurrency, ok := model.MarketCurrencies[market]
+	if !ok {
+		return fmt.Errorf("不支持的市场: %s", market)
+	}
+	if currency == "" {
+		currency = defaultCurrency
+	}
 
-	switch code {
-	case "000001":
-		// 上证指数的API
-		apiURL = "https://push2.eastmoney.com/api/qt/stock/get?secid=1.000001&fields=f43,f44,f45,f46,f47,f48,f49,f50,f51,f52,f57,f58,f60,f107,f152,f162,f169,f170,f171"
-	case "000300":
-		// 沪深300的API
-		apiURL = "https://push2.eastmoney.com/api/qt/stock/get?secid=1.000300&fields=f43,f44,f45,f46,f47,f48,f49,f50,f51,f52,f57,f58,f60,f107,f152,f162,f169,f170,f171"
-	case "SPX":
-		// 标普500的API
-		apiURL = "https://push2.eastmoney.com/api/qt/stock/get?secid=100.SPX&fields=f43,f44,f45,f46,f47,f48,f49,f50,f51,f52,f57,f58,f60,f107,f152,f162,f169,f170,f171"
-	case "NDX":
-		// 纳斯达克的API
-		apiURL = "https://push2.eastmoney.com/api/qt/stock/get?secid=100.NDX&fields=f43,f44,f45,f46,f47,f48,f49,f50,f51,f52,f57,f58,f60,f107,f152,f162,f169,f170,f171"
+	_, err := s.defRepo.GetByCode(ctx, code)
+	if err == nil {
+		return errors.New("指数已存在")
 	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	return s.defRepo.Create(ctx, &model.IndexDef{
+		Code:     code,
+		Name:     name,
+		SecID:    secID,
+		Market:   market,
+		Currency: currency,
+		Watch:    true,
+	})
+}
+
+// RemoveIndex 删除指数
+func (s *IndexService) RemoveIndex(ctx context.Context, code string) error {
+	return s.defRepo.DeleteByCode(ctx, code)
+}
 
-	client := &http.Client{
-		Timeout: 10 * time.Second,
+// SetIndexWatched 设置指数是否显示在行情栏
+func (s *IndexService) SetIndexWatched(ctx context.Context, code string, watch bool) error {
+	_, err := s.defRepo.GetByCode(ctx, code)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("指数不存在: %s", code)
 	}
+	if err != nil {
+		return err
+	}
+	return s.defRepo.SetWatch(ctx, code, watch)
+}
+
+// ReorderIndexes 按给定的代码顺序排列指数
+func (s *IndexService) ReorderIndexes(ctx context.Context, codes []string) error {
+	return s.defRepo.Reorder(ctx, codes)
+}
+
+// InitDefaultIndexes 首次启动时写入默认指数
+func (s *IndexService) InitDefaultIndexes(ctx context.Context) error {
+	return s.defRepo.InitDefaultIndexes(ctx)
+}
 
-	req, err := http.NewRequest("GET", apiURL, nil)
+// fetchIndexData 爬取指数数据
+func (s *IndexService) fetchIndexData(ctx context.Context, def *model.IndexDef) (*IndexData, error) {
+	// 东方财富网的数据是通过API获取的，不是直接在HTML中
+	// 使用他们的行情API
+	params := url.Values{
+		"secid":  {def.SecID},
+		"fields": {quoteFields},
+	}
+	apiURL := quoteURL + "?" + params.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", apiURL, nil)
 	if err != nil {
 		return nil, err
 	}
 
 	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36")
-	req.Header.Set("Referer", url)
+	req.Header.Set("Referer", "https://quote.eastmoney.com/")
 
-	resp, err := client.Do(req)
+	resp, err := s.client.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -125,38 +434,32 @@ func (s *IndexService) fetchIndexData(url, code, name string) (*IndexData, error
 		return nil, err
 	}
 
-	// 解析JSON响应
-	// API返回格式: {"rc":0,"rt":4,"svr":...,"data":{"f43":465417,"f169":-4451,"f170":-95,...}}
-	// f43: 最新价（需要除以100）
-	// f169: 涨跌点（需要除以100）
-	// f170: 涨跌幅（需要除以100）
-	jsonStr := string(body)
-
-	data := &IndexData{
-		Code:       code,
-		Name:       name,
-		UpdateTime: time.Now().Format("2006-01-02 15:04:05"),
-	}
-
-	// 提取价格 f43 (需要除以100)
-	priceRe := regexp.MustCompile(`"f43":([0-9.]+)`)
-	if matches := priceRe.FindStringSubmatch(jsonStr); len(matches) > 1 {
-		price, _ := strconv.ParseFloat(matches[1], 64)
-		data.Price = price / 100
+	q, err := decodeQuote(body)
+	if err != nil {
+		return nil, err
 	}
 
-	// 提取涨跌点 f169 (需要除以100)
-	changeRe := regexp.MustCompile(`"f169":([+-]?[0-9.]+)`)
-	if matches := changeRe.FindStringSubmatch(jsonStr); len(matches) > 1 {
-		change, _ := strconv.ParseFloat(matches[1], 64)
-		data.Change = change / 100
+	// 行情时间优先使用交易所时间戳，休市时显示的是最后成交时间而不是获取时间
+	now := time.Now()
+	updated := now
+	if !q.Time.IsZero() {
+		updated = q.Time
 	}
-
-	// 提取涨跌幅 f170 (需要除以100)
-	changeRateRe := regexp.MustCompile(`"f170":([+-]?[0-9.]+)`)
-	if matches := changeRateRe.FindStringSubmatch(jsonStr); len(matches) > 1 {
-		rate, _ := strconv.ParseFloat(matches[1], 64)
-		data.ChangeRate = rate / 100
+	data := &IndexData{
+		Code:       def.Code,
+		Name:       def.Name,
+		Price:      q.Price,
+		Change:     q.Change,
+		ChangeRate: q.ChangeRate,
+		Open:       q.Open,
+		High:       q.High,
+		Low:        q.Low,
+		PrevClose:  q.PrevClose,
+		Volume:     q.Volume,
+		Amount:     q.Amount,
+		UpdateTime: updated.Local().Format("2006-01-02 15:04:05"),
+		QuoteTime:  q.Time,
+		FetchedAt:  now,
 	}
 
 	return data, nil
@@ -0,0 +1,763 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"margin/internal/crypto"
+	"margin/internal/model"
+	"margin/internal/repo"
+	"os"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+// 数据迁移导入模式
+const (
+	InterchangeModeMerge   = "merge"   // 合并到现有组合
+	InterchangeModeReplace = "replace" // 清空后替换
+)
+
+const (
+	interchangeAlgorithm  = "AES-256-GCM"
+	interchangeKDF        = "PBKDF2-SHA256"
+	interchangeIterations = 210000
+	interchangeCheckText  = "margin-of-safety"
+)
+
+// ExportAmount 导出文档中的金额：明文为十进制字符串，加密时为密文
+type ExportAmount string
+
+// NewExportAmount 按数据库存储精度格式化金额
+func NewExportAmount(v float64) ExportAmount {
+	return ExportAmount(fmt.Sprintf("%.2f", v))
+}
+
+// UnmarshalJSON 兼容 v1 文档中的数值金额
+func (a *ExportAmount) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		*a = ExportAmount(s)
+		return nil
+	}
+
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return err
+	}
+	*a = ExportAmount(n.String())
+	return nil
+}
+
+// Float 解析明文金额
+func (a ExportAmount) Float() (float64, error) {
+	if a == "" {
+		return 0, nil
+	}
+	return strconv.ParseFloat(string(a), 64)
+}
+
+// ExportEncryption 口令加密参数
+type ExportEncryption struct {
+	Algorithm  string `json:"algorithm"`
+	KDF        string `json:"kdf"`
+	Iterations int    `json:"iterations"`
+	Salt       string `json:"salt"`
+	Check      string `json:"check"` // 加密的校验文本，用于验证口令
+}
+
+// amounts 返回文档中所有金额字段
+func (d *ExportDocument) amounts() []*ExportAmount {
+	var amounts []*ExportAmount
+	for i := range d.Assets {
+		amounts = append(amounts, &d.Assets[i].Amount)
+	}
+	for i := range d.History {
+		amounts = append(amounts, &d.History[i].StockTotal, &d.History[i].BondTotal)
+	}
+	for i := range d.HistoryItems {
+		amounts = append(amounts, &d.HistoryItems[i].Amount)
+	}
+	for i := range d.Rebalances {
+		r := &d.Rebalances[i]
+		amounts = append(amounts, &r.TotalAmount, &r.StockAmount, &r.BondAmount)
+	}
+	for i := range d.RebalanceHoldings {
+		amounts = append(amounts, &d.RebalanceHoldings[i].Amount)
+	}
+	for i := range d.RebalanceOrders {
+		o := &d.RebalanceOrders[i]
+		amounts = append(amounts, &o.Amount, &o.FilledAmount)
+	}
+	for i := range d.CashFlows {
+		amounts = append(amounts, &d.CashFlows[i].Amount)
+	}
+	for i := range d.Lots {
+		amounts = append(amounts, &d.Lots[i].Amount)
+	}
+	return amounts
+}
+
+// Encrypt 使用口令加密文档中的全部金额
+func (d *ExportDocument) Encrypt(passphrase string) error {
+	if d.Encryption != nil {
+		return errors.New("文档已加密")
+	}
+
+	salt, err := crypto.GenerateSalt()
+	if err != nil {
+		return err
+	}
+	key, err := crypto.DeriveKey(passphrase, salt, interchangeIterations)
+	if err != nil {
+		return err
+	}
+	check, err := crypto.Encrypt(interchangeCheckText, key)
+	if err != nil {
+		return err
+	}
+
+	for _, amount := range d.amounts() {
+		encrypted, err := crypto.Encrypt(string(*amount), key)
+		if err != nil {
+			return err
+		}
+		*amount = ExportAmount(encrypted)
+	}
+
+	d.Encryption = &ExportEncryption{
+		Algorithm:  interchangeAlgorithm,
+		KDF:        interchangeKDF,
+		Iterations: interchangeIterations,
+		Salt:       salt,
+		Check:      check,
+	}
+	return nil
+}
+
+// Decrypt 使用口令解密文档中的全部金额
+func (d *ExportDocument) Decrypt(passphrase string) error {
+	if d.Encryption == nil {
+		return nil
+	}
+	if passphrase == "" {
+		return errors.New("该文件已加密，请输入口令")
+	}
+
+	key, err := crypto.DeriveKey(passphrase, d.Encryption.Salt, d.Encryption.Iterations)
+	if err != nil {
+		return err
+	}
+	if check, err := crypto.Decrypt(d.Encryption.Check, key); err != nil || check != interchangeCheckText {
+		return errors.New("口令错误")
+	}
+
+	for _, amount := range d.amounts() {
+		plain, err := crypto.Decrypt(string(*amount), key)
+		if err != nil {
+			return fmt.Errorf("解密金额失败: %w", err)
+		}
+		*amount = ExportAmount(plain)
+	}
+
+	d.Encryption = nil
+	return nil
+}
+
+// InterchangeService 跨设备、跨版本的数据迁移服务
+type InterchangeService struct {
+	db            *gorm.DB
+	exportService *ExportService
+	configRepo    *repo.ConfigRepository
+}
+
+func NewInterchangeService(db *gorm.DB, exportService *ExportService) *InterchangeService {
+	return &InterchangeService{
+		db:            db,
+		exportService: exportService,
+		configRepo:    repo.NewConfigRepository(db),
+	}
+}
+
+// Export 导出迁移文件，passphrase 不为空时加密金额
+func (s *InterchangeService) Export(ctx context.Context, path, passphrase string) error {
+	doc, err := s.exportService.BuildDocument(ctx)
+	if err != nil {
+		return err
+	}
+
+	if passphrase != "" {
+		if err := doc.Encrypt(passphrase); err != nil {
+			return err
+		}
+	}
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write export file: %w", err)
+	}
+	return nil
+}
+
+// Import 导入迁移文件，金额使用本机密钥重新加密
+func (s *InterchangeService) Import(ctx context.Context, path, passphrase, mode string) (map[string]interface{}, error) {
+	if mode != InterchangeModeMerge && mode != InterchangeModeReplace {
+		return nil, fmt.Errorf("不支持的导入模式: %s", mode)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取文件失败: %w", err)
+	}
+
+	var doc ExportDocument
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("文件格式错误: %w", err)
+	}
+	if doc.SchemaVersion < 1 {
+		return nil, errors.New("文件格式错误: 缺少 schema_version")
+	}
+	if doc.SchemaVersion > ExportSchemaVersion {
+		return nil, fmt.Errorf("文件版本 %d 高于当前支持的版本 %d，请先升级应用", doc.SchemaVersion, ExportSchemaVersion)
+	}
+	if err := doc.Decrypt(passphrase); err != nil {
+		return nil, err
+	}
+
+	encryptKey, err := s.configRepo.Get(ctx, model.ConfigKeyEncryptKey)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := map[string]int{}
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if mode == InterchangeModeReplace {
+			if err := clearPortfolio(tx); err != nil {
+				return err
+			}
+		}
+		return importDocument(ctx, tx, &doc, encryptKey.Value, mode, summary)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"schema_version": doc.SchemaVersion,
+		"mode":           mode,
+		"summary":        summary,
+	}, nil
+}
+
+// clearPortfolio 清空除配置外的所有数据表
+func clearPortfolio(tx *gorm.DB) error {
+	for _, table := range []interface{}{
+		&model.Asset{}, &model.Source{}, &model.History{}, &model.HistoryItem{}, &model.Rebalance{}, &model.RebalanceHolding{}, &model.RebalanceOrder{}, &model.RebalanceReview{},
+		&model.CashFlow{}, &model.AssetLot{}, &model.FeeRule{}, &model.ImportedTransaction{},
+	} {
+		if err := tx.Where("1 = 1").Delete(table).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// importDocument 写入文档数据。替换模式保留原 ID，合并模式按业务键去重并分配新 ID
+func importDocument(ctx context.Context, tx *gorm.DB, doc *ExportDocument, key, mode string, summary map[string]int) error {
+	keepID := mode == InterchangeModeReplace
+	encrypt := func(a ExportAmount) (string, error) {
+		v, err := a.Float()
+		if err != nil {
+			return "", fmt.Errorf("金额格式错误: %w", err)
+		}
+		return crypto.Encrypt(fmt.Sprintf("%.2f", v), key)
+	}
+
+	sourceRepo := repo.NewSourceRepository(tx)
+	for _, src := range doc.Sources {
+		if !keepID {
+			if _, err := sourceRepo.GetByName(ctx, src.Name); err == nil {
+				continue
+			} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+		}
+		source := &model.Source{Name: src.Name, CreatedAt: src.CreatedAt, UpdatedAt: src.UpdatedAt}
+		if keepID {
+			source.ID = src.ID
+		}
+		if err := sourceRepo.Create(ctx, source); err != nil {
+			return err
+		}
+		summary["sources"]++
+	}
+
+	// 合并模式下资产 ID 会变化，记录文档 ID 到新 ID 的映射，供持仓批次使用
+	assetIDs := map[uint]uint{}
+	assetRepo := repo.NewAssetRepository(tx)
+	for _, a := range doc.Assets {
+		encrypted, err := encrypt(a.Amount)
+		if err != nil {
+			return err
+		}
+
+		if !keepID {
+			existing, err := assetRepo.GetByCodeAndSource(ctx, a.Code, a.Source)
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+			if existing != nil {
+				existing.Name = a.Name
+				existing.URL = a.URL
+				existing.Type = a.Type
+				existing.EncryptedAmount = encrypted
+				existing.TargetWeight = a.TargetWeight
+				if err := assetRepo.Update(ctx, existing); err != nil {
+					return err
+				}
+				assetIDs[a.ID] = existing.ID
+				summary["assets_updated"]++
+				continue
+			}
+			if err := ensureSource(ctx, sourceRepo, a.Source); err != nil {
+				return err
+			}
+		}
+
+		asset := &model.Asset{
+			Code:            a.Code,
+			Name:            a.Name,
+			URL:             a.URL,
+			Type:            a.Type,
+			Source:          a.Source,
+			EncryptedAmount: encrypted,
+			TargetWeight:    a.TargetWeight,
+			CreatedAt:       a.CreatedAt,
+			UpdatedAt:       a.UpdatedAt,
+		}
+		if keepID {
+			asset.ID = a.ID
+		}
+		if err := assetRepo.Create(ctx, asset); err != nil {
+			return err
+		}
+		assetIDs[a.ID] = asset.ID
+		summary["assets"]++
+	}
+
+	// 合并模式下记录快照的新 ID，供资产明细使用
+	historyIDs := map[uint]uint{}
+	historyRepo := repo.NewHistoryRepository(tx)
+	for _, h := range doc.History {
+		if !keepID {
+			var count int64
+			if err := tx.Model(&model.History{}).Where("created_at = ?", h.CreatedAt).Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				continue
+			}
+		}
+
+		stock, err := encrypt(h.StockTotal)
+		if err != nil {
+			return err
+		}
+		bond, err := encrypt(h.BondTotal)
+		if err != nil {
+			return err
+		}
+		history := &model.History{
+			EncryptedStockTotal: stock,
+			EncryptedBondTotal:  bond,
+			StockRatio:          h.StockRatio,
+			BondRatio:           h.BondRatio,
+			CreatedAt:           h.CreatedAt,
+		}
+		if keepID {
+			history.ID = h.ID
+		}
+		if err := historyRepo.Create(ctx, history); err != nil {
+			return err
+		}
+		historyIDs[h.ID] = history.ID
+		summary["history"]++
+	}
+
+	var historyItems []model.HistoryItem
+	for _, item := range doc.HistoryItems {
+		historyID, ok := historyIDs[item.HistoryID]
+		if !ok {
+			continue
+		}
+		amount, err := encrypt(item.Amount)
+		if err != nil {
+			return err
+		}
+		historyItem := model.HistoryItem{
+			HistoryID:       historyID,
+			AssetID:         item.AssetID,
+			Code:            item.Code,
+			Name:            item.Name,
+			Type:            item.Type,
+			Source:          item.Source,
+			EncryptedAmount: amount,
+			CreatedAt:       item.CreatedAt,
+		}
+		if keepID {
+			historyItem.ID = item.ID
+		} else if newID, ok := assetIDs[item.AssetID]; ok {
+			historyItem.AssetID = newID
+		}
+		historyItems = append(historyItems, historyItem)
+	}
+	if err := historyRepo.CreateItems(ctx, historyItems); err != nil {
+		return err
+	}
+	summary["history_items"] += len(historyItems)
+
+	// 合并模式下记录再平衡的新 ID，供持仓快照使用
+	rebalanceIDs := map[uint]uint{}
+	rebalanceRepo := repo.NewRebalanceRepository(tx)
+	for _, r := range doc.Rebalances {
+		if !keepID {
+			var count int64
+			if err := tx.Model(&model.Rebalance{}).Where("created_at = ?", r.CreatedAt).Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				continue
+			}
+		}
+
+		total, err := encrypt(r.TotalAmount)
+		if err != nil {
+			return err
+		}
+		stock, err := encrypt(r.StockAmount)
+		if err != nil {
+			return err
+		}
+		bond, err := encrypt(r.BondAmount)
+		if err != nil {
+			return err
+		}
+		rebalance := &model.Rebalance{
+			StockRatio:           r.StockRatio,
+			BondRatio:            r.BondRatio,
+			EncryptedTotalAmount: total,
+			EncryptedStockAmount: stock,
+			EncryptedBondAmount:  bond,
+			TargetStockRatio:     r.TargetStockRatio,
+			TargetBondRatio:      r.TargetBondRatio,
+			Note:                 r.Note,
+			CreatedAt:            r.CreatedAt,
+		}
+		if keepID {
+			rebalance.ID = r.ID
+		}
+		if err := rebalanceRepo.Create(ctx, rebalance); err != nil {
+			return err
+		}
+		rebalanceIDs[r.ID] = rebalance.ID
+		summary["rebalances"]++
+	}
+
+	var snapshot []model.RebalanceHolding
+	for _, h := range doc.RebalanceHoldings {
+		rebalanceID, ok := rebalanceIDs[h.RebalanceID]
+		if !ok {
+			continue
+		}
+		amount, err := encrypt(h.Amount)
+		if err != nil {
+			return err
+		}
+		holding := model.RebalanceHolding{
+			RebalanceID:     rebalanceID,
+			AssetID:         h.AssetID,
+			Code:            h.Code,
+			Name:            h.Name,
+			Type:            h.Type,
+			Source:          h.Source,
+			EncryptedAmount: amount,
+			Ratio:           h.Ratio,
+			CreatedAt:       h.CreatedAt,
+		}
+		if keepID {
+			holding.ID = h.ID
+		} else if newID, ok := assetIDs[h.AssetID]; ok {
+			holding.AssetID = newID
+		}
+		snapshot = append(snapshot, holding)
+	}
+	if err := rebalanceRepo.CreateHoldings(ctx, snapshot); err != nil {
+		return err
+	}
+	summary["rebalance_holdings"] += len(snapshot)
+
+	// 合并模式下记录交易的新 ID，供资金流水使用
+	var orders []model.RebalanceOrder
+	var orderDocIDs []uint
+	for _, o := range doc.RebalanceOrders {
+		rebalanceID, ok := rebalanceIDs[o.RebalanceID]
+		if !ok {
+			continue
+		}
+		amount, err := encrypt(o.Amount)
+		if err != nil {
+			return err
+		}
+		var filled string
+		if o.FilledAmount != "" {
+			if filled, err = encrypt(o.FilledAmount); err != nil {
+				return err
+			}
+		}
+		order := model.RebalanceOrder{
+			RebalanceID:           rebalanceID,
+			AssetID:               o.AssetID,
+			Code:                  o.Code,
+			Name:                  o.Name,
+			Type:                  o.Type,
+			Source:                o.Source,
+			Action:                o.Action,
+			EncryptedAmount:       amount,
+			EncryptedFilledAmount: filled,
+			Status:                o.Status,
+			SubmittedAt:           o.SubmittedAt,
+			FilledAt:              o.FilledAt,
+			CreatedAt:             o.CreatedAt,
+			UpdatedAt:             o.UpdatedAt,
+		}
+		if keepID {
+			order.ID = o.ID
+		} else if newID, ok := assetIDs[o.AssetID]; ok {
+			order.AssetID = newID
+		}
+		orders = append(orders, order)
+		orderDocIDs = append(orderDocIDs, o.ID)
+	}
+	if err := rebalanceRepo.CreateOrders(ctx, orders); err != nil {
+		return err
+	}
+	orderIDs := make(map[uint]uint, len(orders))
+	for i, o := range orders {
+		orderIDs[orderDocIDs[i]] = o.ID
+	}
+	summary["rebalance_orders"] += len(orders)
+
+	reviewRepo := repo.NewReviewRepository(tx)
+	for _, r := range doc.Reviews {
+		if !keepID {
+			var count int64
+			if err := tx.Model(&model.RebalanceReview{}).Where("created_at = ?", r.CreatedAt).Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				continue
+			}
+		}
+
+		review := &model.RebalanceReview{
+			DueDate:          r.DueDate,
+			TargetStockRatio: r.TargetStockRatio,
+			StockRatio:       r.StockRatio,
+			Breached:         r.Breached,
+			Reasons:          r.Reasons,
+			Outcome:          r.Outcome,
+			Note:             r.Note,
+			CreatedAt:        r.CreatedAt,
+			UpdatedAt:        r.UpdatedAt,
+		}
+		if keepID {
+			review.ID = r.ID
+		}
+		if err := reviewRepo.Create(ctx, review); err != nil {
+			return err
+		}
+		summary["reviews"]++
+	}
+
+	cashFlowRepo := repo.NewCashFlowRepository(tx)
+	for _, f := range doc.CashFlows {
+		if !keepID {
+			var count int64
+			if err := tx.Model(&model.CashFlow{}).Where("created_at = ?", f.CreatedAt).Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				continue
+			}
+		}
+
+		amount, err := encrypt(f.Amount)
+		if err != nil {
+			return err
+		}
+		flow := &model.CashFlow{
+			Date:            f.Date,
+			Kind:            f.Kind,
+			AssetID:         f.AssetID,
+			Code:            f.Code,
+			Name:            f.Name,
+			Type:            f.Type,
+			Source:          f.Source,
+			EncryptedAmount: amount,
+			OrderID:         f.OrderID,
+			Note:            f.Note,
+			CreatedAt:       f.CreatedAt,
+		}
+		if keepID {
+			flow.ID = f.ID
+		} else {
+			if newID, ok := assetIDs[f.AssetID]; ok {
+				flow.AssetID = newID
+			}
+			flow.OrderID = orderIDs[f.OrderID]
+		}
+		if err := cashFlowRepo.Create(ctx, flow); err != nil {
+			return err
+		}
+		summary["cash_flows"]++
+	}
+
+	lotRepo := repo.NewLotRepository(tx)
+	for _, l := range doc.Lots {
+		assetID, ok := assetIDs[l.AssetID]
+		if !ok {
+			continue
+		}
+		if !keepID {
+			var count int64
+			if err := tx.Model(&model.AssetLot{}).
+				Where("asset_id = ? AND buy_date = ?", assetID, l.BuyDate).
+				Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				continue
+			}
+		}
+
+		amount, err := encrypt(l.Amount)
+		if err != nil {
+			return err
+		}
+		lot := &model.AssetLot{
+			AssetID:         assetID,
+			BuyDate:         l.BuyDate,
+			EncryptedAmount: amount,
+			CreatedAt:       l.CreatedAt,
+		}
+		if keepID {
+			lot.ID = l.ID
+		}
+		if err := lotRepo.Create(ctx, lot); err != nil {
+			return err
+		}
+		summary["lots"]++
+	}
+
+	feeRepo := repo.NewFeeRuleRepository(tx)
+	for _, r := range doc.FeeRules {
+		if !keepID {
+			var count int64
+			if err := tx.Model(&model.FeeRule{}).
+				Where("code = ? AND source = ? AND kind = ? AND min_days = ? AND min_amount = ?",
+					r.Code, r.Source, r.Kind, r.MinDays, r.MinAmount).
+				Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				continue
+			}
+		}
+
+		rule := &model.FeeRule{
+			Code:      r.Code,
+			Source:    r.Source,
+			Kind:      r.Kind,
+			MinDays:   r.MinDays,
+			MaxDays:   r.MaxDays,
+			MinAmount: r.MinAmount,
+			MaxAmount: r.MaxAmount,
+			Rate:      r.Rate,
+			Fixed:     r.Fixed,
+			Origin:    r.Origin,
+			CreatedAt: r.CreatedAt,
+			UpdatedAt: r.UpdatedAt,
+		}
+		if keepID {
+			rule.ID = r.ID
+		}
+		if err := feeRepo.Create(ctx, rule); err != nil {
+			return err
+		}
+		summary["fee_rules"]++
+	}
+
+	// 账单交易记录只保存哈希，合并模式下已存在的哈希跳过
+	importRepo := repo.NewImportedTransactionRepository(tx)
+	hashes := make([]string, 0, len(doc.ImportedTransactions))
+	for _, t := range doc.ImportedTransactions {
+		hashes = append(hashes, t.Hash)
+	}
+	existing, err := importRepo.Existing(ctx, hashes)
+	if err != nil {
+		return err
+	}
+	var imported []model.ImportedTransaction
+	for _, t := range doc.ImportedTransactions {
+		if existing[t.Hash] {
+			continue
+		}
+		existing[t.Hash] = true
+		transaction := model.ImportedTransaction{
+			Hash:      t.Hash,
+			Source:    t.Source,
+			Code:      t.Code,
+			CreatedAt: t.CreatedAt,
+		}
+		if keepID {
+			transaction.ID = t.ID
+		}
+		imported = append(imported, transaction)
+	}
+	if err := importRepo.Create(ctx, imported); err != nil {
+		return err
+	}
+	summary["imported_transactions"] += len(imported)
+
+	// 指数按代码写入，两种模式都覆盖同代码指数的设置
+	defs := make([]model.IndexDef, 0, len(doc.IndexDefs))
+	for _, d := range doc.IndexDefs {
+		defs = append(defs, model.IndexDef{
+			Code:      d.Code,
+			Name:      d.Name,
+			SecID:     d.SecID,
+			Market:    d.Market,
+			Currency:  d.Currency,
+			Sort:      d.Sort,
+			Watch:     d.Watch,
+			CreatedAt: d.CreatedAt,
+			UpdatedAt: d.UpdatedAt,
+		})
+	}
+	if err := repo.NewIndexDefRepository(tx).Upsert(ctx, defs); err != nil {
+		return err
+	}
+	summary["index_defs"] += len(defs)
+
+	return nil
+}
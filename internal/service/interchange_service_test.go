@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"margin/internal/model"
+	"margin/internal/repo"
+)
+
+func TestInterchangeKeepsImportedTransactions(t *testing.T) {
+	g := newTestDB(t)
+	ctx := context.Background()
+	assets := NewAssetService(g)
+	imports := NewImportService(g, NewFundService())
+	interchange := NewInterchangeService(g, NewExportService(g))
+
+	if err := assets.SaveAsset(ctx, "110020", "易方达沪深300ETF联接A", "", "stock", "支付宝", 10000); err != nil {
+		t.Fatal(err)
+	}
+	if err := assets.SaveAsset(ctx, "217022", "招商产业债券A", "", "bond", "支付宝", 5000); err != nil {
+		t.Fatal(err)
+	}
+	statement := filepath.Join("..", "importer", "testdata", "alipay_transactions.csv")
+	if _, err := imports.ApplyStatement(ctx, statement, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "export.json")
+	if err := interchange.Export(ctx, path, ""); err != nil {
+		t.Fatal(err)
+	}
+	for _, mode := range []string{InterchangeModeReplace, InterchangeModeMerge} {
+		result, err := interchange.Import(ctx, path, "", mode)
+		if err != nil {
+			t.Fatalf("%s: %v", mode, err)
+		}
+		want := map[string]int{InterchangeModeReplace: 2, InterchangeModeMerge: 0}[mode]
+		if got := result["summary"].(map[string]int)["imported_transactions"]; got != want {
+			t.Errorf("%s: restored %d imported transactions, want %d", mode, got, want)
+		}
+
+		// 迁移后再次导入同一份流水不会重复计入持仓
+		result, err = imports.ApplyStatement(ctx, statement, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if skipped := result["statement"].(map[string]interface{})["skipped_transactions"]; skipped != 2 {
+			t.Errorf("%s: skipped %v transactions after import, want 2", mode, skipped)
+		}
+	}
+}
+
+func TestInterchangeRestoresIndexDefs(t *testing.T) {
+	g := newTestDB(t)
+	ctx := context.Background()
+	defRepo := repo.NewIndexDefRepository(g)
+	interchange := NewInterchangeService(g, NewExportService(g))
+
+	if err := defRepo.InitDefaultIndexes(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := defRepo.Create(ctx, &model.IndexDef{Code: "931151", Name: "光伏产业", SecID: "2.931151", Market: model.MarketCN, Currency: "CNY"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := defRepo.SetWatch(ctx, "931151", true); err != nil {
+		t.Fatal(err)
+	}
+	if err := defRepo.Reorder(ctx, []string{"931151", "000300"}); err != nil {
+		t.Fatal(err)
+	}
+	want, err := defRepo.GetAll(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "export.json")
+	if err := interchange.Export(ctx, path, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// 删除自定义指数并改动默认指数，导入后按代码恢复
+	if err := defRepo.DeleteByCode(ctx, "931151"); err != nil {
+		t.Fatal(err)
+	}
+	if err := defRepo.SetWatch(ctx, "NDX", true); err != nil {
+		t.Fatal(err)
+	}
+	for _, mode := range []string{InterchangeModeMerge, InterchangeModeReplace} {
+		if _, err := interchange.Import(ctx, path, "", mode); err != nil {
+			t.Fatalf("%s: %v", mode, err)
+		}
+		got, err := defRepo.GetAll(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != len(want) {
+			t.Fatalf("%s: got %d indexes, want %d", mode, len(got), len(want))
+		}
+		for i := range want {
+			d, w := got[i], want[i]
+			if d.Code != w.Code || d.Name != w.Name || d.SecID != w.SecID || d.Sort != w.Sort || d.Watch != w.Watch {
+				t.Errorf("%s: index %d = %+v, want %+v", mode, i, d, w)
+			}
+		}
+	}
+}
@@ -0,0 +1,336 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"margin/internal/model"
+	"math"
+	"math/rand/v2"
+	"sort"
+)
+
+// 蒙特卡洛收益模型
+const (
+	MonteCarloBootstrap  = "bootstrap"  // 从历史月度股债收益中有放回抽样（同月成对抽取，保留相关性）
+	MonteCarloParametric = "parametric" // 按年化收益率、波动率和相关系数生成正态分布的月度收益
+)
+
+// 蒙特卡洛规模上限
+const (
+	maxMonteCarloPaths  = 10000
+	maxMonteCarloYears  = 60
+	maxSustainablePaths = 2000 // 可持续提取率二分查找使用的路径数上限
+)
+
+// monteCarloPercentiles 输出的分位数
+var monteCarloPercentiles = []float64{5, 25, 50, 75, 95}
+
+// MonteCarloParams 蒙特卡洛模拟参数，组合每月按股票比例再平衡
+type MonteCarloParams struct {
+	Method              string
+	StockRatio          float64 // 股票比例(%)
+	InitialValue        float64 // 期初金额
+	MonthlyContribution float64 // 每月投入
+	MonthlyWithdrawal   float64 // 每月取出
+	Years               int     // 模拟年数
+	Goal                float64 // 目标金额，0 表示不计算未达标概率
+	Paths               int     // 模拟路径数
+	Seed                uint64  // 随机种子，相同种子和参数得到相同结果
+	SuccessRate         float64 // 可持续提取率要求的成功率(%)
+
+	// 参数模型使用的年化假设(%)
+	StockReturn     float64
+	StockVolatility float64
+	BondReturn      float64
+	BondVolatility  float64
+	Correlation     float64 // 股债相关系数，-1 到 1
+}
+
+// DefaultMonteCarloParams 参数模型的默认假设
+var DefaultMonteCarloParams = MonteCarloParams{
+	Method:          MonteCarloBootstrap,
+	Years:           30,
+	Paths:           2000,
+	Seed:            1,
+	SuccessRate:     90,
+	StockReturn:     8,
+	StockVolatility: 20,
+	BondReturn:      3.5,
+	BondVolatility:  3,
+}
+
+// Validate 校验模拟参数
+func (p MonteCarloParams) Validate() error {
+	switch p.Method {
+	case MonteCarloBootstrap, MonteCarloParametric:
+	default:
+		return fmt.Errorf("不支持的收益模型: %s", p.Method)
+	}
+	if p.StockRatio < 0 || p.StockRatio > 100 {
+		return errors.New("股票比例需在 0-100 之间")
+	}
+	if p.InitialValue <= 0 {
+		return errors.New("期初金额必须大于 0")
+	}
+	if p.MonthlyContribution < 0 || p.MonthlyWithdrawal < 0 || p.Goal < 0 {
+		return errors.New("金额不能为负数")
+	}
+	if p.Years <= 0 || p.Years > maxMonteCarloYears {
+		return fmt.Errorf("模拟年数需在 1-%d 之间", maxMonteCarloYears)
+	}
+	if p.Paths <= 0 || p.Paths > maxMonteCarloPaths {
+		return fmt.Errorf("模拟路径数需在 1-%d 之间", maxMonteCarloPaths)
+	}
+	if p.SuccessRate <= 0 || p.SuccessRate >= 100 {
+		return errors.New("成功率需在 0-100 之间")
+	}
+	if p.Method == MonteCarloParametric {
+		if p.StockVolatility < 0 || p.BondVolatility < 0 {
+			return errors.New("波动率不能为负数")
+		}
+		if p.Correlation < -1 || p.Correlation > 1 {
+			return errors.New("相关系数需在 -1 到 1 之间")
+		}
+	}
+	return nil
+}
+
+// MonthlyReturn 同一月份的股债收益率
+type MonthlyReturn struct {
+	Stock float64
+	Bond  float64
+}
+
+// monthlyReturns 由对齐后的日线计算每个自然月的股债收益率（以月末收盘价计算）
+func monthlyReturns(days []BacktestDay) []MonthlyReturn {
+	var returns []MonthlyReturn
+	var prev *BacktestDay
+	for i := range days {
+		last := i == len(days)-1 || days[i+1].Date.Month() != days[i].Date.Month()
+		if !last {
+			continue
+		}
+		if prev != nil {
+			returns = append(returns, MonthlyReturn{
+				Stock: days[i].Stock/prev.Stock - 1,
+				Bond:  days[i].Bond/prev.Bond - 1,
+			})
+		}
+		prev = &days[i]
+	}
+	return returns
+}
+
+// MonteCarloResult 模拟结果
+type MonteCarloResult struct {
+	Params               MonteCarloParams
+	HistoryMonths        int         // 自助抽样使用的历史月数
+	Bands                [][]float64 // 每年末各分位数的组合金额，Bands[年][分位]
+	FinalPercentiles     []float64
+	ShortfallProbability float64 // 期末低于目标金额的概率(%)
+	DepletionProbability float64 // 期间资金耗尽的概率(%)
+	SustainableRate      float64 // 满足成功率要求的最高年提取率（占期初金额，%）
+}
+
+// monteCarlo 执行模拟，history 为自助抽样的历史月度收益（参数模型时忽略）
+type monteCarlo struct {
+	params  MonteCarloParams
+	history []MonthlyReturn
+}
+
+// sampler 返回按种子生成月度组合收益的函数，相同种子每次得到相同序列
+func (m *monteCarlo) sampler() func() float64 {
+	p := m.params
+	rng := rand.New(rand.NewPCG(p.Seed, p.Seed^0x9e3779b97f4a7c15))
+	w := p.StockRatio / 100
+
+	if p.Method == MonteCarloBootstrap {
+		return func() float64 {
+			r := m.history[rng.IntN(len(m.history))]
+			return w*r.Stock + (1-w)*r.Bond
+		}
+	}
+
+	// 年化假设换算为月度对数收益的均值和波动率，用 Cholesky 分解生成相关的股债收益
+	muS := math.Log(1+p.StockReturn/100) / 12
+	muB := math.Log(1+p.BondReturn/100) / 12
+	sdS := p.StockVolatility / 100 / math.Sqrt(12)
+	sdB := p.BondVolatility / 100 / math.Sqrt(12)
+	rho := p.Correlation
+	return func() float64 {
+		z1, z2 := rng.NormFloat64(), rng.NormFloat64()
+		stock := math.Exp(muS-sdS*sdS/2+sdS*z1) - 1
+		bond := math.Exp(muB-sdB*sdB/2+sdB*(rho*z1+math.Sqrt(1-rho*rho)*z2)) - 1
+		return w*stock + (1-w)*bond
+	}
+}
+
+// simulate 按每月投入和取出模拟所有路径，返回每条路径每年末的金额与是否耗尽
+func (m *monteCarlo) simulate(contribution, withdrawal float64) (yearly [][]float64, depleted []bool) {
+	p := m.params
+	next := m.sampler()
+	yearly = make([][]float64, p.Paths)
+	depleted = make([]bool, p.Paths)
+	for i := range yearly {
+		value := p.InitialValue
+		yearly[i] = make([]float64, p.Years)
+		for month := 0; month < p.Years*12; month++ {
+			r := next()
+			if value > 0 {
+				value = value*(1+r) + contribution - withdrawal
+				if value <= 0 {
+					value = 0
+					depleted[i] = true
+				}
+			}
+			if month%12 == 11 {
+				yearly[i][month/12] = value
+			}
+		}
+	}
+	return yearly, depleted
+}
+
+// returnMatrix 预先抽取 paths 条路径的逐月组合收益，与 simulate 使用同一种子，
+// 因此得到的是完整模拟中前 paths 条路径的收益序列
+func (m *monteCarlo) returnMatrix(paths int) [][]float64 {
+	next := m.sampler()
+	returns := make([][]float64, paths)
+	for i := range returns {
+		returns[i] = make([]float64, m.params.Years*12)
+		for month := range returns[i] {
+			returns[i][month] = next()
+		}
+	}
+	return returns
+}
+
+// successRate 按年提取率（占期初金额，%）在给定收益序列上模拟，返回资金不耗尽的路径比例(%)
+func (m *monteCarlo) successRate(returns [][]float64, rate float64) float64 {
+	withdrawal := m.params.InitialValue * rate / 100 / 12
+	ok := 0
+	for _, path := range returns {
+		value := m.params.InitialValue
+		for _, r := range path {
+			value = value*(1+r) - withdrawal
+			if value <= 0 {
+				break
+			}
+		}
+		if value > 0 {
+			ok++
+		}
+	}
+	return float64(ok) / float64(len(returns)) * 100
+}
+
+// sustainableRate 二分查找满足成功率要求的最高年提取率（不计每月投入）。
+// 收益序列只抽取一次并在各步之间复用，路径数不超过 maxSustainablePaths
+func (m *monteCarlo) sustainableRate() float64 {
+	returns := m.returnMatrix(min(m.params.Paths, maxSustainablePaths))
+	lo, hi := 0.0, 100.0
+	for i := 0; i < 30 && hi-lo > 0.01; i++ {
+		mid := (lo + hi) / 2
+		if m.successRate(returns, mid) >= m.params.SuccessRate {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return lo
+}
+
+// run 执行完整模拟
+func (m *monteCarlo) run() (*MonteCarloResult, error) {
+	p := m.params
+	if p.Method == MonteCarloBootstrap && len(m.history) < 12 {
+		return nil, errors.New("历史数据不足一年，无法自助抽样")
+	}
+
+	yearly, depleted := m.simulate(p.MonthlyContribution, p.MonthlyWithdrawal)
+	result := &MonteCarloResult{
+		Params:        p,
+		HistoryMonths: len(m.history),
+		Bands:         make([][]float64, p.Years),
+	}
+
+	column := make([]float64, p.Paths)
+	for year := 0; year < p.Years; year++ {
+		for i := range yearly {
+			column[i] = yearly[i][year]
+		}
+		result.Bands[year] = percentiles(column, monteCarloPercentiles)
+	}
+	result.FinalPercentiles = result.Bands[p.Years-1]
+
+	var short, empty int
+	for i := range yearly {
+		if p.Goal > 0 && yearly[i][p.Years-1] < p.Goal {
+			short++
+		}
+		if depleted[i] {
+			empty++
+		}
+	}
+	result.ShortfallProbability = float64(short) / float64(p.Paths) * 100
+	result.DepletionProbability = float64(empty) / float64(p.Paths) * 100
+	result.SustainableRate = m.sustainableRate()
+	return result, nil
+}
+
+// percentiles 计算样本的分位数（线性插值），不修改入参
+func percentiles(values []float64, ps []float64) []float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+
+	result := make([]float64, len(ps))
+	for i, p := range ps {
+		pos := p / 100 * float64(len(sorted)-1)
+		lower := int(math.Floor(pos))
+		upper := int(math.Ceil(pos))
+		result[i] = sorted[lower] + (sorted[upper]-sorted[lower])*(pos-float64(lower))
+	}
+	return result
+}
+
+// ToMap 转换为前端展示的结构，bands 按分位数给出逐年序列便于绘图
+func (r *MonteCarloResult) ToMap() map[string]interface{} {
+	years := make([]int, len(r.Bands))
+	bands := make(map[string][]float64, len(monteCarloPercentiles))
+	for year, values := range r.Bands {
+		years[year] = year + 1
+		for i, p := range monteCarloPercentiles {
+			key := fmt.Sprintf("p%g", p)
+			bands[key] = append(bands[key], values[i])
+		}
+	}
+	final := make(map[string]float64, len(monteCarloPercentiles))
+	for i, p := range monteCarloPercentiles {
+		final[fmt.Sprintf("p%g", p)] = r.FinalPercentiles[i]
+	}
+
+	return map[string]interface{}{
+		"method":                r.Params.Method,
+		"stock_ratio":           r.Params.StockRatio,
+		"initial_value":         r.Params.InitialValue,
+		"monthly_contribution":  r.Params.MonthlyContribution,
+		"monthly_withdrawal":    r.Params.MonthlyWithdrawal,
+		"years":                 r.Params.Years,
+		"goal":                  r.Params.Goal,
+		"paths":                 r.Params.Paths,
+		"seed":                  r.Params.Seed,
+		"success_rate":          r.Params.SuccessRate,
+		"history_months":        r.HistoryMonths,
+		"year_axis":             years,
+		"bands":                 bands,
+		"final_percentiles":     final,
+		"shortfall_probability": r.ShortfallProbability,
+		"depletion_probability": r.DepletionProbability,
+		"sustainable_rate":      r.SustainableRate,
+	}
+}
+
+// historyReturns 对齐股债日线后计算月度收益
+func historyReturns(stock, bond []model.IndexPrice) []MonthlyReturn {
+	return monthlyReturns(alignSeries(stock, bond))
+}
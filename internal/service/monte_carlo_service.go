@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"errors"
+)
+
+// 自助抽样使用的股债指数
+const (
+	monteCarloStockCode = "000300"
+	monteCarloBondCode  = "000012"
+)
+
+// MonteCarloService 基于当前持仓的蒙特卡洛收益模拟
+type MonteCarloService struct {
+	assetService *AssetService
+	indexHistory *IndexHistoryService
+}
+
+func NewMonteCarloService(assetService *AssetService, indexHistory *IndexHistoryService) *MonteCarloService {
+	return &MonteCarloService{
+		assetService: assetService,
+		indexHistory: indexHistory,
+	}
+}
+
+// Defaults 返回以当前持仓填充的默认参数，没有持仓时股票比例取 50%
+func (s *MonteCarloService) Defaults(ctx context.Context) (map[string]interface{}, error) {
+	p := DefaultMonteCarloParams
+	p.StockRatio = 50
+	holdings, err := s.assetService.LoadHoldings(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if stockTotal, bondTotal := portfolioTotals(holdings); stockTotal+bondTotal > 0 {
+		p.StockRatio = stockTotal / (stockTotal + bondTotal) * 100
+		p.InitialValue = stockTotal + bondTotal
+	}
+	return map[string]interface{}{
+		"method":           p.Method,
+		"stock_ratio":      p.StockRatio,
+		"initial_value":    p.InitialValue,
+		"years":            p.Years,
+		"paths":            p.Paths,
+		"seed":             p.Seed,
+		"success_rate":     p.SuccessRate,
+		"stock_return":     p.StockReturn,
+		"stock_volatility": p.StockVolatility,
+		"bond_return":      p.BondReturn,
+		"bond_volatility":  p.BondVolatility,
+		"correlation":      p.Correlation,
+	}, nil
+}
+
+// Simulate 执行蒙特卡洛模拟。StockRatio < 0 时使用当前组合的股票比例，InitialValue <= 0 时使用当前总资产
+func (s *MonteCarloService) Simulate(ctx context.Context, p MonteCarloParams) (map[string]interface{}, error) {
+	if p.StockRatio < 0 || p.InitialValue <= 0 {
+		current := p
+		if err := s.fillPortfolio(ctx, &current); err != nil {
+			return nil, err
+		}
+		if p.StockRatio < 0 {
+			p.StockRatio = current.StockRatio
+		}
+		if p.InitialValue <= 0 {
+			p.InitialValue = current.InitialValue
+		}
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	m := &monteCarlo{params: p}
+	if p.Method == MonteCarloBootstrap {
+		stock, err := s.indexHistory.Prices(ctx, monteCarloStockCode, "", "")
+		if err != nil {
+			return nil, err
+		}
+		bond, err := s.indexHistory.Prices(ctx, monteCarloBondCode, "", "")
+		if err != nil {
+			return nil, err
+		}
+		m.history = historyReturns(stock, bond)
+	}
+
+	result, err := m.run()
+	if err != nil {
+		return nil, err
+	}
+	return result.ToMap(), nil
+}
+
+// fillPortfolio 用当前持仓填充股票比例和期初金额
+func (s *MonteCarloService) fillPortfolio(ctx context.Context, p *MonteCarloParams) error {
+	holdings, err := s.assetService.LoadHoldings(ctx)
+	if err != nil {
+		return err
+	}
+	stockTotal, bondTotal := portfolioTotals(holdings)
+	total := stockTotal + bondTotal
+	if total == 0 {
+		return errors.New("no assets found")
+	}
+	p.StockRatio = stockTotal / total * 100
+	p.InitialValue = total
+	return nil
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func parametricParams() MonteCarloParams {
+	p := DefaultMonteCarloParams
+	p.Method = MonteCarloParametric
+	p.StockRatio = 60
+	p.InitialValue = 100000
+	p.MonthlyContribution = 1000
+	p.Years = 10
+	p.Goal = 300000
+	p.Paths = 500
+	return p
+}
+
+func TestMonteCarloSeed(t *testing.T) {
+	run := func(seed uint64) *MonteCarloResult {
+		p := parametricParams()
+		p.Seed = seed
+		result, err := (&monteCarlo{params: p}).run()
+		if err != nil {
+			t.Fatalf("run(seed=%d): %v", seed, err)
+		}
+		return result
+	}
+
+	a, b := run(1), run(1)
+	if !reflect.DeepEqual(a.Bands, b.Bands) {
+		t.Errorf("same seed gave different bands:\n%v\n%v", a.Bands, b.Bands)
+	}
+	if a.ShortfallProbability != b.ShortfallProbability || a.SustainableRate != b.SustainableRate {
+		t.Errorf("same seed gave shortfall %v/%v, sustainable rate %v/%v",
+			a.ShortfallProbability, b.ShortfallProbability, a.SustainableRate, b.SustainableRate)
+	}
+
+	c := run(2)
+	if reflect.DeepEqual(a.Bands, c.Bands) {
+		t.Errorf("different seeds gave identical bands: %v", a.Bands)
+	}
+	if a.SustainableRate == c.SustainableRate {
+		t.Errorf("different seeds gave identical sustainable rate %v", a.SustainableRate)
+	}
+}
+
+func TestMonteCarloZeroVolatility(t *testing.T) {
+	p := parametricParams()
+	p.StockVolatility = 0
+	p.BondVolatility = 0
+	result, err := (&monteCarlo{params: p}).run()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 没有波动时每条路径相同，期末金额为固定月收益下的年金终值
+	w := p.StockRatio / 100
+	r := w*(math.Pow(1+p.StockReturn/100, 1.0/12)-1) + (1-w)*(math.Pow(1+p.BondReturn/100, 1.0/12)-1)
+	n := float64(p.Years * 12)
+	growth := math.Pow(1+r, n)
+	want := p.InitialValue*growth + p.MonthlyContribution*(growth-1)/r
+
+	for i, got := range result.FinalPercentiles {
+		if math.Abs(got-want)/want > 1e-9 {
+			t.Errorf("percentile %v = %.4f, want %.4f", monteCarloPercentiles[i], got, want)
+		}
+	}
+	if result.ShortfallProbability != 0 || result.DepletionProbability != 0 {
+		t.Errorf("got shortfall %v%%, depletion %v%%, want 0", result.ShortfallProbability, result.DepletionProbability)
+	}
+}
+
+func TestPercentiles(t *testing.T) {
+	values := []float64{5, 1, 4, 2, 3}
+	tests := []struct {
+		p    float64
+		want float64
+	}{
+		{p: 0, want: 1},
+		{p: 25, want: 2},
+		{p: 50, want: 3},
+		{p: 90, want: 4.6},
+		{p: 100, want: 5},
+	}
+	for _, tt := range tests {
+		got := percentiles(values, []float64{tt.p})[0]
+		if math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("percentile %v = %v, want %v", tt.p, got, tt.want)
+		}
+	}
+	if !reflect.DeepEqual(values, []float64{5, 1, 4, 2, 3}) {
+		t.Errorf("percentiles modified its input: %v", values)
+	}
+}
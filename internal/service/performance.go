@@ -0,0 +1,243 @@
+package service
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+const daysPerYear = 365.25
+
+// datedFlow 带日期的净流入（正数为流入组合，负数为流出）
+type datedFlow struct {
+	Date   time.Time
+	Amount float64
+}
+
+// valuePoint 某一时点的市值
+type valuePoint struct {
+	Date  time.Time
+	Value float64
+}
+
+// Performance 一段时间内的收益和风险指标，比例均为百分数
+type Performance struct {
+	Start         time.Time
+	End           time.Time
+	StartValue    float64
+	EndValue      float64
+	NetFlow       float64 // 期间净流入
+	Periods       int     // 子区间数（相邻快照之间）
+	TWR           float64 // 时间加权收益率
+	AnnualizedTWR float64
+	XIRR          float64 // 资金加权收益率（年化）
+	XIRRKnown     bool
+	Volatility    float64 // 年化波动率
+	MaxDrawdown   float64 // 最大回撤，为正数
+	DrawdownStart time.Time
+	DrawdownEnd   time.Time
+	DrawdownDays  int  // 最长回撤持续天数（从高点到收复，未收复时算到期末）
+	Recovered     bool // 最大回撤是否已收复
+	Sharpe        float64
+	SharpeKnown   bool
+	Dates         []time.Time
+	Growth        []float64 // 时间加权净值（期初为 1）
+}
+
+// measurePerformance 根据市值序列和期间资金流计算收益指标。
+// 子区间收益采用修正 Dietz 法（按流入发生的时间加权），串联得到时间加权收益率。
+// 资金流水只有日期，按天归入子区间：快照当天的流水视为发生在快照之后，计入下一个子区间
+func measurePerformance(points []valuePoint, flows []datedFlow, riskFree float64) (*Performance, error) {
+	if len(points) < 2 {
+		return nil, errors.New("区间内至少需要两个快照")
+	}
+
+	first, last := points[0], points[len(points)-1]
+	p := &Performance{
+		Start:      first.Date,
+		End:        last.Date,
+		StartValue: first.Value,
+		EndValue:   last.Value,
+		Periods:    len(points) - 1,
+		Dates:      make([]time.Time, len(points)),
+		Growth:     make([]float64, len(points)),
+	}
+	p.Dates[0], p.Growth[0] = first.Date, 1
+
+	returns := make([]float64, 0, len(points)-1)
+	next := 0
+	for i := 1; i < len(points); i++ {
+		prev, cur := points[i-1], points[i]
+		span := cur.Date.Sub(prev.Date).Seconds()
+
+		var net, weighted float64
+		for next < len(flows) && localDay(flows[next].Date).Before(localDay(cur.Date)) {
+			f := flows[next]
+			next++
+			if localDay(f.Date).Before(localDay(prev.Date)) {
+				continue
+			}
+			net += f.Amount
+			if span > 0 {
+				weighted += f.Amount * cur.Date.Sub(flowTime(f, prev)).Seconds() / span
+			}
+		}
+		p.NetFlow += net
+
+		var r float64
+		if base := prev.Value + weighted; base > 0 {
+			r = (cur.Value - prev.Value - net) / base
+		}
+		returns = append(returns, r)
+		p.Dates[i] = cur.Date
+		p.Growth[i] = p.Growth[i-1] * (1 + r)
+	}
+
+	years := last.Date.Sub(first.Date).Hours() / 24 / daysPerYear
+	p.TWR = (p.Growth[len(p.Growth)-1] - 1) * 100
+	if years > 0 && p.Growth[len(p.Growth)-1] > 0 {
+		p.AnnualizedTWR = (math.Pow(p.Growth[len(p.Growth)-1], 1/years) - 1) * 100
+	}
+
+	// 子区间长度不固定，按平均长度年化
+	if len(returns) >= 2 && years > 0 {
+		var sum float64
+		for _, r := range returns {
+			sum += r
+		}
+		mean := sum / float64(len(returns))
+		var sq float64
+		for _, r := range returns {
+			sq += (r - mean) * (r - mean)
+		}
+		periodYears := years / float64(len(returns))
+		p.Volatility = math.Sqrt(sq/float64(len(returns)-1)/periodYears) * 100
+	}
+	if p.Volatility > 0 {
+		p.Sharpe = (p.AnnualizedTWR - riskFree) / p.Volatility
+		p.SharpeKnown = true
+	}
+
+	p.measureDrawdown()
+	p.XIRR, p.XIRRKnown = xirr(first, last, flows)
+	return p, nil
+}
+
+// measureDrawdown 在时间加权净值上计算最大回撤和最长回撤持续时间
+func (p *Performance) measureDrawdown() {
+	peak, peakAt := p.Growth[0], 0
+	p.Recovered = true
+	for i, v := range p.Growth {
+		if v >= peak {
+			if days := int(p.Dates[i].Sub(p.Dates[peakAt]).Hours() / 24); days > p.DrawdownDays && i > peakAt+1 {
+				p.DrawdownDays = days
+			}
+			peak, peakAt = v, i
+			continue
+		}
+		if dd := (1 - v/peak) * 100; dd > p.MaxDrawdown {
+			p.MaxDrawdown = dd
+			p.DrawdownStart = p.Dates[peakAt]
+			p.DrawdownEnd = p.Dates[i]
+		}
+	}
+
+	// 期末仍处于回撤中
+	lastAt := len(p.Growth) - 1
+	if peakAt < lastAt {
+		if days := int(p.Dates[lastAt].Sub(p.Dates[peakAt]).Hours() / 24); days > p.DrawdownDays {
+			p.DrawdownDays = days
+		}
+		if !p.DrawdownStart.Before(p.Dates[peakAt]) {
+			p.Recovered = false
+		}
+	}
+}
+
+// xirr 计算资金加权收益率（年化，%）：期初市值视为投入，期间流入为投入、流出为取回，期末市值视为取回
+func xirr(first, last valuePoint, flows []datedFlow) (float64, bool) {
+	type cash struct {
+		years  float64
+		amount float64
+	}
+	cashes := []cash{{0, -first.Value}}
+	for _, f := range flows {
+		if localDay(f.Date).Before(localDay(first.Date)) || !localDay(f.Date).Before(localDay(last.Date)) {
+			continue
+		}
+		cashes = append(cashes, cash{flowTime(f, first).Sub(first.Date).Hours() / 24 / daysPerYear, -f.Amount})
+	}
+	cashes = append(cashes, cash{last.Date.Sub(first.Date).Hours() / 24 / daysPerYear, last.Value})
+
+	npv := func(rate float64) float64 {
+		var v float64
+		for _, c := range cashes {
+			v += c.amount / math.Pow(1+rate, c.years)
+		}
+		return v
+	}
+
+	// 净现值随收益率单调递减时二分查找
+	lo, hi := -0.9999, 10.0
+	if npv(lo)*npv(hi) > 0 {
+		return 0, false
+	}
+	for i := 0; i < 200 && hi-lo > 1e-10; i++ {
+		mid := (lo + hi) / 2
+		if npv(lo)*npv(mid) <= 0 {
+			hi = mid
+		} else {
+			lo = mid
+		}
+	}
+	return (lo + hi) / 2 * 100, true
+}
+
+// localDay 时间所在的本地日期（零点）
+func localDay(t time.Time) time.Time {
+	t = t.Local()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+}
+
+// flowTime 流水在子区间内的发生时间，与区间起点快照同一天的流水记为紧接快照之后
+func flowTime(f datedFlow, start valuePoint) time.Time {
+	if f.Date.Before(start.Date) {
+		return start.Date
+	}
+	return f.Date
+}
+
+// ToMap 转换为前端展示的结构
+func (p *Performance) ToMap() map[string]interface{} {
+	dates := make([]string, len(p.Dates))
+	for i, d := range p.Dates {
+		dates[i] = d.Format("2006-01-02")
+	}
+	result := map[string]interface{}{
+		"start_date":     p.Start.Format("2006-01-02"),
+		"end_date":       p.End.Format("2006-01-02"),
+		"start_value":    p.StartValue,
+		"end_value":      p.EndValue,
+		"net_flow":       p.NetFlow,
+		"periods":        p.Periods,
+		"twr":            p.TWR,
+		"annualized_twr": p.AnnualizedTWR,
+		"volatility":     p.Volatility,
+		"max_drawdown":   p.MaxDrawdown,
+		"drawdown_days":  p.DrawdownDays,
+		"recovered":      p.Recovered,
+		"dates":          dates,
+		"growth":         p.Growth,
+	}
+	if p.XIRRKnown {
+		result["xirr"] = p.XIRR
+	}
+	if p.SharpeKnown {
+		result["sharpe"] = p.Sharpe
+	}
+	if p.MaxDrawdown > 0 {
+		result["drawdown_start"] = p.DrawdownStart.Format("2006-01-02")
+		result["drawdown_end"] = p.DrawdownEnd.Format("2006-01-02")
+	}
+	return result
+}
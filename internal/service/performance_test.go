@@ -0,0 +1,60 @@
+package service
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestMeasurePerformanceFlowDays(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2024, 3, d, 0, 0, 0, 0, time.Local)
+	}
+	at := func(d, hour int) time.Time {
+		return day(d).Add(time.Duration(hour) * time.Hour)
+	}
+	points := []valuePoint{
+		{at(1, 10), 1000},
+		{at(2, 10), 1500},
+		{at(3, 10), 1650},
+	}
+
+	tests := []struct {
+		name    string
+		flows   []datedFlow
+		wantNet float64
+		wantTWR float64
+	}{
+		{
+			// 第一个快照当天的存入发生在快照之后，计入第一个子区间
+			name:    "deposit on first snapshot day",
+			flows:   []datedFlow{{day(1), 500}},
+			wantNet: 500,
+			wantTWR: 10,
+		},
+		{
+			// 最后一个快照当天的存入发生在快照之后，不计入区间
+			name:    "deposit on last snapshot day",
+			flows:   []datedFlow{{day(1), 500}, {day(3), 200}},
+			wantNet: 500,
+			wantTWR: 10,
+		},
+		{
+			name:    "deposit before first snapshot day",
+			flows:   []datedFlow{{day(0), 300}, {day(1), 500}},
+			wantNet: 500,
+			wantTWR: 10,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := measurePerformance(points, tt.flows, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if math.Abs(p.NetFlow-tt.wantNet) > 1e-9 || math.Abs(p.TWR-tt.wantTWR) > 1e-9 {
+				t.Errorf("net flow = %v, TWR = %v%%, want %v, %v%%", p.NetFlow, p.TWR, tt.wantNet, tt.wantTWR)
+			}
+		})
+	}
+}
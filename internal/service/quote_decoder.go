@@ -0,0 +1,131 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// defaultQuotePrecision 接口没有返回 f152 时的价格小数位数
+const defaultQuotePrecision = 2
+
+// push2Number push2 接口的数值字段。接口以整数返回（按小数位数放大），没有数据时返回 "-"
+type push2Number struct {
+	Value float64
+	Valid bool
+}
+
+func (n *push2Number) UnmarshalJSON(b []byte) error {
+	b = bytes.TrimSpace(b)
+	if bytes.Equal(b, []byte("null")) || bytes.Equal(b, []byte(`"-"`)) || bytes.Equal(b, []byte(`""`)) {
+		*n = push2Number{}
+		return nil
+	}
+	var v float64
+	if err := json.Unmarshal(b, &v); err != nil {
+		return fmt.Errorf("无效的数值 %s", b)
+	}
+	*n = push2Number{Value: v, Valid: true}
+	return nil
+}
+
+// scaled 按小数位数还原为实际数值
+func (n push2Number) scaled(precision int) float64 {
+	return n.Value / math.Pow10(precision)
+}
+
+// push2QuoteResponse push2 实时行情接口（api/qt/stock/get）的响应
+type push2QuoteResponse struct {
+	RC   int `json:"rc"`
+	Data *struct {
+		Price      push2Number `json:"f43"`  // 最新价
+		High       push2Number `json:"f44"`  // 最高
+		Low        push2Number `json:"f45"`  // 最低
+		Open       push2Number `json:"f46"`  // 今开
+		Volume     push2Number `json:"f47"`  // 成交量
+		Amount     push2Number `json:"f48"`  // 成交额
+		Code       string      `json:"f57"`  // 代码
+		Name       string      `json:"f58"`  // 名称
+		PrevClose  push2Number `json:"f60"`  // 昨收
+		Timestamp  push2Number `json:"f86"`  // 行情时间（Unix 秒）
+		Precision  push2Number `json:"f152"` // 价格小数位数
+		Change     push2Number `json:"f169"` // 涨跌额
+		ChangeRate push2Number `json:"f170"` // 涨跌幅，固定放大 100 倍
+	} `json:"data"`
+}
+
+// quote 解码后的实时行情，价格均已按小数位数还原，没有数据的字段为 0
+type quote struct {
+	Code       string
+	Name       string
+	Price      float64
+	Change     float64
+	ChangeRate float64 // 百分数
+	Open       float64
+	High       float64
+	Low        float64
+	PrevClose  float64
+	Volume     float64
+	Amount     float64
+	Time       time.Time // 交易所行情时间，接口没有返回时为零值
+}
+
+// decodeQuote 解析 push2 实时行情响应
+// 返回格式: {"rc":0,"rt":4,"svr":...,"data":{"f43":465417,"f152":2,"f169":-4451,"f170":-95,...}}
+func decodeQuote(body []byte) (*quote, error) {
+	var resp push2QuoteResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("解析行情失败: %w", err)
+	}
+	if resp.RC != 0 {
+		return nil, fmt.Errorf("行情接口返回错误: rc=%d", resp.RC)
+	}
+	d := resp.Data
+	if d == nil {
+		return nil, errors.New("行情数据为空，请检查行情 ID")
+	}
+	if !d.Price.Valid {
+		return nil, errors.New("行情缺少最新价")
+	}
+
+	precision := defaultQuotePrecision
+	if d.Precision.Valid {
+		precision = int(d.Precision.Value)
+		if precision < 0 || precision > 8 {
+			return nil, fmt.Errorf("无效的价格精度: %d", precision)
+		}
+	}
+
+	q := &quote{
+		Code:      d.Code,
+		Name:      d.Name,
+		Price:     d.Price.scaled(precision),
+		Open:      d.Open.scaled(precision),
+		High:      d.High.scaled(precision),
+		Low:       d.Low.scaled(precision),
+		PrevClose: d.PrevClose.scaled(precision),
+		Volume:    d.Volume.Value,
+		Amount:    d.Amount.Value,
+	}
+	if d.Timestamp.Valid && d.Timestamp.Value > 0 {
+		q.Time = time.Unix(int64(d.Timestamp.Value), 0)
+	}
+
+	// 涨跌额和涨跌幅缺失时按昨收计算
+	switch {
+	case d.Change.Valid:
+		q.Change = d.Change.scaled(precision)
+	case q.PrevClose > 0:
+		q.Change = q.Price - q.PrevClose
+	}
+	switch {
+	case d.ChangeRate.Valid:
+		q.ChangeRate = d.ChangeRate.scaled(2)
+	case q.PrevClose > 0:
+		q.ChangeRate = q.Change / q.PrevClose * 100
+	}
+	return q, nil
+}
@@ -0,0 +1,71 @@
+package service
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestDecodeQuote(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    quote
+		wantErr bool
+	}{
+		{
+			name: "f152=2",
+			body: `{"rc":0,"rt":4,"data":{"f43":465417,"f44":466820,"f45":462730,"f46":465000,"f57":"000300","f58":"沪深300","f60":469868,"f86":1760943600,"f152":2,"f169":-4451,"f170":-95}}`,
+			want: quote{Code: "000300", Name: "沪深300", Price: 4654.17, High: 4668.2, Low: 4627.3, Open: 4650, PrevClose: 4698.68,
+				Change: -44.51, ChangeRate: -0.95, Time: time.Unix(1760943600, 0)},
+		},
+		{
+			name: "f152=0",
+			body: `{"rc":0,"data":{"f43":26858,"f57":"HSI","f58":"恒生指数","f60":26537,"f152":0,"f169":321,"f170":121}}`,
+			want: quote{Code: "HSI", Name: "恒生指数", Price: 26858, PrevClose: 26537, Change: 321, ChangeRate: 1.21},
+		},
+		{
+			name: "f152=3",
+			body: `{"rc":0,"data":{"f43":3120456,"f57":"000012","f58":"国债指数","f60":3119856,"f152":3,"f169":600,"f170":2}}`,
+			want: quote{Code: "000012", Name: "国债指数", Price: 3120.456, PrevClose: 3119.856, Change: 0.6, ChangeRate: 0.02},
+		},
+		{
+			// 涨跌额和涨跌幅缺失时按昨收计算
+			name: "missing change",
+			body: `{"rc":0,"data":{"f43":660000,"f44":"-","f45":"-","f46":"-","f57":"SPX","f58":"标普500","f60":640000,"f86":"-","f152":2,"f169":"-","f170":"-"}}`,
+			want: quote{Code: "SPX", Name: "标普500", Price: 6600, PrevClose: 6400, Change: 200, ChangeRate: 3.125},
+		},
+		{
+			name: "missing precision",
+			body: `{"rc":0,"data":{"f43":388012,"f57":"000001","f58":"上证指数","f60":"-","f169":1012,"f170":26}}`,
+			want: quote{Code: "000001", Name: "上证指数", Price: 3880.12, Change: 10.12, ChangeRate: 0.26},
+		},
+		{name: "missing price", body: `{"rc":0,"data":{"f43":"-","f57":"000300","f152":2}}`, wantErr: true},
+		{name: "rc not zero", body: `{"rc":102,"data":null}`, wantErr: true},
+		{name: "empty data", body: `{"rc":0,"data":null}`, wantErr: true},
+		{name: "invalid precision", body: `{"rc":0,"data":{"f43":1,"f152":12}}`, wantErr: true},
+		{name: "invalid number", body: `{"rc":0,"data":{"f43":"abc"}}`, wantErr: true},
+	}
+
+	near := func(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeQuote([]byte(tt.body))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			w := tt.want
+			if got.Code != w.Code || got.Name != w.Name || !got.Time.Equal(w.Time) ||
+				!near(got.Price, w.Price) || !near(got.High, w.High) || !near(got.Low, w.Low) || !near(got.Open, w.Open) ||
+				!near(got.PrevClose, w.PrevClose) || !near(got.Change, w.Change) || !near(got.ChangeRate, w.ChangeRate) {
+				t.Errorf("got %+v\nwant %+v", *got, w)
+			}
+		})
+	}
+}
@@ -0,0 +1,314 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"margin/internal/crypto"
+	"margin/internal/model"
+	"margin/internal/repo"
+	"math"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 再平衡计划状态（由交易状态汇总得出）
+const (
+	PlanStatusRecord     = "record"      // 仅记录，没有交易
+	PlanStatusPending    = "pending"     // 交易均未开始
+	PlanStatusInProgress = "in_progress" // 部分交易已提交或成交
+	PlanStatusCompleted  = "completed"   // 全部交易已成交或取消
+)
+
+// planStatus 汇总交易状态：全部成交或取消才算完成
+func planStatus(orders []model.RebalanceOrder) (status string, done int) {
+	if len(orders) == 0 {
+		return PlanStatusRecord, 0
+	}
+
+	started := false
+	for _, o := range orders {
+		switch o.Status {
+		case model.OrderStatusFilled, model.OrderStatusCancelled:
+			done++
+			started = true
+		case model.OrderStatusSubmitted:
+			started = true
+		}
+	}
+
+	switch {
+	case done == len(orders):
+		return PlanStatusCompleted, done
+	case started:
+		return PlanStatusInProgress, done
+	default:
+		return PlanStatusPending, done
+	}
+}
+
+// CreateRebalancePlan 按当前持仓生成基金级交易计划并保存，返回再平衡记录 ID
+func (s *RebalanceService) CreateRebalancePlan(ctx context.Context, targetStockRatio, minTrade float64, note string) (uint, error) {
+	return s.saveRebalance(ctx, targetStockRatio, note, func(holdings []Holding) ([]PlanOrder, error) {
+		fees, err := s.assetService.loadFeeModel(ctx)
+		if err != nil {
+			return nil, err
+		}
+		plan := planRebalance(holdings, targetStockRatio, minTrade, fees)
+		if len(plan.Orders) == 0 {
+			return nil, errors.New("当前持仓无需交易")
+		}
+		return plan.Orders, nil
+	})
+}
+
+// GetRebalanceOrders 获取再平衡计划的交易及执行情况
+func (s *RebalanceService) GetRebalanceOrders(ctx context.Context, rebalanceID uint) ([]map[string]interface{}, error) {
+	orders, err := s.rebalanceRepo.GetOrders(ctx, rebalanceID)
+	if err != nil {
+		return nil, err
+	}
+
+	encryptKey, err := s.configRepo.Get(ctx, model.ConfigKeyEncryptKey)
+	if err != nil {
+		return nil, err
+	}
+
+	formatTime := func(t *time.Time, layout string) string {
+		if t == nil {
+			return ""
+		}
+		return t.Format(layout)
+	}
+
+	result := make([]map[string]interface{}, 0, len(orders))
+	for _, o := range orders {
+		amount, err := decryptAmount(o.EncryptedAmount, encryptKey.Value)
+		if err != nil {
+			return nil, err
+		}
+		filled, err := decryptAmount(o.EncryptedFilledAmount, encryptKey.Value)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, map[string]interface{}{
+			"id":            o.ID,
+			"rebalance_id":  o.RebalanceID,
+			"asset_id":      o.AssetID,
+			"code":          o.Code,
+			"name":          o.Name,
+			"type":          o.Type,
+			"source":        o.Source,
+			"action":        o.Action,
+			"amount":        amount,
+			"filled_amount": filled,
+			"status":        o.Status,
+			"submitted_at":  formatTime(o.SubmittedAt, "2006-01-02 15:04:05"),
+			"filled_at":     formatTime(o.FilledAt, "2006-01-02"),
+		})
+	}
+	return result, nil
+}
+
+// UpdateOrderStatus 更新交易状态（pending/submitted/cancelled），成交请使用 FillOrder
+func (s *RebalanceService) UpdateOrderStatus(ctx context.Context, orderID uint, status string) error {
+	switch status {
+	case model.OrderStatusPending, model.OrderStatusSubmitted, model.OrderStatusCancelled:
+	case model.OrderStatusFilled:
+		return errors.New("请通过确认成交录入实际金额")
+	default:
+		return fmt.Errorf("不支持的交易状态: %s", status)
+	}
+
+	order, err := s.rebalanceRepo.GetOrder(ctx, orderID)
+	if err != nil {
+		return err
+	}
+	if order.Status == model.OrderStatusFilled {
+		return errors.New("交易已成交，不能修改状态")
+	}
+
+	order.Status = status
+	if status == model.OrderStatusSubmitted {
+		now := time.Now()
+		order.SubmittedAt = &now
+	}
+	return s.rebalanceRepo.UpdateOrder(ctx, order)
+}
+
+// FillOrder 确认交易成交，录入实际金额和日期（2006-01-02），并同步更新对应资产的持仓金额。
+// 买入的资产已录入持仓批次时，按成交日期新增一个批次；卖出时按估算赎回费的顺序从批次中扣减
+func (s *RebalanceService) FillOrder(ctx context.Context, orderID uint, amount float64, filledDate string) error {
+	if amount <= 0 {
+		return errors.New("成交金额必须大于 0")
+	}
+	date, err := time.ParseInLocation("2006-01-02", filledDate, time.Local)
+	if err != nil {
+		return fmt.Errorf("成交日期格式错误: %w", err)
+	}
+
+	encryptKey, err := s.configRepo.Get(ctx, model.ConfigKeyEncryptKey)
+	if err != nil {
+		return err
+	}
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		rebalanceRepo := repo.NewRebalanceRepository(tx)
+		assetRepo := repo.NewAssetRepository(tx)
+		lotRepo := repo.NewLotRepository(tx)
+
+		order, err := rebalanceRepo.GetOrder(ctx, orderID)
+		if err != nil {
+			return err
+		}
+		if order.Status == model.OrderStatusFilled || order.Status == model.OrderStatusCancelled {
+			return errors.New("交易已成交或已取消")
+		}
+
+		var asset model.Asset
+		if err := tx.First(&asset, order.AssetID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("资产 %s（%s）已不存在", order.Name, order.Source)
+			}
+			return err
+		}
+
+		amountStr, err := crypto.Decrypt(asset.EncryptedAmount, encryptKey.Value)
+		if err != nil {
+			return err
+		}
+		current, _ := strconv.ParseFloat(amountStr, 64)
+
+		updated := current + amount
+		if order.Action == OrderActionSell {
+			if amount > current+0.01 {
+				return fmt.Errorf("卖出金额 %.2f 超过当前持仓 %.2f", amount, current)
+			}
+			updated = max(current-amount, 0)
+		}
+
+		encrypted, err := crypto.Encrypt(fmt.Sprintf("%.2f", updated), encryptKey.Value)
+		if err != nil {
+			return err
+		}
+		asset.EncryptedAmount = encrypted
+		if err := assetRepo.Update(ctx, &asset); err != nil {
+			return err
+		}
+
+		if order.Action == OrderActionSell {
+			if err := sellLots(ctx, tx, encryptKey.Value, &Holding{Asset: asset, Amount: current}, amount, date); err != nil {
+				return err
+			}
+		}
+
+		if order.Action == OrderActionBuy {
+			lots, err := lotRepo.GetByAsset(ctx, asset.ID)
+			if err != nil {
+				return err
+			}
+			if len(lots) > 0 {
+				lotAmount, err := crypto.Encrypt(fmt.Sprintf("%.2f", amount), encryptKey.Value)
+				if err != nil {
+					return err
+				}
+				if err := lotRepo.Create(ctx, &model.AssetLot{
+					AssetID:         asset.ID,
+					BuyDate:         date,
+					EncryptedAmount: lotAmount,
+				}); err != nil {
+					return err
+				}
+			}
+		}
+
+		filled, err := crypto.Encrypt(fmt.Sprintf("%.2f", amount), encryptKey.Value)
+		if err != nil {
+			return err
+		}
+
+		// 记录为组合内部调仓，用于按类别和来源计算收益
+		kind := model.CashFlowTransferIn
+		if order.Action == OrderActionSell {
+			kind = model.CashFlowTransferOut
+		}
+		if err := repo.NewCashFlowRepository(tx).Create(ctx, &model.CashFlow{
+			Date:            date,
+			Kind:            kind,
+			AssetID:         asset.ID,
+			Code:            asset.Code,
+			Name:            asset.Name,
+			Type:            asset.Type,
+			Source:          asset.Source,
+			EncryptedAmount: filled,
+			OrderID:         order.ID,
+			Note:            "再平衡成交",
+		}); err != nil {
+			return err
+		}
+		order.EncryptedFilledAmount = filled
+		order.Status = model.OrderStatusFilled
+		order.FilledAt = &date
+		return rebalanceRepo.UpdateOrder(ctx, order)
+	})
+}
+
+// GetOpenRebalancePlans 获取尚未完成的再平衡计划
+func (s *RebalanceService) GetOpenRebalancePlans(ctx context.Context) ([]map[string]interface{}, error) {
+	history, err := s.GetRebalanceHistory(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]map[string]interface{}, 0)
+	for _, item := range history {
+		if status := item["status"]; status == PlanStatusPending || status == PlanStatusInProgress {
+			result = append(result, item)
+		}
+	}
+	return result, nil
+}
+
+// sellLots 从持仓批次中扣除卖出的金额，顺序与 planSale 一致：费率已知的批次优先，再按费率从低到高、
+// 买入日期从早到晚。批次金额为买入成本，按持仓市值与成本的比例折算；扣完的批次删除
+func sellLots(ctx context.Context, tx *gorm.DB, key string, h *Holding, amount float64, date time.Time) error {
+	lotRepo := repo.NewLotRepository(tx)
+	m, err := loadFeeModel(ctx, repo.NewFeeRuleRepository(tx), lotRepo, key)
+	if err != nil {
+		return err
+	}
+	lots := m.lots[h.Asset.ID]
+	if len(lots) == 0 || h.Amount <= 0 {
+		return nil
+	}
+	m.now = date
+
+	cost := make(map[uint]float64, len(lots))
+	var total float64
+	for _, l := range lots {
+		cost[l.LotID] = l.Value
+		total += l.Value
+	}
+
+	sales, _, _ := m.planSale(h, math.Min(amount, h.Amount))
+	for _, sale := range sales {
+		value := cost[sale.LotID] * h.Amount / total
+		if sale.Amount >= value-0.01 {
+			if err := lotRepo.Delete(ctx, sale.LotID); err != nil {
+				return err
+			}
+			continue
+		}
+		remaining := cost[sale.LotID] * (1 - sale.Amount/value)
+		encrypted, err := crypto.Encrypt(fmt.Sprintf("%.2f", remaining), key)
+		if err != nil {
+			return err
+		}
+		if err := lotRepo.UpdateAmount(ctx, sale.LotID, encrypted); err != nil {
+			return err
+		}
+	}
+	return nil
+}
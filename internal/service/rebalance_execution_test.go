@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"testing"
+	"time"
+
+	"margin/internal/crypto"
+	"margin/internal/model"
+	"margin/internal/repo"
+)
+
+func TestFillOrderSellsLots(t *testing.T) {
+	g := newTestDB(t)
+	ctx := context.Background()
+	encrypt := func(v float64) string {
+		encrypted, err := crypto.Encrypt(strconv.FormatFloat(v, 'f', 2, 64), testEncryptKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return encrypted
+	}
+	create := func(v interface{}) {
+		if err := g.Create(v).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	fillDate := time.Date(2024, 6, 3, 0, 0, 0, 0, time.Local)
+	asset := &model.Asset{Code: "000001", Name: "华夏成长混合", Type: "stock", Source: "天天基金", EncryptedAmount: encrypt(3000)}
+	create(asset)
+	for _, r := range []model.FeeRule{
+		{Code: "000001", Kind: model.FeeKindRedemption, MinDays: 0, MaxDays: 7, Rate: 1.5},
+		{Code: "000001", Kind: model.FeeKindRedemption, MinDays: 7, MaxDays: 365, Rate: 0.5},
+		{Code: "000001", Kind: model.FeeKindRedemption, MinDays: 365, Rate: 0},
+	} {
+		create(&r)
+	}
+	// 批次金额为买入成本，持仓市值 3000 是成本 2000 的 1.5 倍
+	recent := &model.AssetLot{AssetID: asset.ID, BuyDate: fillDate.AddDate(0, 0, -3), EncryptedAmount: encrypt(1000)}
+	old := &model.AssetLot{AssetID: asset.ID, BuyDate: fillDate.AddDate(-2, 0, 0), EncryptedAmount: encrypt(1000)}
+	create(recent)
+	create(old)
+
+	rebalance := &model.Rebalance{StockRatio: 60, BondRatio: 40, TargetStockRatio: 50, TargetBondRatio: 50}
+	create(rebalance)
+	order := &model.RebalanceOrder{
+		RebalanceID: rebalance.ID, AssetID: asset.ID, Code: asset.Code, Name: asset.Name, Type: asset.Type,
+		Source: asset.Source, Action: OrderActionSell, EncryptedAmount: encrypt(1800), Status: model.OrderStatusPending,
+	}
+	create(order)
+
+	// 先卖出免赎回费的老批次（市值 1500），剩余 300 从新批次中扣除，折合成本 200
+	s := NewRebalanceService(g, NewAssetService(g), NewValuationService(g))
+	if err := s.FillOrder(ctx, order.ID, 1800, fillDate.Format("2006-01-02")); err != nil {
+		t.Fatal(err)
+	}
+
+	lots, err := repo.NewLotRepository(g).GetByAsset(ctx, asset.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lots) != 1 || lots[0].ID != recent.ID {
+		t.Fatalf("got lots %+v, want only the recent lot", lots)
+	}
+	plain, err := crypto.Decrypt(lots[0].EncryptedAmount, testEncryptKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if amount, _ := strconv.ParseFloat(plain, 64); math.Abs(amount-800) > 0.005 {
+		t.Errorf("recent lot amount = %v, want 800", amount)
+	}
+
+	holdings, err := NewAssetService(g).LoadHoldings(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(holdings) != 1 || math.Abs(holdings[0].Amount-1200) > 0.005 {
+		t.Errorf("got holdings %+v, want 1200 left", holdings)
+	}
+}
@@ -0,0 +1,312 @@
+package service
+
+import (
+	"margin/internal/model"
+	"math"
+	"sort"
+)
+
+// 交易方向
+const (
+	OrderActionBuy  = "buy"
+	OrderActionSell = "sell"
+)
+
+// PlanOrder 再平衡计划中的一笔基金交易
+type PlanOrder struct {
+	AssetID uint    `json:"asset_id"`
+	Code    string  `json:"code"`
+	Name    string  `json:"name"`
+	Source  string  `json:"source"`
+	Type    string  `json:"type"`
+	Action  string  `json:"action"` // buy/sell
+	Amount  float64 `json:"amount"`
+
+	EstimatedFee float64   `json:"estimated_fee"` // 预估交易费用
+	FeeRate      float64   `json:"fee_rate"`      // 综合费率(%)
+	FeeKnown     bool      `json:"fee_known"`     // 是否找到适用的费率
+	Lots         []LotSale `json:"lots"`          // 卖出时的批次明细
+}
+
+// classPlan 单个类别（股票/债券）的调整情况
+type classPlan struct {
+	Type        string  `json:"type"`
+	Current     float64 `json:"current"`     // 当前金额
+	Target      float64 `json:"target"`      // 目标金额
+	Adjust      float64 `json:"adjust"`      // 需调整金额
+	Planned     float64 `json:"planned"`     // 计划调整金额（买入为正）
+	Unallocated float64 `json:"unallocated"` // 无法分配到具体基金的金额
+	Weighted    bool    `json:"weighted"`    // 是否按目标权重分配
+	Unweighted  float64 `json:"unweighted"`  // 按权重分配时，未设置权重、保持不动的基金金额
+}
+
+// RebalancePlan 基金级别的再平衡计划
+type RebalancePlan struct {
+	TargetStockRatio float64     `json:"target_stock_ratio"`
+	Total            float64     `json:"total"`
+	Orders           []PlanOrder `json:"orders"`
+	Classes          []classPlan `json:"classes"`
+	EstimatedFee     float64     `json:"estimated_fee"` // 预估交易费用合计
+	FeeKnown         bool        `json:"fee_known"`
+}
+
+// planRebalance 计算达到目标股债比例所需的基金交易。
+// 类别内设置了目标权重时按权重分配（未设置权重的基金保持不动）；否则保持基金间现有比例，
+// 并把调整集中到尽量少的基金上，以减少交易笔数。fees 不为空时估算交易费用，
+// 并优先卖出赎回费低的基金和批次
+func planRebalance(holdings []Holding, targetStockRatio, minTrade float64, fees *feeModel) *RebalancePlan {
+	stockTotal, bondTotal := portfolioTotals(holdings)
+	total := stockTotal + bondTotal
+
+	plan := &RebalancePlan{
+		TargetStockRatio: targetStockRatio,
+		Total:            total,
+		Orders:           []PlanOrder{},
+	}
+
+	targets := map[string]float64{
+		model.AssetTypeStock: total * targetStockRatio / 100,
+		model.AssetTypeBond:  total * (100 - targetStockRatio) / 100,
+	}
+
+	for _, class := range []string{model.AssetTypeStock, model.AssetTypeBond} {
+		members := classHoldings(holdings, class)
+		orders, cp := planClass(members, targets[class], minTrade, fees)
+		cp.Type = class
+		plan.Orders = append(plan.Orders, orders...)
+		plan.Classes = append(plan.Classes, cp)
+	}
+
+	// 先卖后买，便于用卖出资金买入
+	sort.SliceStable(plan.Orders, func(i, j int) bool {
+		if plan.Orders[i].Action != plan.Orders[j].Action {
+			return plan.Orders[i].Action == OrderActionSell
+		}
+		return plan.Orders[i].Amount > plan.Orders[j].Amount
+	})
+	plan.EstimatedFee, plan.FeeKnown = totalFees(plan.Orders)
+
+	return plan
+}
+
+// classHoldings 返回属于指定类别的持仓（非股票类均视为债券）
+func classHoldings(holdings []Holding, class string) []*Holding {
+	var members []*Holding
+	for i := range holdings {
+		isStock := holdings[i].Asset.Type == model.AssetTypeStock
+		if (class == model.AssetTypeStock) == isStock {
+			members = append(members, &holdings[i])
+		}
+	}
+	return members
+}
+
+func planClass(members []*Holding, target, minTrade float64, fees *feeModel) ([]PlanOrder, classPlan) {
+	var current, weightSum float64
+	for _, h := range members {
+		current += h.Amount
+		weightSum += h.Asset.TargetWeight
+	}
+
+	cp := classPlan{
+		Current:  current,
+		Target:   target,
+		Adjust:   target - current,
+		Weighted: weightSum > 0,
+	}
+
+	if math.Abs(cp.Adjust) < minTrade && !cp.Weighted {
+		return nil, cp
+	}
+	if len(members) == 0 {
+		cp.Unallocated = cp.Adjust
+		return nil, cp
+	}
+
+	// 按权重分配时只调整设置了权重的基金：未设置权重（0）的基金保持现有金额，
+	// 其余基金按权重分配类别目标中剩下的部分，不足时差额计入未分配
+	adjust := cp.Adjust
+	if cp.Weighted {
+		pool := target
+		weighted := make([]*Holding, 0, len(members))
+		for _, h := range members {
+			if h.Asset.TargetWeight > 0 {
+				weighted = append(weighted, h)
+			} else {
+				pool -= h.Amount
+				cp.Unweighted += h.Amount
+			}
+		}
+		members = weighted
+		target = math.Max(pool, 0)
+		adjust = target - (current - cp.Unweighted)
+	}
+
+	// 每只基金的目标调整金额
+	deltas := make([]float64, len(members))
+	if cp.Weighted {
+		for i, h := range members {
+			deltas[i] = target*h.Asset.TargetWeight/weightSum - h.Amount
+		}
+	} else {
+		concentrate(members, cp.Adjust, deltas, fees)
+	}
+
+	// 忽略低于最小交易额的调整，剩余差额并入同方向最大的一笔交易
+	var kept float64
+	largest := -1
+	for i, d := range deltas {
+		if math.Abs(d) < minTrade || d == 0 {
+			deltas[i] = 0
+			continue
+		}
+		kept += d
+		if largest < 0 || math.Abs(d) > math.Abs(deltas[largest]) {
+			largest = i
+		}
+	}
+
+	residual := adjust - kept
+	if math.Abs(residual) >= 0.01 {
+		switch {
+		case largest >= 0 && sameSign(deltas[largest], residual):
+			deltas[largest] += residual
+		case math.Abs(residual) >= minTrade:
+			i := mostUnbalanced(members, deltas, residual)
+			deltas[i] += residual
+		}
+	}
+
+	var orders []PlanOrder
+	for i, d := range deltas {
+		h := members[i]
+		// 卖出不超过当前持仓
+		if d < 0 && -d > h.Amount {
+			d = -h.Amount
+		}
+		if math.Abs(d) < 0.01 {
+			continue
+		}
+		orders = append(orders, newPlanOrder(h, d, fees))
+		cp.Planned += d
+	}
+	cp.Unallocated = cp.Adjust - cp.Planned
+
+	return orders, cp
+}
+
+// newPlanOrder 根据调整金额（买入为正、卖出为负）生成交易并估算费用
+func newPlanOrder(h *Holding, delta float64, fees *feeModel) PlanOrder {
+	action := OrderActionBuy
+	if delta < 0 {
+		action = OrderActionSell
+	}
+	order := PlanOrder{
+		AssetID: h.Asset.ID,
+		Code:    h.Asset.Code,
+		Name:    h.Asset.Name,
+		Source:  h.Asset.Source,
+		Type:    h.Asset.Type,
+		Action:  action,
+		Amount:  math.Round(math.Abs(delta)*100) / 100,
+	}
+	fees.annotate(&order, h)
+	return order
+}
+
+// concentrate 将类别调整集中到持仓最大的基金：买入全部计入最大的一只，
+// 卖出从大到小依次扣减，直到满足调整金额；有费率信息时先卖赎回费低的
+func concentrate(members []*Holding, adjust float64, deltas []float64, fees *feeModel) {
+	order := make([]int, len(members))
+	rates := make([]float64, len(members))
+	for i := range order {
+		order[i] = i
+		if adjust < 0 && fees != nil {
+			rates[i] = fees.cheapestRedemptionRate(members[i])
+		}
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		if rates[order[a]] != rates[order[b]] {
+			return rates[order[a]] < rates[order[b]]
+		}
+		return members[order[a]].Amount > members[order[b]].Amount
+	})
+
+	if adjust > 0 {
+		deltas[order[0]] = adjust
+		return
+	}
+
+	remaining := -adjust
+	for _, i := range order {
+		if remaining <= 0 {
+			break
+		}
+		sell := math.Min(members[i].Amount, remaining)
+		deltas[i] = -sell
+		remaining -= sell
+	}
+}
+
+// mostUnbalanced 找出最适合吸收剩余差额的基金：买入选持仓最小的，卖出选持仓最大的
+func mostUnbalanced(members []*Holding, deltas []float64, residual float64) int {
+	best := 0
+	for i, h := range members {
+		after := h.Amount + deltas[i]
+		bestAfter := members[best].Amount + deltas[best]
+		if residual > 0 && after < bestAfter || residual < 0 && after > bestAfter {
+			best = i
+		}
+	}
+	return best
+}
+
+func sameSign(a, b float64) bool {
+	return a > 0 && b > 0 || a < 0 && b < 0
+}
+
+func (p *RebalancePlan) toMap() map[string]interface{} {
+	classes := make([]map[string]interface{}, 0, len(p.Classes))
+	for _, c := range p.Classes {
+		classes = append(classes, map[string]interface{}{
+			"type":        c.Type,
+			"current":     c.Current,
+			"target":      c.Target,
+			"adjust":      c.Adjust,
+			"planned":     c.Planned,
+			"unallocated": c.Unallocated,
+			"weighted":    c.Weighted,
+		})
+	}
+
+	return map[string]interface{}{
+		"target_stock_ratio": p.TargetStockRatio,
+		"total_assets":       p.Total,
+		"orders":             ordersToMaps(p.Orders),
+		"classes":            classes,
+		"trade_count":        len(p.Orders),
+		"estimated_fee":      p.EstimatedFee,
+		"fee_known":          p.FeeKnown,
+	}
+}
+
+func ordersToMaps(orders []PlanOrder) []map[string]interface{} {
+	result := make([]map[string]interface{}, 0, len(orders))
+	for _, o := range orders {
+		result = append(result, map[string]interface{}{
+			"asset_id": o.AssetID,
+			"code":     o.Code,
+			"name":     o.Name,
+			"source":   o.Source,
+			"type":     o.Type,
+			"action":   o.Action,
+			"amount":   o.Amount,
+
+			"estimated_fee": o.EstimatedFee,
+			"fee_rate":      o.FeeRate,
+			"fee_known":     o.FeeKnown,
+			"lots":          lotSalesToMaps(o.Lots),
+		})
+	}
+	return result
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"math"
+	"testing"
+
+	"margin/internal/model"
+)
+
+func TestPlanClassKeepsUnweightedFunds(t *testing.T) {
+	holding := func(id uint, amount, weight float64) *Holding {
+		return &Holding{Asset: model.Asset{ID: id, Type: model.AssetTypeStock, TargetWeight: weight}, Amount: amount}
+	}
+
+	tests := []struct {
+		name        string
+		target      float64
+		members     []*Holding
+		want        map[uint]float64 // 资产 ID → 调整金额，未出现的资产不应有交易
+		unallocated float64
+	}{
+		{
+			name:    "unweighted fund is kept",
+			target:  10000,
+			members: []*Holding{holding(1, 4000, 60), holding(2, 3000, 0), holding(3, 2000, 40)},
+			want:    map[uint]float64{1: 200, 3: 800},
+		},
+		{
+			name:        "unweighted funds exceed the class target",
+			target:      5000,
+			members:     []*Holding{holding(1, 1000, 100), holding(2, 6000, 0)},
+			want:        map[uint]float64{1: -1000},
+			unallocated: -1000,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orders, cp := planClass(tt.members, tt.target, 10, nil)
+			got := make(map[uint]float64)
+			for _, o := range orders {
+				if o.Action == OrderActionSell {
+					got[o.AssetID] = -o.Amount
+				} else {
+					got[o.AssetID] = o.Amount
+				}
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("orders = %+v, want %v", orders, tt.want)
+			}
+			for id, amount := range tt.want {
+				if math.Abs(got[id]-amount) > 0.01 {
+					t.Errorf("asset %d adjust = %.2f, want %.2f", id, got[id], amount)
+				}
+			}
+			if math.Abs(cp.Unallocated-tt.unallocated) > 0.01 {
+				t.Errorf("unallocated = %.2f, want %.2f", cp.Unallocated, tt.unallocated)
+			}
+		})
+	}
+}
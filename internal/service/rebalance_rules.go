@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"margin/internal/model"
+	"margin/internal/repo"
+	"math"
+
+	"gorm.io/gorm"
+)
+
+// 容忍带模式
+const (
+	BandModeAbsolute = "absolute" // 绝对偏离（百分点）
+	BandModeRelative = "relative" // 相对偏离（占目标权重的百分比）
+	BandModeCombined = "combined" // 任一阈值被突破即触发，如 5/25 规则
+)
+
+// BandRule 单个类别的容忍带规则
+type BandRule struct {
+	Mode     string  `json:"mode"`
+	Absolute float64 `json:"absolute"` // 绝对阈值，如 5 表示 ±5 个百分点
+	Relative float64 `json:"relative"` // 相对阈值，如 25 表示偏离目标权重的 25%
+}
+
+// RebalanceBands 各类别的容忍带规则
+type RebalanceBands struct {
+	Stock BandRule `json:"stock"`
+	Bond  BandRule `json:"bond"`
+}
+
+// DefaultRebalanceBands 默认使用 5/25 规则
+var DefaultRebalanceBands = RebalanceBands{
+	Stock: BandRule{Mode: BandModeCombined, Absolute: 5, Relative: 25},
+	Bond:  BandRule{Mode: BandModeCombined, Absolute: 5, Relative: 25},
+}
+
+// bandCheck 单个类别的检查结果
+type bandCheck struct {
+	Class         string
+	Target        float64 // 目标比例
+	Current       float64 // 当前比例
+	AbsoluteDrift float64 // 绝对偏离（百分点）
+	RelativeDrift float64 // 相对偏离（%）
+	Triggered     bool
+	Rule          string // 触发的规则 absolute/relative
+	Reason        string
+}
+
+// Validate 校验规则
+func (r BandRule) Validate() error {
+	switch r.Mode {
+	case BandModeAbsolute:
+		if r.Absolute <= 0 {
+			return errors.New("绝对阈值必须大于 0")
+		}
+	case BandModeRelative:
+		if r.Relative <= 0 {
+			return errors.New("相对阈值必须大于 0")
+		}
+	case BandModeCombined:
+		if r.Absolute <= 0 || r.Relative <= 0 {
+			return errors.New("绝对阈值和相对阈值都必须大于 0")
+		}
+	default:
+		return fmt.Errorf("不支持的容忍带模式: %s", r.Mode)
+	}
+	return nil
+}
+
+// check 检查当前比例是否突破容忍带
+func (r BandRule) check(class string, current, target float64) bandCheck {
+	c := bandCheck{
+		Class:         class,
+		Target:        target,
+		Current:       current,
+		AbsoluteDrift: current - target,
+	}
+	if target > 0 {
+		c.RelativeDrift = c.AbsoluteDrift / target * 100
+	}
+
+	name := classDisplayName(class)
+	useAbsolute := r.Mode == BandModeAbsolute || r.Mode == BandModeCombined
+	useRelative := r.Mode == BandModeRelative || r.Mode == BandModeCombined
+
+	switch {
+	case useAbsolute && math.Abs(c.AbsoluteDrift) > r.Absolute:
+		c.Triggered = true
+		c.Rule = BandModeAbsolute
+		c.Reason = fmt.Sprintf("%s偏离目标 %.2f 个百分点，超过绝对阈值 ±%.2f", name, c.AbsoluteDrift, r.Absolute)
+	case useRelative && target > 0 && math.Abs(c.RelativeDrift) > r.Relative:
+		c.Triggered = true
+		c.Rule = BandModeRelative
+		c.Reason = fmt.Sprintf("%s相对目标偏离 %.1f%%，超过相对阈值 ±%.1f%%", name, c.RelativeDrift, r.Relative)
+	default:
+		c.Reason = fmt.Sprintf("%s偏离 %.2f 个百分点，在容忍范围内", name, c.AbsoluteDrift)
+	}
+
+	return c
+}
+
+// check 检查股票和债券两个类别
+func (b RebalanceBands) check(currentStockRatio, targetStockRatio float64) []bandCheck {
+	return []bandCheck{
+		b.Stock.check(model.AssetTypeStock, currentStockRatio, targetStockRatio),
+		b.Bond.check(model.AssetTypeBond, 100-currentStockRatio, 100-targetStockRatio),
+	}
+}
+
+// ToMap 转换为前端展示的结构
+func (b RebalanceBands) ToMap() map[string]interface{} {
+	rule := func(r BandRule) map[string]interface{} {
+		return map[string]interface{}{
+			"mode":     r.Mode,
+			"absolute": r.Absolute,
+			"relative": r.Relative,
+		}
+	}
+	return map[string]interface{}{
+		"stock": rule(b.Stock),
+		"bond":  rule(b.Bond),
+	}
+}
+
+func classDisplayName(class string) string {
+	if class == model.AssetTypeStock {
+		return "股票"
+	}
+	return "债券"
+}
+
+// loadRebalanceBands 读取容忍带配置，未配置时返回默认规则
+func loadRebalanceBands(ctx context.Context, configRepo *repo.ConfigRepository) (RebalanceBands, error) {
+	config, err := configRepo.Get(ctx, model.ConfigKeyRebalanceBands)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return DefaultRebalanceBands, nil
+	}
+	if err != nil {
+		return RebalanceBands{}, err
+	}
+
+	var bands RebalanceBands
+	if err := json.Unmarshal([]byte(config.Value), &bands); err != nil {
+		return RebalanceBands{}, fmt.Errorf("容忍带配置格式错误: %w", err)
+	}
+	return bands, nil
+}
+
+// bandChecksToMap 转换为前端展示的结构
+func bandChecksToMap(checks []bandCheck) ([]map[string]interface{}, []string) {
+	result := make([]map[string]interface{}, 0, len(checks))
+	var reasons []string
+	for _, c := range checks {
+		result = append(result, map[string]interface{}{
+			"class":          c.Class,
+			"target":         c.Target,
+			"current":        c.Current,
+			"absolute_drift": c.AbsoluteDrift,
+			"relative_drift": c.RelativeDrift,
+			"triggered":      c.Triggered,
+			"rule":           c.Rule,
+			"reason":         c.Reason,
+		})
+		if c.Triggered {
+			reasons = append(reasons, c.Reason)
+		}
+	}
+	return result, reasons
+}
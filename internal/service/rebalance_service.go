@@ -2,37 +2,200 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"margin/internal/crypto"
 	"margin/internal/model"
 	"margin/internal/repo"
+	"strconv"
+	"time"
 
 	"gorm.io/gorm"
 )
 
+// legacyRebalanceAmountColumns 旧版本以明文保存的金额列
+var legacyRebalanceAmountColumns = []string{"total_amount", "stock_amount", "bond_amount"}
+
 type RebalanceService struct {
-	db            *gorm.DB
-	rebalanceRepo *repo.RebalanceRepository
+	db               *gorm.DB
+	rebalanceRepo    *repo.RebalanceRepository
+	configRepo       *repo.ConfigRepository
+	assetService     *AssetService
+	valuationService *ValuationService
 }
 
-func NewRebalanceService(db *gorm.DB) *RebalanceService {
+func NewRebalanceService(db *gorm.DB, assetService *AssetService, valuationService *ValuationService) *RebalanceService {
 	return &RebalanceService{
-		db:            db,
-		rebalanceRepo: repo.NewRebalanceRepository(db),
+		db:               db,
+		rebalanceRepo:    repo.NewRebalanceRepository(db),
+		configRepo:       repo.NewConfigRepository(db),
+		assetService:     assetService,
+		valuationService: valuationService,
 	}
 }
 
-// SaveRebalance 保存再平衡记录
-func (s *RebalanceService) SaveRebalance(ctx context.Context, stockRatio, bondRatio, totalAmount, stockAmount, bondAmount, targetStockRatio, targetBondRatio float64, note string) error {
+// GetRebalanceAdvice 获取再平衡建议。开启估值驱动模式时附带估值分析；
+// targetStockRatio 为负数表示使用默认目标：开启下滑路径时取今天的下滑路径比例，否则取估值建议的股票比例
+func (s *RebalanceService) GetRebalanceAdvice(ctx context.Context, targetStockRatio float64) (map[string]interface{}, error) {
+	if targetStockRatio > 100 {
+		return nil, errors.New("目标股票比例需在 0-100 之间")
+	}
+	useDefault := targetStockRatio < 0
+
+	glide, err := loadGlidePath(ctx, s.configRepo)
+	if err != nil {
+		return nil, err
+	}
+	if useDefault && glide.Enabled {
+		targetStockRatio = glide.RatioAt(time.Now())
+		useDefault = false
+	}
+
+	config, err := s.valuationService.GetConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var valuation map[string]interface{}
+	var valuationErr error
+	if config.Enabled || useDefault {
+		v, err := s.valuationService.Suggest(ctx)
+		if err != nil {
+			if useDefault {
+				return nil, fmt.Errorf("无法获取估值建议: %w", err)
+			}
+			valuationErr = err
+		} else {
+			valuation = v.ToMap()
+			if useDefault {
+				targetStockRatio = v.SuggestedStockRatio
+			}
+		}
+	}
+
+	advice, err := s.assetService.GetRebalanceAdvice(ctx, targetStockRatio)
+	if err != nil {
+		return nil, err
+	}
+	if valuation != nil {
+		advice["valuation"] = valuation
+	} else if valuationErr != nil {
+		advice["valuation_error"] = valuationErr.Error()
+	}
+	if glide.Enabled {
+		advice["glide_path"] = glide.ToMap()
+	}
+	return advice, nil
+}
+
+// SaveRebalance 根据当前持仓保存再平衡记录，并保存每个资产的持仓快照
+func (s *RebalanceService) SaveRebalance(ctx context.Context, targetStockRatio float64, note string) error {
+	_, err := s.saveRebalance(ctx, targetStockRatio, note, nil)
+	return err
+}
+
+// saveRebalance 保存再平衡记录和持仓快照，planOrders 不为空时根据持仓生成待执行的交易
+func (s *RebalanceService) saveRebalance(ctx context.Context, targetStockRatio float64, note string, planOrders func([]Holding) ([]PlanOrder, error)) (uint, error) {
+	if targetStockRatio < 0 || targetStockRatio > 100 {
+		return 0, errors.New("目标股票比例需在 0-100 之间")
+	}
+
+	holdings, err := s.assetService.LoadHoldings(ctx)
+	if err != nil {
+		return 0, err
+	}
+	stockTotal, bondTotal := portfolioTotals(holdings)
+	total := stockTotal + bondTotal
+	if total == 0 {
+		return 0, errors.New("no assets found")
+	}
+
+	encryptKey, err := s.configRepo.Get(ctx, model.ConfigKeyEncryptKey)
+	if err != nil {
+		return 0, err
+	}
+	encrypt := func(v float64) (string, error) {
+		return crypto.Encrypt(fmt.Sprintf("%.2f", v), encryptKey.Value)
+	}
+
 	rebalance := &model.Rebalance{
-		StockRatio:       stockRatio,
-		BondRatio:        bondRatio,
-		TotalAmount:      totalAmount,
-		StockAmount:      stockAmount,
-		BondAmount:       bondAmount,
+		StockRatio:       stockTotal / total * 100,
+		BondRatio:        bondTotal / total * 100,
 		TargetStockRatio: targetStockRatio,
-		TargetBondRatio:  targetBondRatio,
+		TargetBondRatio:  100 - targetStockRatio,
 		Note:             note,
 	}
-	return s.rebalanceRepo.Create(ctx, rebalance)
+	if rebalance.EncryptedTotalAmount, err = encrypt(total); err != nil {
+		return 0, err
+	}
+	if rebalance.EncryptedStockAmount, err = encrypt(stockTotal); err != nil {
+		return 0, err
+	}
+	if rebalance.EncryptedBondAmount, err = encrypt(bondTotal); err != nil {
+		return 0, err
+	}
+
+	snapshot := make([]model.RebalanceHolding, 0, len(holdings))
+	for _, h := range holdings {
+		amount, err := encrypt(h.Amount)
+		if err != nil {
+			return 0, err
+		}
+		snapshot = append(snapshot, model.RebalanceHolding{
+			AssetID:         h.Asset.ID,
+			Code:            h.Asset.Code,
+			Name:            h.Asset.Name,
+			Type:            h.Asset.Type,
+			Source:          h.Asset.Source,
+			EncryptedAmount: amount,
+			Ratio:           h.Amount / total * 100,
+		})
+	}
+
+	var orders []model.RebalanceOrder
+	if planOrders != nil {
+		planned, err := planOrders(holdings)
+		if err != nil {
+			return 0, err
+		}
+		for _, o := range planned {
+			amount, err := encrypt(o.Amount)
+			if err != nil {
+				return 0, err
+			}
+			orders = append(orders, model.RebalanceOrder{
+				AssetID:         o.AssetID,
+				Code:            o.Code,
+				Name:            o.Name,
+				Type:            o.Type,
+				Source:          o.Source,
+				Action:          o.Action,
+				EncryptedAmount: amount,
+				Status:          model.OrderStatusPending,
+			})
+		}
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		rebalanceRepo := repo.NewRebalanceRepository(tx)
+		if err := rebalanceRepo.Create(ctx, rebalance); err != nil {
+			return err
+		}
+		for i := range snapshot {
+			snapshot[i].RebalanceID = rebalance.ID
+		}
+		if err := rebalanceRepo.CreateHoldings(ctx, snapshot); err != nil {
+			return err
+		}
+		for i := range orders {
+			orders[i].RebalanceID = rebalance.ID
+		}
+		return rebalanceRepo.CreateOrders(ctx, orders)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return rebalance.ID, nil
 }
 
 // GetRebalanceHistory 获取再平衡历史记录
@@ -42,20 +205,63 @@ func (s *RebalanceService) GetRebalanceHistory(ctx context.Context) ([]map[strin
 		return nil, err
 	}
 
+	orders, err := s.rebalanceRepo.GetAllOrders(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.rebalancesToMaps(ctx, rebalances, orders)
+}
+
+// QueryRebalanceHistory 按时间区间查询再平衡记录，可按周期降采样（每个周期取最后一条）并分页，结果按时间倒序
+func (s *RebalanceService) QueryRebalanceHistory(ctx context.Context, q TimeQuery) (map[string]interface{}, error) {
+	r, err := q.timeRange()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.rebalanceRepo.ListTimes(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	ids, total := q.selectPage(rows)
+
+	rebalances, err := s.rebalanceRepo.GetByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	orders, err := s.rebalanceRepo.GetOrdersByRebalanceIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	items, err := s.rebalancesToMaps(ctx, rebalances, orders)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"items": items,
+		"total": total,
+	}, nil
+}
+
+// rebalancesToMaps 解密再平衡记录并附带各自的交易执行情况
+func (s *RebalanceService) rebalancesToMaps(ctx context.Context, rebalances []*model.Rebalance, orders []model.RebalanceOrder) ([]map[string]interface{}, error) {
+	encryptKey, err := s.configRepo.Get(ctx, model.ConfigKeyEncryptKey)
+	if err != nil {
+		return nil, err
+	}
+
+	ordersByRebalance := map[uint][]model.RebalanceOrder{}
+	for _, o := range orders {
+		ordersByRebalance[o.RebalanceID] = append(ordersByRebalance[o.RebalanceID], o)
+	}
+
 	result := make([]map[string]interface{}, 0, len(rebalances))
 	for _, r := range rebalances {
-		result = append(result, map[string]interface{}{
-			"id":                 r.ID,
-			"stock_ratio":        r.StockRatio,
-			"bond_ratio":         r.BondRatio,
-			"total_amount":       r.TotalAmount,
-			"stock_amount":       r.StockAmount,
-			"bond_amount":        r.BondAmount,
-			"target_stock_ratio": r.TargetStockRatio,
-			"target_bond_ratio":  r.TargetBondRatio,
-			"note":               r.Note,
-			"created_at":         r.CreatedAt.Format("2006-01-02 15:04:05"),
-		})
+		item, err := rebalanceToMap(r, ordersByRebalance[r.ID], encryptKey.Value)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, item)
 	}
 
 	return result, nil
@@ -71,21 +277,172 @@ func (s *RebalanceService) GetLatestRebalance(ctx context.Context) (map[string]i
 		return nil, err
 	}
 
+	orders, err := s.rebalanceRepo.GetOrders(ctx, rebalance.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	encryptKey, err := s.configRepo.Get(ctx, model.ConfigKeyEncryptKey)
+	if err != nil {
+		return nil, err
+	}
+	return rebalanceToMap(rebalance, orders, encryptKey.Value)
+}
+
+// GetRebalanceHoldings 获取再平衡时的资产持仓快照
+func (s *RebalanceService) GetRebalanceHoldings(ctx context.Context, id uint) ([]map[string]interface{}, error) {
+	holdings, err := s.rebalanceRepo.GetHoldings(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	encryptKey, err := s.configRepo.Get(ctx, model.ConfigKeyEncryptKey)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]map[string]interface{}, 0, len(holdings))
+	for _, h := range holdings {
+		amount, err := decryptAmount(h.EncryptedAmount, encryptKey.Value)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, map[string]interface{}{
+			"id":       h.ID,
+			"asset_id": h.AssetID,
+			"code":     h.Code,
+			"name":     h.Name,
+			"type":     h.Type,
+			"source":   h.Source,
+			"amount":   amount,
+			"ratio":    h.Ratio,
+		})
+	}
+	return result, nil
+}
+
+// DeleteRebalance 删除再平衡记录及其持仓快照和交易
+func (s *RebalanceService) DeleteRebalance(ctx context.Context, id uint) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		rebalanceRepo := repo.NewRebalanceRepository(tx)
+		if err := rebalanceRepo.DeleteHoldings(ctx, id); err != nil {
+			return err
+		}
+		if err := rebalanceRepo.DeleteOrders(ctx, id); err != nil {
+			return err
+		}
+		return rebalanceRepo.Delete(ctx, id)
+	})
+}
+
+// MigrateLegacyAmounts 将旧版本明文保存的金额加密到新列，然后删除明文列。
+// 未设置密码（没有加密密钥）时跳过，下次启动再迁移
+func (s *RebalanceService) MigrateLegacyAmounts(ctx context.Context) error {
+	migrator := s.db.Migrator()
+	var legacy []string
+	for _, column := range legacyRebalanceAmountColumns {
+		if migrator.HasColumn(&model.Rebalance{}, column) {
+			legacy = append(legacy, column)
+		}
+	}
+	if len(legacy) == 0 {
+		return nil
+	}
+
+	encryptKey, err := s.configRepo.Get(ctx, model.ConfigKeyEncryptKey)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if len(legacy) == len(legacyRebalanceAmountColumns) {
+			var rows []struct {
+				ID          uint
+				TotalAmount float64
+				StockAmount float64
+				BondAmount  float64
+			}
+			if err := tx.Table(model.Rebalance{}.TableName()).
+				Select("id, total_amount, stock_amount, bond_amount").
+				Where("encrypted_total_amount = ''").
+				Scan(&rows).Error; err != nil {
+				return err
+			}
+
+			for _, row := range rows {
+				updates := map[string]interface{}{}
+				for column, v := range map[string]float64{
+					"encrypted_total_amount": row.TotalAmount,
+					"encrypted_stock_amount": row.StockAmount,
+					"encrypted_bond_amount":  row.BondAmount,
+				} {
+					encrypted, err := crypto.Encrypt(fmt.Sprintf("%.2f", v), encryptKey.Value)
+					if err != nil {
+						return err
+					}
+					updates[column] = encrypted
+				}
+				if err := tx.Model(&model.Rebalance{}).Where("id = ?", row.ID).Updates(updates).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		for _, column := range legacy {
+			sql := fmt.Sprintf("ALTER TABLE `%s` DROP COLUMN `%s`", model.Rebalance{}.TableName(), column)
+			if err := tx.Exec(sql).Error; err != nil {
+				return fmt.Errorf("删除明文金额列 %s 失败: %w", column, err)
+			}
+		}
+		return nil
+	})
+}
+
+// rebalanceToMap 解密金额并转换为前端展示的结构，附带计划执行状态
+func rebalanceToMap(r *model.Rebalance, orders []model.RebalanceOrder, key string) (map[string]interface{}, error) {
+	total, err := decryptAmount(r.EncryptedTotalAmount, key)
+	if err != nil {
+		return nil, err
+	}
+	stock, err := decryptAmount(r.EncryptedStockAmount, key)
+	if err != nil {
+		return nil, err
+	}
+	bond, err := decryptAmount(r.EncryptedBondAmount, key)
+	if err != nil {
+		return nil, err
+	}
+
+	status, done := planStatus(orders)
+
 	return map[string]interface{}{
-		"id":                 rebalance.ID,
-		"stock_ratio":        rebalance.StockRatio,
-		"bond_ratio":         rebalance.BondRatio,
-		"total_amount":       rebalance.TotalAmount,
-		"stock_amount":       rebalance.StockAmount,
-		"bond_amount":        rebalance.BondAmount,
-		"target_stock_ratio": rebalance.TargetStockRatio,
-		"target_bond_ratio":  rebalance.TargetBondRatio,
-		"note":               rebalance.Note,
-		"created_at":         rebalance.CreatedAt.Format("2006-01-02 15:04:05"),
+		"id":                 r.ID,
+		"stock_ratio":        r.StockRatio,
+		"bond_ratio":         r.BondRatio,
+		"total_amount":       total,
+		"stock_amount":       stock,
+		"bond_amount":        bond,
+		"target_stock_ratio": r.TargetStockRatio,
+		"target_bond_ratio":  r.TargetBondRatio,
+		"note":               r.Note,
+		"created_at":         r.CreatedAt.Format("2006-01-02 15:04:05"),
+		"status":             status,
+		"order_count":        len(orders),
+		"done_count":         done,
 	}, nil
 }
 
-// DeleteRebalance 删除再平衡记录
-func (s *RebalanceService) DeleteRebalance(ctx context.Context, id uint) error {
-	return s.rebalanceRepo.Delete(ctx, id)
+// decryptAmount 解密金额，空字符串视为 0
+func decryptAmount(encrypted, key string) (float64, error) {
+	if encrypted == "" {
+		return 0, nil
+	}
+	amountStr, err := crypto.Decrypt(encrypted, key)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseFloat(amountStr, 64)
 }
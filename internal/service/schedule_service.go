@@ -0,0 +1,338 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"margin/internal/model"
+	"margin/internal/repo"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 再平衡日历频率
+const (
+	ScheduleOff        = "off"
+	ScheduleMonthly    = "monthly"
+	ScheduleQuarterly  = "quarterly"  // 1/4/7/10 月
+	ScheduleSemiannual = "semiannual" // 1/7 月
+	ScheduleDates      = "dates"      // 每年的指定日期
+)
+
+// RebalanceSchedule 定期再平衡日历
+type RebalanceSchedule struct {
+	Frequency        string   `json:"frequency"`
+	Day              int      `json:"day"`                // 每期的第几天（1-28）
+	Dates            []string `json:"dates"`              // 指定日期，格式 MM-DD
+	TargetStockRatio float64  `json:"target_stock_ratio"` // 检查时使用的目标股票比例
+	Since            string   `json:"since"`              // 日历生效日期，之前的计划日期不提醒
+}
+
+// Validate 校验日历
+func (s RebalanceSchedule) Validate() error {
+	switch s.Frequency {
+	case ScheduleOff:
+		return nil
+	case ScheduleMonthly, ScheduleQuarterly, ScheduleSemiannual:
+		if s.Day < 1 || s.Day > 28 {
+			return errors.New("检查日需在 1-28 之间")
+		}
+	case ScheduleDates:
+		if len(s.Dates) == 0 {
+			return errors.New("请至少设置一个检查日期")
+		}
+		for _, d := range s.Dates {
+			if _, err := time.Parse("01-02", d); err != nil {
+				return fmt.Errorf("日期格式错误: %s（应为 MM-DD）", d)
+			}
+		}
+	default:
+		return fmt.Errorf("不支持的频率: %s", s.Frequency)
+	}
+	if s.TargetStockRatio < 0 || s.TargetStockRatio > 100 {
+		return errors.New("目标股票比例需在 0-100 之间")
+	}
+	return nil
+}
+
+// occurrences 返回 [from, to] 之间的所有计划日期（按时间升序）
+func (s RebalanceSchedule) occurrences(from, to time.Time) []time.Time {
+	var months []time.Month
+	switch s.Frequency {
+	case ScheduleMonthly:
+		for m := time.January; m <= time.December; m++ {
+			months = append(months, m)
+		}
+	case ScheduleQuarterly:
+		months = []time.Month{time.January, time.April, time.July, time.October}
+	case ScheduleSemiannual:
+		months = []time.Month{time.January, time.July}
+	}
+
+	var result []time.Time
+	for year := from.Year(); year <= to.Year(); year++ {
+		if s.Frequency == ScheduleDates {
+			for _, d := range s.Dates {
+				md, err := time.Parse("01-02", d)
+				if err != nil {
+					continue
+				}
+				day := min(md.Day(), daysIn(md.Month(), year))
+				result = append(result, time.Date(year, md.Month(), day, 0, 0, 0, 0, time.Local))
+			}
+			continue
+		}
+		for _, m := range months {
+			result = append(result, time.Date(year, m, s.Day, 0, 0, 0, 0, time.Local))
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Before(result[j]) })
+	filtered := result[:0]
+	for _, t := range result {
+		if !t.Before(from) && !t.After(to) {
+			filtered = append(filtered, t)
+		}
+	}
+	return filtered
+}
+
+// latestDue 返回不晚于 now 的最近一个计划日期
+func (s RebalanceSchedule) latestDue(now time.Time) (time.Time, bool) {
+	dates := s.occurrences(now.AddDate(-1, 0, 0), now)
+	if len(dates) == 0 {
+		return time.Time{}, false
+	}
+	return dates[len(dates)-1], true
+}
+
+// nextDue 返回晚于 now 的下一个计划日期
+func (s RebalanceSchedule) nextDue(now time.Time) (time.Time, bool) {
+	for _, t := range s.occurrences(now, now.AddDate(1, 0, 1)) {
+		if t.After(now) {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func daysIn(m time.Month, year int) int {
+	return time.Date(year, m+1, 0, 0, 0, 0, 0, time.Local).Day()
+}
+
+// ScheduleService 定期再平衡日历和检查记录
+type ScheduleService struct {
+	db           *gorm.DB
+	configRepo   *repo.ConfigRepository
+	reviewRepo   *repo.ReviewRepository
+	assetService *AssetService
+}
+
+func NewScheduleService(db *gorm.DB, assetService *AssetService) *ScheduleService {
+	return &ScheduleService{
+		db:           db,
+		configRepo:   repo.NewConfigRepository(db),
+		reviewRepo:   repo.NewReviewRepository(db),
+		assetService: assetService,
+	}
+}
+
+// GetSchedule 获取再平衡日历，未配置时返回关闭状态
+func (s *ScheduleService) GetSchedule(ctx context.Context) (RebalanceSchedule, error) {
+	config, err := s.configRepo.Get(ctx, model.ConfigKeyRebalanceSchedule)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return RebalanceSchedule{Frequency: ScheduleOff, Day: 1, TargetStockRatio: 50}, nil
+	}
+	if err != nil {
+		return RebalanceSchedule{}, err
+	}
+
+	var schedule RebalanceSchedule
+	if err := json.Unmarshal([]byte(config.Value), &schedule); err != nil {
+		return RebalanceSchedule{}, fmt.Errorf("再平衡日历配置格式错误: %w", err)
+	}
+	return schedule, nil
+}
+
+// SaveSchedule 保存再平衡日历，从保存当天开始生效
+func (s *ScheduleService) SaveSchedule(ctx context.Context, schedule RebalanceSchedule) error {
+	if err := schedule.Validate(); err != nil {
+		return err
+	}
+	schedule.Since = time.Now().Format("2006-01-02")
+
+	data, err := json.Marshal(schedule)
+	if err != nil {
+		return err
+	}
+	return s.configRepo.Set(ctx, model.ConfigKeyRebalanceSchedule, string(data))
+}
+
+// ToMap 转换为前端展示的结构，附带下一次检查日期
+func (schedule RebalanceSchedule) ToMap() map[string]interface{} {
+	result := map[string]interface{}{
+		"frequency":          schedule.Frequency,
+		"day":                schedule.Day,
+		"dates":              schedule.Dates,
+		"target_stock_ratio": schedule.TargetStockRatio,
+		"since":              schedule.Since,
+		"next_due":           "",
+	}
+	if schedule.Frequency != ScheduleOff {
+		if next, ok := schedule.nextDue(time.Now()); ok {
+			result["next_due"] = next.Format("2006-01-02")
+		}
+	}
+	return result
+}
+
+// CheckDue 检查是否到了计划检查日期。到期时按容忍带规则检查偏离并记录一次检查，
+// 返回检查结果；未到期、日历关闭或没有资产时返回 nil
+func (s *ScheduleService) CheckDue(ctx context.Context) (map[string]interface{}, error) {
+	schedule, err := s.GetSchedule(ctx)
+	if err != nil || schedule.Frequency == ScheduleOff {
+		return nil, err
+	}
+
+	now := time.Now()
+	due, ok := schedule.latestDue(now)
+	if !ok {
+		return nil, nil
+	}
+	if since, err := time.ParseInLocation("2006-01-02", schedule.Since, time.Local); err == nil && due.Before(since) {
+		return nil, nil
+	}
+
+	latest, err := s.reviewRepo.GetLatest(ctx)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if latest != nil && !latest.DueDate.Before(due) {
+		return nil, nil
+	}
+
+	review, err := s.review(ctx, due, schedule.TargetStockRatio, "")
+	if err != nil || review == nil {
+		return nil, err
+	}
+
+	result := reviewToMap(review)
+	result["scheduled"] = true
+	if next, ok := schedule.nextDue(now); ok {
+		result["next_due"] = next.Format("2006-01-02")
+	}
+	return result, nil
+}
+
+// RunReview 立即进行一次检查并记录
+func (s *ScheduleService) RunReview(ctx context.Context, targetStockRatio float64, note string) (map[string]interface{}, error) {
+	if targetStockRatio < 0 || targetStockRatio > 100 {
+		return nil, errors.New("目标股票比例需在 0-100 之间")
+	}
+
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	review, err := s.review(ctx, today, targetStockRatio, note)
+	if err != nil {
+		return nil, err
+	}
+	if review == nil {
+		return nil, errors.New("no assets found")
+	}
+	return reviewToMap(review), nil
+}
+
+// review 按容忍带规则检查当前持仓并记录，没有资产时返回 nil
+func (s *ScheduleService) review(ctx context.Context, due time.Time, targetStockRatio float64, note string) (*model.RebalanceReview, error) {
+	holdings, err := s.assetService.LoadHoldings(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stockTotal, bondTotal := portfolioTotals(holdings)
+	total := stockTotal + bondTotal
+	if total == 0 {
+		return nil, nil
+	}
+
+	bands, err := loadRebalanceBands(ctx, s.configRepo)
+	if err != nil {
+		return nil, err
+	}
+	stockRatio := stockTotal / total * 100
+	_, reasons := bandChecksToMap(bands.check(stockRatio, targetStockRatio))
+
+	review := &model.RebalanceReview{
+		DueDate:          due,
+		TargetStockRatio: targetStockRatio,
+		StockRatio:       stockRatio,
+		Breached:         len(reasons) > 0,
+		Reasons:          strings.Join(reasons, "\n"),
+		Outcome:          model.ReviewOutcomeNoAction,
+		Note:             note,
+	}
+	if review.Breached {
+		review.Outcome = model.ReviewOutcomeRebalanceNeeded
+	}
+	if err := s.reviewRepo.Create(ctx, review); err != nil {
+		return nil, err
+	}
+	return review, nil
+}
+
+// GetReviews 获取检查记录
+func (s *ScheduleService) GetReviews(ctx context.Context) ([]map[string]interface{}, error) {
+	reviews, err := s.reviewRepo.GetAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]map[string]interface{}, 0, len(reviews))
+	for i := range reviews {
+		result = append(result, reviewToMap(&reviews[i]))
+	}
+	return result, nil
+}
+
+// UpdateReview 更新检查结果（如已完成再平衡）和备注
+func (s *ScheduleService) UpdateReview(ctx context.Context, id uint, outcome, note string) error {
+	switch outcome {
+	case model.ReviewOutcomeNoAction, model.ReviewOutcomeRebalanceNeeded, model.ReviewOutcomeRebalanced:
+	default:
+		return fmt.Errorf("不支持的检查结果: %s", outcome)
+	}
+
+	review, err := s.reviewRepo.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	review.Outcome = outcome
+	review.Note = note
+	return s.reviewRepo.Update(ctx, review)
+}
+
+// DeleteReview 删除检查记录
+func (s *ScheduleService) DeleteReview(ctx context.Context, id uint) error {
+	return s.reviewRepo.Delete(ctx, id)
+}
+
+func reviewToMap(r *model.RebalanceReview) map[string]interface{} {
+	reasons := []string{}
+	if r.Reasons != "" {
+		reasons = strings.Split(r.Reasons, "\n")
+	}
+	return map[string]interface{}{
+		"id":                 r.ID,
+		"due_date":           r.DueDate.Format("2006-01-02"),
+		"target_stock_ratio": r.TargetStockRatio,
+		"stock_ratio":        r.StockRatio,
+		"breached":           r.Breached,
+		"reasons":            reasons,
+		"outcome":            r.Outcome,
+		"note":               r.Note,
+		"created_at":         r.CreatedAt.Format("2006-01-02 15:04:05"),
+	}
+}
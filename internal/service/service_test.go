@@ -0,0 +1,34 @@
+package service
+
+import (
+	"testing"
+
+	"margin/internal/model"
+	"margin/pkg/db"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testEncryptKey 测试数据库使用的加密密钥
+const testEncryptKey = "0123456789abcdef0123456789abcdef"
+
+// newTestDB 在临时目录中创建数据库并写入加密密钥
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	g, err := db.InitDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.Logger = g.Logger.LogMode(logger.Silent)
+	if err := g.Create(&model.Config{Key: model.ConfigKeyEncryptKey, Value: testEncryptKey}).Error; err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := g.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return g
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"fmt"
+	"margin/internal/repo"
+	"time"
+)
+
+// 降采样周期，每个周期保留最后一条记录
+const (
+	PeriodAll       = ""          // 不降采样
+	PeriodDaily     = "daily"     // 每天
+	PeriodWeekly    = "weekly"    // 每周（ISO 周）
+	PeriodMonthly   = "monthly"   // 每月
+	PeriodQuarterly = "quarterly" // 每季度
+)
+
+// TimeQuery 按时间筛选、降采样和分页的查询条件
+type TimeQuery struct {
+	From   string // 开始日期（含），格式 2006-01-02，空表示不限
+	To     string // 结束日期（含），空表示不限
+	Period string // 降采样周期
+	Limit  int    // 每页条数，0 表示不分页
+	Offset int
+}
+
+// timeRange 校验查询条件并转换为时间区间
+func (q TimeQuery) timeRange() (repo.TimeRange, error) {
+	switch q.Period {
+	case PeriodAll, PeriodDaily, PeriodWeekly, PeriodMonthly, PeriodQuarterly:
+	default:
+		return repo.TimeRange{}, fmt.Errorf("不支持的周期: %s", q.Period)
+	}
+	if q.Limit < 0 || q.Offset < 0 {
+		return repo.TimeRange{}, fmt.Errorf("分页参数不能为负数")
+	}
+
+	var r repo.TimeRange
+	if q.From != "" {
+		from, err := time.ParseInLocation("2006-01-02", q.From, time.Local)
+		if err != nil {
+			return repo.TimeRange{}, fmt.Errorf("开始日期格式错误: %w", err)
+		}
+		r.From = from
+	}
+	if q.To != "" {
+		to, err := time.ParseInLocation("2006-01-02", q.To, time.Local)
+		if err != nil {
+			return repo.TimeRange{}, fmt.Errorf("结束日期格式错误: %w", err)
+		}
+		r.To = to.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+	return r, nil
+}
+
+// selectPage 对按时间升序的记录降采样，然后按时间倒序分页，返回本页的 ID 和降采样后的总数
+func (q TimeQuery) selectPage(rows []repo.TimeRow) ([]uint, int) {
+	if q.Period != PeriodAll {
+		var sampled []repo.TimeRow
+		for i, row := range rows {
+			// 周期内最后一条记录
+			if i == len(rows)-1 || periodBucket(rows[i+1].CreatedAt, q.Period) != periodBucket(row.CreatedAt, q.Period) {
+				sampled = append(sampled, row)
+			}
+		}
+		rows = sampled
+	}
+
+	total := len(rows)
+	ids := make([]uint, 0, total)
+	for i := total - 1; i >= 0; i-- {
+		ids = append(ids, rows[i].ID)
+	}
+
+	if q.Offset >= len(ids) {
+		return []uint{}, total
+	}
+	ids = ids[q.Offset:]
+	if q.Limit > 0 && q.Limit < len(ids) {
+		ids = ids[:q.Limit]
+	}
+	return ids, total
+}
+
+// periodBucket 返回时间所在周期的标识
+func periodBucket(t time.Time, period string) string {
+	t = t.Local()
+	switch period {
+	case PeriodWeekly:
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	case PeriodMonthly:
+		return t.Format("2006-01")
+	case PeriodQuarterly:
+		return fmt.Sprintf("%d-Q%d", t.Year(), (int(t.Month())-1)/3+1)
+	default:
+		return t.Format("2006-01-02")
+	}
+}
@@ -0,0 +1,257 @@
+package service
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"margin/internal/model"
+	"time"
+	_ "time/tzdata" // 内置时区数据，系统缺少时区数据时也能正确换算交易所时间
+)
+
+// builtinHolidays 随应用发布的休市日历，可通过导入更新的版本覆盖
+//
+//go:embed holidays.json
+var builtinHolidays []byte
+
+// 市场状态
+const (
+	MarketStateOpen    = "open"     // 交易中
+	MarketStateLunch   = "lunch"    // 午间休市
+	MarketStatePreOpen = "pre_open" // 今天尚未开盘
+	MarketStateClosed  = "closed"   // 今天已收盘
+	MarketStateWeekend = "weekend"  // 周末
+	MarketStateHoliday = "holiday"  // 节假日
+)
+
+// marketSession 市场所在时区和连续交易时段（当地时间，从零点起的分钟数）
+type marketSession struct {
+	timezone   string
+	open       int
+	close      int
+	lunchStart int // 午休开始，0 表示没有午休
+	lunchEnd   int
+}
+
+var marketSessions = map[string]marketSession{
+	model.MarketCN: {timezone: "Asia/Shanghai", open: 9*60 + 30, close: 15 * 60, lunchStart: 11*60 + 30, lunchEnd: 13 * 60},
+	model.MarketHK: {timezone: "Asia/Hong_Kong", open: 9*60 + 30, close: 16 * 60, lunchStart: 12 * 60, lunchEnd: 13 * 60},
+	model.MarketUS: {timezone: "America/New_York", open: 9*60 + 30, close: 16 * 60},
+}
+
+// holidayTable 休市日历文件格式
+type holidayTable struct {
+	Version string `json:"version"`
+	Markets map[string]struct {
+		CoveredUntil string            `json:"covered_until"` // 日历覆盖到的日期，之后只按周末判断
+		Holidays     []string          `json:"holidays"`      // 全天休市的工作日
+		EarlyClose   map[string]string `json:"early_close"`   // 提前收盘的日期和收盘时间（HH:MM）
+	} `json:"markets"`
+}
+
+// marketCalendar 单个市场的交易日历
+type marketCalendar struct {
+	session      marketSession
+	location     *time.Location
+	coveredUntil string
+	holidays     map[string]bool
+	earlyClose   map[string]int
+}
+
+// TradingCalendar A 股、港股和美股的交易日历
+type TradingCalendar struct {
+	Version string
+	markets map[string]*marketCalendar
+}
+
+// MarketStatus 某一时刻市场的交易状态
+type MarketStatus struct {
+	Market    string
+	State     string
+	Open      bool
+	LocalTime time.Time // 交易所当地时间
+	LastClose time.Time // 最近一次收盘时间（不晚于当前）
+	NextOpen  time.Time // 下一次开盘（含午休后开盘）时间
+	Covered   bool      // 当前日期是否在休市日历覆盖范围内
+}
+
+// parseHolidayTable 解析休市日历，校验日期格式和时间
+func parseHolidayTable(data []byte) (*TradingCalendar, error) {
+	var table holidayTable
+	if err := json.Unmarshal(data, &table); err != nil {
+		return nil, fmt.Errorf("休市日历格式错误: %w", err)
+	}
+	if table.Version == "" {
+		return nil, errors.New("休市日历缺少版本号")
+	}
+
+	c := &TradingCalendar{Version: table.Version, markets: make(map[string]*marketCalendar)}
+	for market, session := range marketSessions {
+		loc, err := time.LoadLocation(session.timezone)
+		if err != nil {
+			return nil, err
+		}
+		m := &marketCalendar{
+			session:    session,
+			location:   loc,
+			holidays:   make(map[string]bool),
+			earlyClose: make(map[string]int),
+		}
+		c.markets[market] = m
+
+		entry, ok := table.Markets[market]
+		if !ok {
+			continue
+		}
+		if entry.CoveredUntil != "" {
+			if _, err := time.Parse("2006-01-02", entry.CoveredUntil); err != nil {
+				return nil, fmt.Errorf("%s 覆盖日期格式错误: %s", market, entry.CoveredUntil)
+			}
+			m.coveredUntil = entry.CoveredUntil
+		}
+		for _, day := range entry.Holidays {
+			if _, err := time.Parse("2006-01-02", day); err != nil {
+				return nil, fmt.Errorf("%s 休市日期格式错误: %s", market, day)
+			}
+			m.holidays[day] = true
+		}
+		for day, at := range entry.EarlyClose {
+			if _, err := time.Parse("2006-01-02", day); err != nil {
+				return nil, fmt.Errorf("%s 提前收盘日期格式错误: %s", market, day)
+			}
+			t, err := time.Parse("15:04", at)
+			if err != nil {
+				return nil, fmt.Errorf("%s 提前收盘时间格式错误: %s", market, at)
+			}
+			m.earlyClose[day] = t.Hour()*60 + t.Minute()
+		}
+	}
+	return c, nil
+}
+
+// tradingDay 判断当地日期是否为交易日
+func (m *marketCalendar) tradingDay(day time.Time) bool {
+	if day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+		return false
+	}
+	return !m.holidays[day.Format("2006-01-02")]
+}
+
+// closeMinute 当地日期的收盘时间
+func (m *marketCalendar) closeMinute(day time.Time) int {
+	if minute, ok := m.earlyClose[day.Format("2006-01-02")]; ok {
+		return minute
+	}
+	return m.session.close
+}
+
+// at 当地日期某一分钟对应的时刻
+func (m *marketCalendar) at(day time.Time, minute int) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), minute/60, minute%60, 0, 0, m.location)
+}
+
+// hasLunch 当天是否有午休（提前收盘早于午休时没有午后交易）
+func (m *marketCalendar) hasLunch(day time.Time) bool {
+	return m.session.lunchStart > 0 && m.closeMinute(day) > m.session.lunchStart
+}
+
+// Status 返回市场在 now 时的交易状态，未知市场返回错误
+func (c *TradingCalendar) Status(market string, now time.Time) (MarketStatus, error) {
+	m, ok := c.markets[market]
+	if !ok {
+		return MarketStatus{}, fmt.Errorf("不支持的市场: %s", market)
+	}
+
+	local := now.In(m.location)
+	today := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, m.location)
+	minute := local.Hour()*60 + local.Minute()
+	s := MarketStatus{
+		Market:    market,
+		LocalTime: local,
+		LastClose: m.lastClose(today, now),
+		NextOpen:  m.nextOpen(today, now),
+		Covered:   m.coveredUntil != "" && today.Format("2006-01-02") <= m.coveredUntil,
+	}
+
+	switch {
+	case today.Weekday() == time.Saturday || today.Weekday() == time.Sunday:
+		s.State = MarketStateWeekend
+	case !m.tradingDay(today):
+		s.State = MarketStateHoliday
+	case minute < m.session.open:
+		s.State = MarketStatePreOpen
+	case minute >= m.closeMinute(today):
+		s.State = MarketStateClosed
+	case m.hasLunch(today) && minute >= m.session.lunchStart && minute < m.session.lunchEnd:
+		s.State = MarketStateLunch
+	default:
+		s.State = MarketStateOpen
+		s.Open = true
+	}
+	return s, nil
+}
+
+// lastClose 不晚于 now 的最近一次收盘时间，包括午间休市
+func (m *marketCalendar) lastClose(today, now time.Time) time.Time {
+	for i := 0; i < 30; i++ {
+		day := today.AddDate(0, 0, -i)
+		if !m.tradingDay(day) {
+			continue
+		}
+		if t := m.at(day, m.closeMinute(day)); !t.After(now) {
+			return t
+		}
+		if m.hasLunch(day) {
+			if t := m.at(day, m.session.lunchStart); !t.After(now) {
+				return t
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// nextOpen 晚于 now 的下一次开盘时间，包括午休后开盘
+func (m *marketCalendar) nextOpen(today, now time.Time) time.Time {
+	for i := 0; i < 30; i++ {
+		day := today.AddDate(0, 0, i)
+		if !m.tradingDay(day) {
+			continue
+		}
+		if t := m.at(day, m.session.open); t.After(now) {
+			return t
+		}
+		if m.hasLunch(day) {
+			if t := m.at(day, m.session.lunchEnd); t.After(now) {
+				return t
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// CoveredUntil 各市场休市日历覆盖到的日期
+func (c *TradingCalendar) CoveredUntil() map[string]string {
+	result := make(map[string]string, len(c.markets))
+	for market, m := range c.markets {
+		result[market] = m.coveredUntil
+	}
+	return result
+}
+
+func (s MarketStatus) ToMap() map[string]interface{} {
+	result := map[string]interface{}{
+		"market":     s.Market,
+		"state":      s.State,
+		"open":       s.Open,
+		"local_time": s.LocalTime.Format("2006-01-02 15:04"),
+		"covered":    s.Covered,
+	}
+	if !s.LastClose.IsZero() {
+		result["last_close"] = s.LastClose.Local().Format("2006-01-02 15:04")
+	}
+	if !s.NextOpen.IsZero() {
+		result["next_open"] = s.NextOpen.Local().Format("2006-01-02 15:04")
+	}
+	return result
+}
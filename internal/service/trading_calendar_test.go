@@ -0,0 +1,146 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"margin/internal/model"
+)
+
+// testHolidays 测试用的休市日历，不随内置日历更新而变化
+const testHolidays = `{
+  "version": "test",
+  "markets": {
+    "cn": {"covered_until": "2026-12-31", "holidays": ["2026-10-01", "2026-10-02", "2026-10-05"]},
+    "hk": {"covered_until": "2026-12-31", "holidays": ["2026-10-19", "2026-12-25", "2026-12-28"], "early_close": {"2026-12-24": "12:00"}},
+    "us": {"covered_until": "2026-12-31", "holidays": ["2026-11-26"], "early_close": {"2026-11-27": "13:00"}}
+  }
+}`
+
+func TestBuiltinHolidays(t *testing.T) {
+	if _, err := parseHolidayTable(builtinHolidays); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTradingCalendarStatus(t *testing.T) {
+	calendar, err := parseHolidayTable([]byte(testHolidays))
+	if err != nil {
+		t.Fatal(err)
+	}
+	zone := func(name string) *time.Location {
+		loc, err := time.LoadLocation(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return loc
+	}
+	shanghai, hongKong, newYork := zone("Asia/Shanghai"), zone("Asia/Hong_Kong"), zone("America/New_York")
+	at := func(loc *time.Location, day string, clock string) time.Time {
+		t.Helper()
+		v, err := time.ParseInLocation("2006-01-02 15:04", day+" "+clock, loc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+	utc := func(day, clock string) time.Time { return at(time.UTC, day, clock) }
+
+	tests := []struct {
+		name      string
+		market    string
+		now       time.Time
+		state     string
+		lastClose time.Time
+		nextOpen  time.Time
+	}{
+		{
+			name: "cn pre open", market: model.MarketCN, now: at(shanghai, "2026-10-20", "09:00"), state: MarketStatePreOpen,
+			lastClose: at(shanghai, "2026-10-19", "15:00"), nextOpen: at(shanghai, "2026-10-20", "09:30"),
+		},
+		{
+			name: "cn open", market: model.MarketCN, now: at(shanghai, "2026-10-20", "10:00"), state: MarketStateOpen,
+			lastClose: at(shanghai, "2026-10-19", "15:00"), nextOpen: at(shanghai, "2026-10-20", "13:00"),
+		},
+		{
+			name: "cn lunch", market: model.MarketCN, now: at(shanghai, "2026-10-20", "11:45"), state: MarketStateLunch,
+			lastClose: at(shanghai, "2026-10-20", "11:30"), nextOpen: at(shanghai, "2026-10-20", "13:00"),
+		},
+		{
+			name: "cn holiday", market: model.MarketCN, now: at(shanghai, "2026-10-02", "10:00"), state: MarketStateHoliday,
+			lastClose: at(shanghai, "2026-09-30", "15:00"), nextOpen: at(shanghai, "2026-10-06", "09:30"),
+		},
+		{
+			name: "hk lunch break", market: model.MarketHK, now: at(hongKong, "2026-10-20", "12:30"), state: MarketStateLunch,
+			lastClose: at(hongKong, "2026-10-20", "12:00"), nextOpen: at(hongKong, "2026-10-20", "13:00"),
+		},
+		{
+			name: "hk afternoon", market: model.MarketHK, now: at(hongKong, "2026-10-20", "15:59"), state: MarketStateOpen,
+			lastClose: at(hongKong, "2026-10-20", "12:00"), nextOpen: at(hongKong, "2026-10-21", "09:30"),
+		},
+		{
+			name: "hk holiday", market: model.MarketHK, now: at(hongKong, "2026-10-19", "10:00"), state: MarketStateHoliday,
+			lastClose: at(hongKong, "2026-10-16", "16:00"), nextOpen: at(hongKong, "2026-10-20", "09:30"),
+		},
+		{
+			// 提前在午休开始时收盘，当天没有午后交易
+			name: "hk early close", market: model.MarketHK, now: at(hongKong, "2026-12-24", "12:30"), state: MarketStateClosed,
+			lastClose: at(hongKong, "2026-12-24", "12:00"), nextOpen: at(hongKong, "2026-12-29", "09:30"),
+		},
+		{
+			name: "hk before early close", market: model.MarketHK, now: at(hongKong, "2026-12-24", "11:59"), state: MarketStateOpen,
+			lastClose: at(hongKong, "2026-12-23", "16:00"), nextOpen: at(hongKong, "2026-12-29", "09:30"),
+		},
+		{
+			name: "us early close", market: model.MarketUS, now: at(newYork, "2026-11-27", "13:30"), state: MarketStateClosed,
+			lastClose: at(newYork, "2026-11-27", "13:00"), nextOpen: at(newYork, "2026-11-30", "09:30"),
+		},
+		{
+			name: "us holiday", market: model.MarketUS, now: at(newYork, "2026-11-26", "10:00"), state: MarketStateHoliday,
+			lastClose: at(newYork, "2026-11-25", "16:00"), nextOpen: at(newYork, "2026-11-27", "09:30"),
+		},
+		{
+			// 夏令时从 3 月 8 日开始：周五 16:00 EST 为 21:00 UTC，周一 9:30 EDT 为 13:30 UTC
+			name: "us dst start weekend", market: model.MarketUS, now: utc("2026-03-07", "12:00"), state: MarketStateWeekend,
+			lastClose: utc("2026-03-06", "21:00"), nextOpen: utc("2026-03-09", "13:30"),
+		},
+		{
+			name: "us before dst 13:45 utc", market: model.MarketUS, now: utc("2026-03-06", "13:45"), state: MarketStatePreOpen,
+			lastClose: utc("2026-03-05", "21:00"), nextOpen: utc("2026-03-06", "14:30"),
+		},
+		{
+			name: "us after dst 13:45 utc", market: model.MarketUS, now: utc("2026-03-09", "13:45"), state: MarketStateOpen,
+			lastClose: utc("2026-03-06", "21:00"), nextOpen: utc("2026-03-10", "13:30"),
+		},
+		{
+			// 夏令时在 11 月 1 日结束：周五 16:00 EDT 为 20:00 UTC，周一 9:30 EST 为 14:30 UTC
+			name: "us dst end weekend", market: model.MarketUS, now: utc("2026-10-31", "12:00"), state: MarketStateWeekend,
+			lastClose: utc("2026-10-30", "20:00"), nextOpen: utc("2026-11-02", "14:30"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := calendar.Status(tt.market, tt.now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s.State != tt.state || s.Open != (tt.state == MarketStateOpen) {
+				t.Errorf("state = %s (open %v), want %s", s.State, s.Open, tt.state)
+			}
+			if !s.LastClose.Equal(tt.lastClose) {
+				t.Errorf("last close = %v, want %v", s.LastClose, tt.lastClose)
+			}
+			if !s.NextOpen.Equal(tt.nextOpen) {
+				t.Errorf("next open = %v, want %v", s.NextOpen, tt.nextOpen)
+			}
+			if !s.Covered {
+				t.Error("expected date to be covered")
+			}
+		})
+	}
+
+	if _, err := calendar.Status("jp", time.Now()); err == nil {
+		t.Error("expected error for unsupported market")
+	}
+}
@@ -0,0 +1,472 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"margin/internal/model"
+	"margin/internal/repo"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 估值方法
+const (
+	ValuationMethodPercentile    = "percentile"     // 估值历史百分位
+	ValuationMethodEarningsYield = "earnings_yield" // 盈利收益率减十年期国债收益率
+)
+
+// 估值指标
+const (
+	ValuationMetricPE = "pe"
+	ValuationMetricPB = "pb"
+)
+
+// Graham 建议的股票比例范围
+const (
+	grahamMinStockRatio = 25
+	grahamMaxStockRatio = 75
+)
+
+const (
+	valuationIndexCode = "000300"
+	valuationCacheTTL  = 6 * time.Hour
+	datacenterURL      = "https://datacenter-web.eastmoney.com/api/data/v1/get"
+)
+
+// CurvePoint 估值曲线上的点：输入值（百分位或收益率差）对应的股票比例
+type CurvePoint struct {
+	X     float64 `json:"x"`
+	Stock float64 `json:"stock"`
+}
+
+// ValuationConfig 估值驱动的动态目标配置
+type ValuationConfig struct {
+	Enabled bool         `json:"enabled"`
+	Method  string       `json:"method"`
+	Metric  string       `json:"metric"` // 百分位方法使用的指标 pe/pb
+	Years   int          `json:"years"`  // 百分位的回看年数
+	Curve   []CurvePoint `json:"curve"`  // 为空时使用默认曲线
+}
+
+// DefaultValuationConfig 默认按市盈率 10 年百分位线性映射：0% → 75%，100% → 25%
+var DefaultValuationConfig = ValuationConfig{
+	Enabled: false,
+	Method:  ValuationMethodPercentile,
+	Metric:  ValuationMetricPE,
+	Years:   10,
+}
+
+// defaultCurve 各方法的默认曲线
+func defaultCurve(method string) []CurvePoint {
+	if method == ValuationMethodEarningsYield {
+		// 收益率差 0 个百分点以下为最低，6 个百分点以上为最高
+		return []CurvePoint{{X: 0, Stock: 25}, {X: 3, Stock: 50}, {X: 6, Stock: 75}}
+	}
+	return []CurvePoint{{X: 0, Stock: 75}, {X: 50, Stock: 50}, {X: 100, Stock: 25}}
+}
+
+// Validate 校验配置
+func (c ValuationConfig) Validate() error {
+	switch c.Method {
+	case ValuationMethodPercentile:
+		if c.Metric != ValuationMetricPE && c.Metric != ValuationMetricPB {
+			return fmt.Errorf("不支持的估值指标: %s", c.Metric)
+		}
+		if c.Years < 1 || c.Years > 20 {
+			return errors.New("回看年数需在 1-20 之间")
+		}
+	case ValuationMethodEarningsYield:
+	default:
+		return fmt.Errorf("不支持的估值方法: %s", c.Method)
+	}
+
+	// 曲线为空时使用默认曲线，否则至少需要两个点才能插值
+	if len(c.Curve) == 1 {
+		return errors.New("曲线请至少设置两个点")
+	}
+	for i, p := range c.Curve {
+		if i > 0 && p.X <= c.Curve[i-1].X {
+			return errors.New("曲线的输入值必须递增")
+		}
+		if p.Stock < grahamMinStockRatio || p.Stock > grahamMaxStockRatio {
+			return fmt.Errorf("曲线的股票比例需在 %d-%d 之间", grahamMinStockRatio, grahamMaxStockRatio)
+		}
+	}
+	return nil
+}
+
+// curve 返回生效的曲线
+func (c ValuationConfig) curve() []CurvePoint {
+	if len(c.Curve) >= 2 {
+		return c.Curve
+	}
+	return defaultCurve(c.Method)
+}
+
+// interpolate 在曲线上线性插值，并限制在 25-75 之间
+func interpolate(curve []CurvePoint, x float64) float64 {
+	return math.Min(math.Max(interpolateCurve(curve, x), grahamMinStockRatio), grahamMaxStockRatio)
+}
+
+// interpolateCurve 在按 X 升序的曲线上线性插值，超出范围取端点
+func interpolateCurve(curve []CurvePoint, x float64) float64 {
+	switch {
+	case x <= curve[0].X:
+		return curve[0].Stock
+	case x >= curve[len(curve)-1].X:
+		return curve[len(curve)-1].Stock
+	}
+	for i := 1; i < len(curve); i++ {
+		if x <= curve[i].X {
+			a, b := curve[i-1], curve[i]
+			return a.Stock + (b.Stock-a.Stock)*(x-a.X)/(b.X-a.X)
+		}
+	}
+	return curve[len(curve)-1].Stock
+}
+
+// ValuationPoint 指数某一交易日的估值
+type ValuationPoint struct {
+	Date time.Time
+	PE   float64
+	PB   float64
+}
+
+// Valuation 当前估值和建议的股票比例
+type Valuation struct {
+	Date                time.Time
+	PE                  float64
+	PB                  float64
+	Percentile          float64 // 当前指标在回看期内的百分位
+	EarningsYield       float64 // 盈利收益率 = 1/PE (%)
+	BondYield           float64 // 十年期国债收益率 (%)
+	Spread              float64 // 盈利收益率 - 国债收益率（百分点）
+	SuggestedStockRatio float64
+	Config              ValuationConfig
+	Reasoning           []string
+}
+
+// ValuationService 沪深300估值与动态目标比例
+type ValuationService struct {
+	client     *http.Client
+	configRepo *repo.ConfigRepository
+
+	mu        sync.Mutex
+	history   []ValuationPoint
+	bondYield float64
+	fetchedAt time.Time
+}
+
+func NewValuationService(db *gorm.DB) *ValuationService {
+	return &ValuationService{
+		client: &http.Client{
+			Timeout: 15 * time.Second,
+		},
+		configRepo: repo.NewConfigRepository(db),
+	}
+}
+
+// GetConfig 获取估值配置，未配置时返回默认配置（关闭）
+func (s *ValuationService) GetConfig(ctx context.Context) (ValuationConfig, error) {
+	config, err := s.configRepo.Get(ctx, model.ConfigKeyValuationTarget)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return DefaultValuationConfig, nil
+	}
+	if err != nil {
+		return ValuationConfig{}, err
+	}
+
+	var c ValuationConfig
+	if err := json.Unmarshal([]byte(config.Value), &c); err != nil {
+		return ValuationConfig{}, fmt.Errorf("估值配置格式错误: %w", err)
+	}
+	return c, nil
+}
+
+// SaveConfig 保存估值配置
+func (s *ValuationService) SaveConfig(ctx context.Context, c ValuationConfig) error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return s.configRepo.Set(ctx, model.ConfigKeyValuationTarget, string(data))
+}
+
+// Suggest 根据当前估值计算建议的股票比例
+func (s *ValuationService) Suggest(ctx context.Context) (*Valuation, error) {
+	c, err := s.GetConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	history, bondYield, err := s.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return evaluate(c, history, bondYield)
+}
+
+// evaluate 计算估值百分位或收益率差，并映射为股票比例
+func evaluate(c ValuationConfig, history []ValuationPoint, bondYield float64) (*Valuation, error) {
+	if len(history) == 0 {
+		return nil, errors.New("没有估值数据")
+	}
+	latest := history[len(history)-1]
+
+	v := &Valuation{
+		Date:      latest.Date,
+		PE:        latest.PE,
+		PB:        latest.PB,
+		BondYield: bondYield,
+		Config:    c,
+	}
+	if latest.PE > 0 {
+		v.EarningsYield = 100 / latest.PE
+		v.Spread = v.EarningsYield - bondYield
+	}
+
+	metricName := "市盈率(TTM)"
+	metric := func(p ValuationPoint) float64 { return p.PE }
+	if c.Metric == ValuationMetricPB {
+		metricName = "市净率"
+		metric = func(p ValuationPoint) float64 { return p.PB }
+	}
+
+	start := latest.Date.AddDate(-c.Years, 0, 0)
+	var below, count int
+	for _, p := range history {
+		if p.Date.Before(start) || metric(p) <= 0 {
+			continue
+		}
+		count++
+		if metric(p) < metric(latest) {
+			below++
+		}
+	}
+	if count > 0 {
+		v.Percentile = float64(below) / float64(count) * 100
+	}
+
+	curve := c.curve()
+	date := latest.Date.Format("2006-01-02")
+	switch c.Method {
+	case ValuationMethodEarningsYield:
+		if latest.PE <= 0 || bondYield <= 0 {
+			return nil, errors.New("缺少市盈率或国债收益率数据")
+		}
+		v.SuggestedStockRatio = interpolate(curve, v.Spread)
+		v.Reasoning = []string{
+			fmt.Sprintf("沪深300 市盈率(TTM) %.2f（%s），盈利收益率 %.2f%%", latest.PE, date, v.EarningsYield),
+			fmt.Sprintf("十年期国债收益率 %.2f%%，股债收益率差 %.2f 个百分点", bondYield, v.Spread),
+		}
+	default:
+		v.SuggestedStockRatio = interpolate(curve, v.Percentile)
+		v.Reasoning = []string{
+			fmt.Sprintf("沪深300 %s %.2f（%s），处于近 %d 年 %.1f%% 百分位", metricName, metric(latest), date, c.Years, v.Percentile),
+		}
+		if v.EarningsYield > 0 && bondYield > 0 {
+			v.Reasoning = append(v.Reasoning, fmt.Sprintf("参考：盈利收益率 %.2f%%，十年期国债收益率 %.2f%%", v.EarningsYield, bondYield))
+		}
+	}
+
+	switch {
+	case v.SuggestedStockRatio >= 60:
+		v.Reasoning = append(v.Reasoning, fmt.Sprintf("市场估值偏低，建议股票比例提高到 %.1f%%", v.SuggestedStockRatio))
+	case v.SuggestedStockRatio <= 40:
+		v.Reasoning = append(v.Reasoning, fmt.Sprintf("市场估值偏高，建议股票比例降低到 %.1f%%", v.SuggestedStockRatio))
+	default:
+		v.Reasoning = append(v.Reasoning, fmt.Sprintf("市场估值适中，建议股票比例 %.1f%%", v.SuggestedStockRatio))
+	}
+	v.Reasoning = append(v.Reasoning, fmt.Sprintf("按格雷厄姆建议，股票比例限制在 %d%%-%d%% 之间", grahamMinStockRatio, grahamMaxStockRatio))
+
+	return v, nil
+}
+
+// load 获取估值历史和国债收益率，结果缓存一段时间
+func (s *ValuationService) load(ctx context.Context) ([]ValuationPoint, float64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.history) > 0 && time.Since(s.fetchedAt) < valuationCacheTTL {
+		return s.history, s.bondYield, nil
+	}
+
+	history, err := s.fetchIndexValuation(ctx)
+	if err != nil {
+		return nil, 0, fmt.Errorf("获取沪深300估值失败: %w", err)
+	}
+	bondYield, err := s.fetchBondYield(ctx)
+	if err != nil {
+		// 国债收益率仅用于收益率差方法，失败时保留上次的值
+		bondYield = s.bondYield
+	}
+
+	s.history = history
+	s.bondYield = bondYield
+	s.fetchedAt = time.Now()
+	return history, bondYield, nil
+}
+
+// fetchIndexValuation 获取沪深300市盈率、市净率历史（东方财富数据中心）
+func (s *ValuationService) fetchIndexValuation(ctx context.Context) ([]ValuationPoint, error) {
+	rows, err := s.fetchDatacenter(ctx, url.Values{
+		"reportName":  {"RPT_VALUEMARKET"},
+		"columns":     {"ALL"},
+		"filter":      {fmt.Sprintf(`(TRADE_MARKET_CODE="%s")`, valuationIndexCode)},
+		"sortColumns": {"TRADE_DATE"},
+		"sortTypes":   {"-1"},
+		"pageSize":    {"5000"},
+		"pageNumber":  {"1"},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	history := make([]ValuationPoint, 0, len(rows))
+	for _, row := range rows {
+		date, err := parseDatacenterDate(row["TRADE_DATE"])
+		if err != nil {
+			continue
+		}
+		history = append(history, ValuationPoint{
+			Date: date,
+			PE:   firstNumber(row, "PE_TTM_AVG", "PE_TTM"),
+			PB:   firstNumber(row, "PB_MRQ_AVG", "PB_MRQ", "PB_AVG"),
+		})
+	}
+	if len(history) == 0 {
+		return nil, errors.New("没有估值数据")
+	}
+
+	sort.Slice(history, func(i, j int) bool { return history[i].Date.Before(history[j].Date) })
+	return history, nil
+}
+
+// fetchBondYield 获取最新的中国十年期国债收益率 (%)
+func (s *ValuationService) fetchBondYield(ctx context.Context) (float64, error) {
+	rows, err := s.fetchDatacenter(ctx, url.Values{
+		"reportName":  {"RPTA_WEB_TREASURYYIELD"},
+		"columns":     {"SOLAR_DATE,EMM00166466"},
+		"sortColumns": {"SOLAR_DATE"},
+		"sortTypes":   {"-1"},
+		"pageSize":    {"10"},
+		"pageNumber":  {"1"},
+	})
+	if err != nil {
+		return 0, err
+	}
+	for _, row := range rows {
+		if v := firstNumber(row, "EMM00166466"); v > 0 {
+			return v, nil
+		}
+	}
+	return 0, errors.New("没有国债收益率数据")
+}
+
+// fetchDatacenter 请求东方财富数据中心接口，返回 result.data
+func (s *ValuationService) fetchDatacenter(ctx context.Context, params url.Values) ([]map[string]interface{}, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", datacenterURL+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36")
+	req.Header.Set("Referer", "https://data.eastmoney.com/")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP status: %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var payload struct {
+		Success bool   `json:"success"`
+		Message string `json:"message"`
+		Result  *struct {
+			Data []map[string]interface{} `json:"data"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("解析响应失败: %w", err)
+	}
+	if !payload.Success || payload.Result == nil {
+		return nil, fmt.Errorf("接口返回错误: %s", payload.Message)
+	}
+	return payload.Result.Data, nil
+}
+
+// parseDatacenterDate 解析 "2024-01-02 00:00:00" 格式的日期
+func parseDatacenterDate(v interface{}) (time.Time, error) {
+	s, ok := v.(string)
+	if !ok || len(s) < 10 {
+		return time.Time{}, errors.New("invalid date")
+	}
+	return time.ParseInLocation("2006-01-02", s[:10], time.Local)
+}
+
+// firstNumber 返回第一个存在的数值字段
+func firstNumber(row map[string]interface{}, keys ...string) float64 {
+	for _, key := range keys {
+		switch v := row[key].(type) {
+		case float64:
+			return v
+		case string:
+			if n, err := strconv.ParseFloat(v, 64); err == nil {
+				return n
+			}
+		}
+	}
+	return 0
+}
+
+// ToMap 转换为前端展示的结构
+func (c ValuationConfig) ToMap() map[string]interface{} {
+	curve := make([]map[string]interface{}, 0, len(c.curve()))
+	for _, p := range c.curve() {
+		curve = append(curve, map[string]interface{}{"x": p.X, "stock": p.Stock})
+	}
+	return map[string]interface{}{
+		"enabled": c.Enabled,
+		"method":  c.Method,
+		"metric":  c.Metric,
+		"years":   c.Years,
+		"curve":   curve,
+	}
+}
+
+// ToMap 转换为前端展示的结构
+func (v *Valuation) ToMap() map[string]interface{} {
+	return map[string]interface{}{
+		"date":                  v.Date.Format("2006-01-02"),
+		"pe":                    v.PE,
+		"pb":                    v.PB,
+		"percentile":            v.Percentile,
+		"earnings_yield":        v.EarningsYield,
+		"bond_yield":            v.BondYield,
+		"spread":                v.Spread,
+		"suggested_stock_ratio": v.SuggestedStockRatio,
+		"method":                v.Config.Method,
+		"metric":                v.Config.Metric,
+		"reasoning":             v.Reasoning,
+	}
+}
@@ -0,0 +1,26 @@
+package service
+
+import "testing"
+
+func TestValuationConfigValidateCurve(t *testing.T) {
+	tests := []struct {
+		name    string
+		curve   []CurvePoint
+		wantErr bool
+	}{
+		{name: "default curve"},
+		{name: "two points", curve: []CurvePoint{{X: 0, Stock: 70}, {X: 100, Stock: 30}}},
+		{name: "single point", curve: []CurvePoint{{X: 50, Stock: 50}}, wantErr: true},
+		{name: "not increasing", curve: []CurvePoint{{X: 50, Stock: 50}, {X: 50, Stock: 40}}, wantErr: true},
+		{name: "stock out of range", curve: []CurvePoint{{X: 0, Stock: 80}, {X: 100, Stock: 25}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := DefaultValuationConfig
+			c.Curve = tt.curve
+			if err := c.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
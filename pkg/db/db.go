@@ -113,8 +113,19 @@ func InitDB() (*gorm.DB, error) {
 		&model.Config{},
 		&model.Asset{},
 		&model.History{},
+		&model.HistoryItem{},
 		&model.Source{},
 		&model.Rebalance{},
+		&model.RebalanceHolding{},
+		&model.RebalanceOrder{},
+		&model.RebalanceReview{},
+		&model.FeeRule{},
+		&model.AssetLot{},
+		&model.CashFlow{},
+		&model.IndexPrice{},
+		&model.IndexDef{},
+		&model.IndexQuote{},
+		&model.ImportedTransaction{},
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to migrate database: %w", err)
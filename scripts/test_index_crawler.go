@@ -3,13 +3,43 @@ package main
 import (
 	"context"
 	"fmt"
+	"log"
+	"margin/internal/repo"
 	"margin/internal/service"
+	"margin/pkg/db"
+	"os"
 )
 
 func main() {
-	// 创建服务实例（不需要数据库）
-	indexService := service.NewIndexService(nil)
+	// run 返回后临时目录已清理，再退出
+	if err := run(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run() error {
+	// 在临时目录中创建数据库并写入默认指数，不影响应用的数据
+	home, err := os.MkdirTemp("", "margin-index-crawler")
+	if err != nil {
+		return fmt.Errorf("创建临时目录失败: %w", err)
+	}
+	defer os.RemoveAll(home)
+	os.Setenv("HOME", home)
+	os.Setenv("USERPROFILE", home)
+
+	database, err := db.InitDB()
+	if err != nil {
+		return fmt.Errorf("初始化数据库失败: %w", err)
+	}
+	if sqlDB, err := database.DB(); err == nil {
+		defer sqlDB.Close()
+	}
 	ctx := context.Background()
+	if err := repo.NewIndexDefRepository(database).InitDefaultIndexes(ctx); err != nil {
+		return fmt.Errorf("写入默认指数失败: %w", err)
+	}
+
+	indexService := service.NewIndexService(database, service.NewCalendarService(database))
 
 	fmt.Println("=== 测试指数爬虫 ===\n")
 
@@ -65,6 +95,7 @@ func main() {
 	} else {
 		fmt.Printf("   成功获取 %d 个指数\n", len(allIndexes))
 	}
+	return nil
 }
 
 func printIndexData(data *service.IndexData) {